@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"time"
+)
+
+// auditTail is how much of the end of the audit log is read to find the
+// record the next one chains to.
+const auditTail = 64 * 1024
+
+// auditRecord is one line of the -audit-log, describing a run: who ran it
+// where, with which flags, and a digest of the run summary it produced.
+type auditRecord struct {
+	Time    time.Time         `json:"time"`
+	User    string            `json:"user"`
+	Host    string            `json:"host"`
+	Mode    string            `json:"mode"`
+	Options map[string]string `json:"options"`
+	Digest  string            `json:"digest,omitempty"`
+	// Result is the SHA-256 of the run summary, which holds the counts,
+	// errors and verification results of the run.
+	Result string `json:"result"`
+	// Previous is the SHA-256 of the line before, under -audit-chain, so
+	// editing or removing a record breaks the chain after it.
+	Previous string `json:"previous,omitempty"`
+}
+
+func newAuditRecord(summary runSummary) (auditRecord, error) {
+	data, err := json.Marshal(summary)
+
+	if err != nil {
+		return auditRecord{}, err
+	}
+
+	result := sha256.Sum256(data)
+
+	record := auditRecord{
+		Time:    summary.FinishedAt.UTC(),
+		Mode:    summary.Mode,
+		Options: make(map[string]string),
+		Digest:  summary.Digest,
+		Result:  hex.EncodeToString(result[:]),
+	}
+
+	if current, err := user.Current(); err == nil {
+		record.User = current.Username
+	}
+
+	record.Host, _ = os.Hostname()
+
+	flag.Visit(func(f *flag.Flag) {
+		record.Options[f.Name] = redactedValue(f.Name, f.Value.String())
+	})
+
+	return record, nil
+}
+
+// appendAuditRecord appends a record of the run to the audit log, locking it
+// so concurrent runs do not interleave their records or chain to the same
+// one.
+func appendAuditRecord(path string, chain bool, summary runSummary) error {
+	record, err := newAuditRecord(summary)
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if err := lockFile(file, true); err != nil {
+		return err
+	}
+
+	defer unlockFile(file)
+
+	if chain {
+		last, err := lastLine(file)
+
+		if err != nil {
+			return err
+		}
+
+		if last != nil {
+			previous := sha256.Sum256(last)
+			record.Previous = hex.EncodeToString(previous[:])
+		}
+	}
+
+	data, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(data, '\n'))
+
+	return err
+}
+
+// lastLine returns the last line of file without its newline, nil when the
+// file is empty.
+func lastLine(file *os.File) ([]byte, error) {
+	info, err := file.Stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	offset := max(info.Size()-auditTail, 0)
+	tail := make([]byte, info.Size()-offset)
+
+	if _, err := file.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	tail = bytes.TrimSuffix(tail, []byte("\n"))
+
+	if len(tail) == 0 {
+		return nil, nil
+	}
+
+	if i := bytes.LastIndexByte(tail, '\n'); i >= 0 {
+		return tail[i+1:], nil
+	}
+
+	if offset > 0 {
+		return nil, fmt.Errorf("last record of %s is longer than %d bytes", file.Name(), auditTail)
+	}
+
+	return tail, nil
+}
+
+// runVerifyAuditLog checks that every record of a hash-chained audit log
+// chains to the one before it, reporting the first that does not.
+func runVerifyAuditLog(cfg config) bool {
+	if cfg.auditLog == "" {
+		fmt.Println("Error validating flags: the verify-audit-log command requires -audit-log")
+
+		return false
+	}
+
+	file, err := os.Open(cfg.auditLog)
+
+	if err != nil {
+		fmt.Println("Error reading audit log:", err)
+
+		return false
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, auditTail), auditTail)
+
+	var previous []byte
+
+	records := 0
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		records++
+
+		var record auditRecord
+
+		if err := json.Unmarshal(line, &record); err != nil {
+			fmt.Printf("Audit log record %d is not valid JSON: %v\n", records, err)
+
+			return false
+		}
+
+		expected := ""
+
+		if previous != nil {
+			digest := sha256.Sum256(previous)
+			expected = hex.EncodeToString(digest[:])
+		}
+
+		if record.Previous != expected {
+			fmt.Printf("Audit log chain is broken at record %d (%s, %s)\n", records, record.Time.Format(time.RFC3339), record.Mode)
+
+			return false
+		}
+
+		previous = append(previous[:0], line...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Println("Error reading audit log:", err)
+
+		return false
+	}
+
+	fmt.Printf("Audit log chain of %d records is intact\n", records)
+
+	return true
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+type githubContent struct {
+	SHA string `json:"sha"`
+}
+
+type githubBlob struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+func (c *githubClient) getFile(branch string, filePath string) ([]byte, string, error) {
+	var content githubContent
+
+	contentPath := fmt.Sprintf("/repos/%s/contents/%s?ref=%s", c.repository, filePath, url.QueryEscape(branch))
+
+	if err := c.do(http.MethodGet, contentPath, nil, &content); err != nil {
+		return nil, "", err
+	}
+
+	var blob githubBlob
+
+	if err := c.do(http.MethodGet, fmt.Sprintf("/repos/%s/git/blobs/%s", c.repository, content.SHA), nil, &blob); err != nil {
+		return nil, "", err
+	}
+
+	if blob.Encoding != "base64" {
+		return nil, "", fmt.Errorf("unsupported blob encoding: %s", blob.Encoding)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\n", "", "\r", "").Replace(blob.Content))
+
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode blob: %w", err)
+	}
+
+	return data, content.SHA, nil
+}
+
+func (c *githubClient) putFile(branch string, filePath string, data []byte, sha string, message string) error {
+	body := map[string]string{
+		"message": message,
+		"content": base64.StdEncoding.EncodeToString(data),
+		"branch":  branch,
+	}
+
+	if sha != "" {
+		body["sha"] = sha
+	}
+
+	return c.do(http.MethodPut, fmt.Sprintf("/repos/%s/contents/%s", c.repository, filePath), body, nil)
+}
+
+func (c *githubClient) createOrphanBranch(branch string, filePath string, data []byte, message string) error {
+	var blob struct {
+		SHA string `json:"sha"`
+	}
+
+	err := c.do(http.MethodPost, fmt.Sprintf("/repos/%s/git/blobs", c.repository), map[string]string{
+		"content":  base64.StdEncoding.EncodeToString(data),
+		"encoding": "base64",
+	}, &blob)
+
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+
+	err = c.do(http.MethodPost, fmt.Sprintf("/repos/%s/git/trees", c.repository), map[string]any{
+		"tree": []map[string]string{
+			{"path": filePath, "mode": "100644", "type": "blob", "sha": blob.SHA},
+		},
+	}, &tree)
+
+	if err != nil {
+		return fmt.Errorf("failed to create tree: %w", err)
+	}
+
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+
+	err = c.do(http.MethodPost, fmt.Sprintf("/repos/%s/git/commits", c.repository), map[string]any{
+		"message": message,
+		"tree":    tree.SHA,
+		"parents": []string{},
+	}, &commit)
+
+	if err != nil {
+		return fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	err = c.do(http.MethodPost, fmt.Sprintf("/repos/%s/git/refs", c.repository), map[string]string{
+		"ref": "refs/heads/" + branch,
+		"sha": commit.SHA,
+	}, nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
+	}
+
+	return nil
+}
+
+func (c *githubClient) branchExists(branch string) (bool, error) {
+	err := c.do(http.MethodGet, fmt.Sprintf("/repos/%s/git/ref/heads/%s", c.repository, branch), nil, nil)
+
+	if isGithubNotFound(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func storeBaseline(client *githubClient, branch string, filePath string, data []byte, sha string) error {
+	message := "Update checksum baseline"
+
+	if commit := os.Getenv("GITHUB_SHA"); commit != "" {
+		message = fmt.Sprintf("Update checksum baseline for %s", commit)
+	}
+
+	exists, err := client.branchExists(branch)
+
+	if err != nil {
+		return fmt.Errorf("failed to look up baseline branch: %w", err)
+	}
+
+	if !exists {
+		return client.createOrphanBranch(branch, filePath, data, message)
+	}
+
+	if err := client.putFile(branch, filePath, data, sha, message); err != nil {
+		return fmt.Errorf("failed to update baseline: %w", err)
+	}
+
+	return nil
+}
+
+// storeBaselineSignature stores the detached signature next to the baseline,
+// which storeBaseline has created the branch for.
+func storeBaselineSignature(client *githubClient, signer manifestSigner, branch string, filePath string, data []byte) error {
+	signature, err := signer.sign(data)
+
+	if err != nil {
+		return err
+	}
+
+	_, sha, err := client.getFile(branch, filePath)
+
+	if err != nil && !isGithubNotFound(err) {
+		return err
+	}
+
+	return client.putFile(branch, filePath, signature, sha, "Update checksum baseline signature")
+}
+
+// runBaseline verifies against the baseline stored on cfg.baselineBranch, or
+// stores a new one when there is none or -baseline-update is set.
+func runBaseline(ctx context.Context, cfg config, projectDir string, opts scanOptions) bool {
+	client, err := newGithubClient(cfg.githubToken)
+
+	if err != nil {
+		fmt.Println("Error configuring GitHub client:", err)
+
+		return false
+	}
+
+	baselinePath := path.Base(filepath.ToSlash(cfg.outputFile))
+
+	data, sha, err := client.getFile(cfg.baselineBranch, baselinePath)
+
+	if err != nil && !isGithubNotFound(err) {
+		fmt.Println("Error loading baseline:", err)
+
+		return false
+	}
+
+	if data != nil && !cfg.baselineUpdate {
+		if cfg.manifestVerifier != nil {
+			signature, _, err := client.getFile(cfg.baselineBranch, signaturePath(baselinePath, cfg.signatureFormat))
+
+			if err == nil {
+				err = cfg.manifestVerifier.verify(data, signature)
+			}
+
+			if err != nil {
+				fmt.Println("Error loading baseline: untrusted baseline:", err)
+				return false
+			}
+		}
+
+		expected, err := checksum.ParseEntries(data)
+
+		if err != nil {
+			fmt.Println("Error loading baseline:", err)
+
+			return false
+		}
+
+		expected = removeTombstones(expected)
+
+		for i, entry := range expected {
+			expected[i] = opts.hash.DetectAlgorithm(entry)
+		}
+
+		return runVerify(ctx, cfg, projectDir, expected, opts)
+	}
+
+	checksums, err := calculateChecksums(ctx, projectDir, opts)
+
+	if err != nil {
+		fmt.Println("Error calculating checksums:", err)
+
+		return false
+	}
+
+	outputData, err := formatChecksums(checksums, cfg.format, cfg.header)
+
+	if err != nil {
+		fmt.Println("Error formatting checksums:", err)
+
+		return false
+	}
+
+	if err := storeBaseline(client, cfg.baselineBranch, baselinePath, outputData, sha); err != nil {
+		fmt.Println("Error storing baseline:", err)
+
+		return false
+	}
+
+	if cfg.manifestSigner != nil {
+		if err := storeBaselineSignature(client, cfg.manifestSigner, cfg.baselineBranch, signaturePath(baselinePath, cfg.signatureFormat), outputData); err != nil {
+			fmt.Println("Error storing baseline signature:", err)
+
+			return false
+		}
+	}
+
+	fmt.Printf("Stored baseline of %d files on branch %s\n", len(checksums), cfg.baselineBranch)
+
+	return true
+}
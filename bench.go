@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+const defaultBenchSample = "256MB"
+
+var benchWalkers = []int{1, 2, 4, 8, 16}
+
+var errSampleFull = errors.New("sample full")
+
+type benchSample struct {
+	paths []string
+	bytes int64
+}
+
+type benchResult struct {
+	name     string
+	duration time.Duration
+	files    int
+	bytes    int64
+}
+
+func (r benchResult) throughput() float64 {
+	return float64(r.bytes) / (1 << 20) / r.duration.Seconds()
+}
+
+func (r benchResult) filesPerSecond() float64 {
+	return float64(r.files) / r.duration.Seconds()
+}
+
+// collectBenchSample takes files in walk order until limit bytes are covered.
+func collectBenchSample(ctx context.Context, rootDir string, opts scanOptions, limit int64) (benchSample, error) {
+	var sample benchSample
+
+	err := walkFiles(ctx, rootDir, opts, func(path string, relativePath string) error {
+		if sample.bytes >= limit {
+			return errSampleFull
+		}
+
+		info, err := os.Stat(path)
+
+		if err != nil {
+			return err
+		}
+
+		sample.paths = append(sample.paths, path)
+		sample.bytes += info.Size()
+
+		return nil
+	})
+
+	if errors.Is(err, errSampleFull) {
+		err = nil
+	}
+
+	return sample, err
+}
+
+func benchWalk(ctx context.Context, rootDir string, opts scanOptions, walkers int) (benchResult, error) {
+	opts.walkers = walkers
+	result := benchResult{name: fmt.Sprint(walkers)}
+	started := time.Now()
+
+	err := walkFiles(ctx, rootDir, opts, func(path string, relativePath string) error {
+		result.files++
+
+		return nil
+	})
+
+	result.duration = time.Since(started)
+
+	return result, err
+}
+
+func benchAlgorithm(ctx context.Context, sample benchSample, opts scanOptions, algorithm string) (benchResult, error) {
+	result := benchResult{name: algorithm, files: len(sample.paths)}
+	started := time.Now()
+
+	for _, path := range sample.paths {
+		_, size, err := opts.digest(ctx, path, algorithm)
+
+		if err != nil {
+			return result, fmt.Errorf("failed to calculate checksum for %s: %w", path, err)
+		}
+
+		result.bytes += size
+	}
+
+	result.duration = time.Since(started)
+
+	return result, nil
+}
+
+func isChecksumAlgorithm(algorithm string) bool {
+	return strings.HasPrefix(algorithm, "crc")
+}
+
+// fastestWalkers picks the smallest walker count within 10% of the best walk
+// time, since extra walkers only add load once latency is hidden.
+func fastestWalkers(results []benchResult) string {
+	best := results[0]
+
+	for _, result := range results {
+		if result.duration < best.duration {
+			best = result
+		}
+	}
+
+	for _, result := range results {
+		if float64(result.duration) <= float64(best.duration)*1.1 {
+			return result.name
+		}
+	}
+
+	return best.name
+}
+
+func fastestAlgorithm(results []benchResult, include func(algorithm string) bool) string {
+	best := ""
+	fastest := 0.0
+
+	for _, result := range results {
+		if include(result.name) && result.throughput() > fastest {
+			best = result.name
+			fastest = result.throughput()
+		}
+	}
+
+	return best
+}
+
+func runBench(ctx context.Context, cfg config, projectDir string, opts scanOptions) bool {
+	limit, err := parseSize(cfg.benchSample)
+
+	if err != nil {
+		fmt.Println("Error configuring benchmark:", err)
+
+		return false
+	}
+
+	var walks []benchResult
+
+	for _, walkers := range benchWalkers {
+		result, err := benchWalk(ctx, projectDir, opts, walkers)
+
+		if err != nil {
+			fmt.Println("Error walking files:", err)
+
+			return false
+		}
+
+		walks = append(walks, result)
+	}
+
+	sample, err := collectBenchSample(ctx, projectDir, opts, limit)
+
+	if err != nil {
+		fmt.Println("Error sampling files:", err)
+
+		return false
+	}
+
+	// Read the sample once so every algorithm hashes from the page cache.
+	if _, err := benchAlgorithm(ctx, sample, opts, "crc32"); err != nil {
+		fmt.Println("Error reading sample:", err)
+
+		return false
+	}
+
+	var algorithms []benchResult
+
+	for _, algorithm := range checksum.SupportedAlgorithms() {
+		result, err := benchAlgorithm(ctx, sample, opts, algorithm)
+
+		if err != nil {
+			fmt.Println("Error benchmarking algorithm:", err)
+
+			return false
+		}
+
+		algorithms = append(algorithms, result)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "WALKERS\tFILES\tDURATION\tFILES/S")
+
+	for _, result := range walks {
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%.0f\n", result.name, result.files, result.duration.Round(time.Millisecond), result.filesPerSecond())
+	}
+
+	fmt.Fprintln(writer)
+	fmt.Fprintf(writer, "ALGORITHM\tFILES\tDURATION\tMB/S\n")
+
+	for _, result := range algorithms {
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%.1f\n", result.name, result.files, result.duration.Round(time.Millisecond), result.throughput())
+	}
+
+	writer.Flush()
+
+	fmt.Printf("Sampled %d files, %d bytes\n", len(sample.paths), sample.bytes)
+	fmt.Printf("Recommended: -walkers %s\n", fastestWalkers(walks))
+	fmt.Printf("Recommended: -algo %s (fastest cryptographic), -algo %s (fastest overall, change detection only)\n",
+		fastestAlgorithm(algorithms, func(algorithm string) bool { return !isChecksumAlgorithm(algorithm) }),
+		fastestAlgorithm(algorithms, func(string) bool { return true }),
+	)
+
+	return true
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// canonicalizeText hashes text files with CRLF and CR line endings turned
+// into LF and trailing spaces and tabs trimmed from every line, so
+// formatting-only changes keep their digest.
+const canonicalizeText = "text"
+
+// textSniffSize is how much of a file is checked for NUL bytes to tell text
+// from binary content, as git does.
+const textSniffSize = 8000
+
+func validateCanonicalize(mode string) error {
+	switch mode {
+	case "", canonicalizeText:
+		return nil
+	default:
+		return fmt.Errorf("unsupported canonicalization: %s", mode)
+	}
+}
+
+// textCanonicalizer reads a file in its canonical text form. Binary files,
+// with a NUL byte among their first bytes, are read unchanged.
+type textCanonicalizer struct {
+	source  *bufio.Reader
+	sniffed bool
+	binary  bool
+	// size counts the bytes read from the file, not the canonical ones.
+	size int64
+	// spaces holds whitespace that is only written if the line goes on.
+	spaces []byte
+	// afterCR is set after a CR, whose LF is already written.
+	afterCR bool
+	// out holds canonical bytes not returned yet from offset on.
+	out    []byte
+	offset int
+	buffer []byte
+}
+
+func newTextCanonicalizer(r io.Reader) *textCanonicalizer {
+	return &textCanonicalizer{source: bufio.NewReaderSize(r, 32*1024), buffer: make([]byte, 32*1024)}
+}
+
+func (c *textCanonicalizer) Read(p []byte) (int, error) {
+	if !c.sniffed {
+		c.sniffed = true
+
+		head, _ := c.source.Peek(textSniffSize)
+		c.binary = bytes.IndexByte(head, 0) >= 0
+	}
+
+	if c.binary {
+		n, err := c.source.Read(p)
+		c.size += int64(n)
+
+		return n, err
+	}
+
+	for c.offset == len(c.out) {
+		c.out, c.offset = c.out[:0], 0
+
+		n, err := c.source.Read(c.buffer)
+		c.size += int64(n)
+		c.canonicalize(c.buffer[:n])
+
+		if err == io.EOF {
+			// Trailing whitespace at the end of the file is dropped too.
+			c.spaces = c.spaces[:0]
+
+			if len(c.out) == 0 {
+				return 0, io.EOF
+			}
+
+			break
+		}
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, c.out[c.offset:])
+	c.offset += n
+
+	return n, nil
+}
+
+func (c *textCanonicalizer) canonicalize(data []byte) {
+	for _, b := range data {
+		switch b {
+		case ' ', '\t':
+			c.spaces = append(c.spaces, b)
+			c.afterCR = false
+		case '\r':
+			c.spaces = c.spaces[:0]
+			c.out = append(c.out, '\n')
+			c.afterCR = true
+		case '\n':
+			if !c.afterCR {
+				c.spaces = c.spaces[:0]
+				c.out = append(c.out, '\n')
+			}
+
+			c.afterCR = false
+		default:
+			c.out = append(c.out, c.spaces...)
+			c.out = append(c.out, b)
+			c.spaces = c.spaces[:0]
+			c.afterCR = false
+		}
+	}
+}
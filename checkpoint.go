@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+const checkpointFlushInterval = 100
+
+type checkpointRecord struct {
+	FileChecksum
+
+	ModTime int64 `json:"modTime"`
+}
+
+// checkpointOptions are the options shaping the recorded entries. A
+// checkpoint written with other options holds digests this run would not
+// compute, so it is discarded rather than resumed.
+type checkpointOptions struct {
+	Algorithm    string   `json:"algorithm"`
+	Overrides    []string `json:"overrides,omitempty"`
+	DigestBytes  int      `json:"digestBytes,omitempty"`
+	Encoding     string   `json:"encoding"`
+	CIDChunker   string   `json:"cidChunker,omitempty"`
+	FIPS         bool     `json:"fips,omitempty"`
+	Key          string   `json:"key,omitempty"`
+	Canonicalize string   `json:"canonicalize,omitempty"`
+	Inspect      []string `json:"inspect,omitempty"`
+}
+
+// checkpointHeader is the first line of a checkpoint.
+type checkpointHeader struct {
+	Options *checkpointOptions `json:"options"`
+}
+
+type checkpoint struct {
+	path      string
+	done      map[string]checkpointRecord
+	file      *os.File
+	writer    *bufio.Writer
+	pending   int
+	discarded bool
+}
+
+func checkpointPath(outputFile string) string {
+	return outputFile + ".partial"
+}
+
+// checkpointOptionsFor returns the options of a scan as its checkpoint header
+// records them. The key is recorded by its SHA-256 fingerprint only.
+func checkpointOptionsFor(opts scanOptions) checkpointOptions {
+	options := checkpointOptions{
+		Algorithm:    opts.hash.Algorithm,
+		DigestBytes:  opts.hash.DigestBytes,
+		Encoding:     opts.hash.Encoding,
+		CIDChunker:   opts.hash.CIDChunker,
+		FIPS:         opts.hash.FIPS,
+		Canonicalize: opts.canonicalize,
+	}
+
+	for _, override := range opts.hash.Overrides {
+		options.Overrides = append(options.Overrides, override.Pattern+"="+override.Algorithm)
+	}
+
+	if opts.hash.Key != nil {
+		fingerprint := sha256.Sum256(opts.hash.Key)
+		options.Key = hex.EncodeToString(fingerprint[:])
+	}
+
+	inspect := []struct {
+		name    string
+		enabled bool
+	}{
+		{"content-type", opts.contentType},
+		{"detect-encoding", opts.detectEncoding},
+		{"entropy", opts.entropy},
+		{"scan-secrets", opts.scanSecrets},
+		{"similarity", opts.similarity},
+	}
+
+	for _, inspector := range inspect {
+		if inspector.enabled {
+			options.Inspect = append(options.Inspect, inspector.name)
+		}
+	}
+
+	return options
+}
+
+// openCheckpoint loads the entries recorded by an interrupted run with the
+// same options and opens the checkpoint for appending. A checkpoint recorded
+// with other options, or before they were recorded, is started over.
+func openCheckpoint(path string, options checkpointOptions) (*checkpoint, error) {
+	c := &checkpoint{
+		path: path,
+		done: make(map[string]checkpointRecord),
+	}
+
+	header, err := json.Marshal(checkpointHeader{Options: &options})
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	valid := 0
+
+	if end := bytes.IndexByte(data, '\n'); end >= 0 && sameCheckpointOptions(data[:end], header) {
+		valid = end + 1
+	} else {
+		c.discarded = len(data) > 0
+		data = nil
+	}
+
+	for valid < len(data) {
+		end := bytes.IndexByte(data[valid:], '\n')
+
+		if end < 0 {
+			break
+		}
+
+		var record checkpointRecord
+
+		if err := json.Unmarshal(data[valid:valid+end], &record); err != nil {
+			break
+		}
+
+		c.done[record.Path] = record
+		valid += end + 1
+	}
+
+	if valid > 0 && valid < len(data) {
+		if err := os.Truncate(path, int64(valid)); err != nil {
+			return nil, fmt.Errorf("failed to repair checkpoint: %w", err)
+		}
+	}
+
+	flags := os.O_APPEND | os.O_CREATE | os.O_WRONLY
+
+	if valid == 0 {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+
+	c.file = file
+	c.writer = bufio.NewWriter(file)
+
+	if valid == 0 {
+		c.writer.Write(header)
+		c.writer.WriteByte('\n')
+
+		if err := c.writer.Flush(); err != nil {
+			file.Close()
+
+			return nil, fmt.Errorf("failed to write checkpoint: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// sameCheckpointOptions reports whether the header line of a checkpoint
+// records the options of header, however its JSON is laid out.
+func sameCheckpointOptions(line []byte, header []byte) bool {
+	var recorded checkpointHeader
+
+	if err := json.Unmarshal(line, &recorded); err != nil || recorded.Options == nil {
+		return false
+	}
+
+	normalized, err := json.Marshal(recorded)
+
+	return err == nil && bytes.Equal(normalized, header)
+}
+
+func (c *checkpoint) lookup(path string, info fs.FileInfo) (FileChecksum, bool) {
+	record, ok := c.done[path]
+
+	if !ok || record.Size != info.Size() || record.ModTime != info.ModTime().UnixNano() {
+		return FileChecksum{}, false
+	}
+
+	return record.FileChecksum, true
+}
+
+func (c *checkpoint) record(entry FileChecksum, info fs.FileInfo) error {
+	data, err := json.Marshal(checkpointRecord{
+		FileChecksum: entry,
+		ModTime:      info.ModTime().UnixNano(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	c.writer.Write(data)
+	c.writer.WriteByte('\n')
+	c.pending++
+
+	if c.pending < checkpointFlushInterval {
+		return nil
+	}
+
+	c.pending = 0
+
+	return c.writer.Flush()
+}
+
+func (c *checkpoint) close() error {
+	if err := c.writer.Flush(); err != nil {
+		c.file.Close()
+
+		return err
+	}
+
+	return c.file.Close()
+}
+
+func (c *checkpoint) remove() error {
+	c.file.Close()
+
+	return os.Remove(c.path)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func TestOpenCheckpointOptions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checksums.json.partial")
+	file := filepath.Join(dir, "a")
+
+	if err := os.WriteFile(file, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(file)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sha256Hex := checkpointOptionsFor(scanOptions{hash: checksum.Options{Algorithm: "sha256", Encoding: "hex"}})
+
+	c, err := openCheckpoint(path, sha256Hex)
+
+	if err != nil {
+		t.Fatalf("openCheckpoint() error = %v", err)
+	}
+
+	if err := c.record(FileChecksum{Path: "a", Checksum: abcSHA256, Algorithm: "sha256", Size: 3}, info); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		options       checkpointOptions
+		wantResumed   bool
+		wantDiscarded bool
+	}{
+		{"same options", sha256Hex, true, false},
+		{"other encoding", checkpointOptionsFor(scanOptions{hash: checksum.Options{Algorithm: "sha256", Encoding: "base64"}}), false, true},
+		{"other algorithm", checkpointOptionsFor(scanOptions{hash: checksum.Options{Algorithm: "sha512", Encoding: "hex"}}), false, true},
+		{"other digest bytes", checkpointOptionsFor(scanOptions{hash: checksum.Options{Algorithm: "sha256", Encoding: "hex", DigestBytes: 4}}), false, true},
+		{"a key", checkpointOptionsFor(scanOptions{hash: checksum.Options{Algorithm: "sha256", Encoding: "hex", Key: []byte("key")}}), false, true},
+		{"other overrides", checkpointOptionsFor(scanOptions{hash: checksum.Options{Algorithm: "sha256", Encoding: "hex", Overrides: []checksum.AlgorithmOverride{{Pattern: "*.iso", Algorithm: "blake3"}}}}), false, true},
+		{"canonicalized", checkpointOptionsFor(scanOptions{hash: checksum.Options{Algorithm: "sha256", Encoding: "hex"}, canonicalize: "text"}), false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Each case opens a copy, so a discarded checkpoint does not
+			// leave the next case without entries.
+			copied := filepath.Join(t.TempDir(), "checksums.json.partial")
+
+			if err := os.WriteFile(copied, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := openCheckpoint(copied, test.options)
+
+			if err != nil {
+				t.Fatalf("openCheckpoint() error = %v", err)
+			}
+
+			defer c.close()
+
+			if _, ok := c.lookup("a", info); ok != test.wantResumed {
+				t.Errorf("lookup() found = %v, want %v", ok, test.wantResumed)
+			}
+
+			if c.discarded != test.wantDiscarded {
+				t.Errorf("discarded = %v, want %v", c.discarded, test.wantDiscarded)
+			}
+		})
+	}
+
+	t.Run("recorded without options", func(t *testing.T) {
+		legacy := filepath.Join(t.TempDir(), "checksums.json.partial")
+		record := `{"path":"a","checksum":"` + abcSHA256 + `","algorithm":"sha256","size":3,"modTime":0}` + "\n"
+
+		if err := os.WriteFile(legacy, []byte(record), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := openCheckpoint(legacy, sha256Hex)
+
+		if err != nil {
+			t.Fatalf("openCheckpoint() error = %v", err)
+		}
+
+		defer c.close()
+
+		if len(c.done) != 0 || !c.discarded {
+			t.Errorf("openCheckpoint() resumed %d entries, discarded = %v, want the checkpoint discarded", len(c.done), c.discarded)
+		}
+	})
+}
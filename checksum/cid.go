@@ -0,0 +1,202 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	cidCodecRaw        = 0x55
+	cidCodecDagPB      = 0x70
+	cidMultihashSHA256 = 0x12
+
+	DefaultCIDChunker = "size-262144"
+	cidMaxLinks       = 174
+)
+
+var cidBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type cidLink struct {
+	cid      []byte
+	fileSize uint64
+	treeSize uint64
+}
+
+// cidHasher computes an IPFS CIDv1 for a file laid out as a balanced UnixFS
+// DAG with raw leaves, matching `ipfs add --cid-version=1 --raw-leaves`.
+type cidHasher struct {
+	chunkSize int
+	buffer    []byte
+	leaves    []cidLink
+}
+
+func parseCIDChunker(chunker string) (int, error) {
+	value, found := strings.CutPrefix(chunker, "size-")
+
+	if !found {
+		return 0, fmt.Errorf("unsupported cid chunker %q, expected size-<bytes>", chunker)
+	}
+
+	size, err := strconv.Atoi(value)
+
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid cid chunk size %q", value)
+	}
+
+	return size, nil
+}
+
+func newCIDHasher(chunkSize int) *cidHasher {
+	return &cidHasher{chunkSize: chunkSize}
+}
+
+func (h *cidHasher) Write(p []byte) (int, error) {
+	written := len(p)
+
+	for len(p) > 0 {
+		n := min(h.chunkSize-len(h.buffer), len(p))
+		h.buffer = append(h.buffer, p[:n]...)
+		p = p[n:]
+
+		if len(h.buffer) == h.chunkSize {
+			h.flush()
+		}
+	}
+
+	return written, nil
+}
+
+func (h *cidHasher) flush() {
+	h.leaves = append(h.leaves, cidLink{
+		cid:      encodeCID(cidCodecRaw, h.buffer),
+		fileSize: uint64(len(h.buffer)),
+		treeSize: uint64(len(h.buffer)),
+	})
+	h.buffer = h.buffer[:0]
+}
+
+func (h *cidHasher) Sum(b []byte) []byte {
+	leaves := h.leaves
+
+	if len(h.buffer) > 0 || len(leaves) == 0 {
+		leaves = append(leaves[:len(leaves):len(leaves)], cidLink{
+			cid:      encodeCID(cidCodecRaw, h.buffer),
+			fileSize: uint64(len(h.buffer)),
+			treeSize: uint64(len(h.buffer)),
+		})
+	}
+
+	if len(leaves) == 1 {
+		return append(b, leaves[0].cid...)
+	}
+
+	root := leaves[0]
+	leaves = leaves[1:]
+
+	for depth := 1; len(leaves) > 0; depth++ {
+		children := []cidLink{root}
+		children, leaves = fillCIDNode(children, leaves, depth)
+		root = buildCIDNode(children)
+	}
+
+	return append(b, root.cid...)
+}
+
+func fillCIDNode(children []cidLink, leaves []cidLink, depth int) ([]cidLink, []cidLink) {
+	for len(children) < cidMaxLinks && len(leaves) > 0 {
+		if depth == 1 {
+			children = append(children, leaves[0])
+			leaves = leaves[1:]
+
+			continue
+		}
+
+		var subtree []cidLink
+
+		subtree, leaves = fillCIDNode(nil, leaves, depth-1)
+		children = append(children, buildCIDNode(subtree))
+	}
+
+	return children, leaves
+}
+
+func buildCIDNode(children []cidLink) cidLink {
+	var fileSize, treeSize uint64
+
+	unixfs := appendProtoVarint(nil, 1, 2)
+
+	for _, child := range children {
+		fileSize += child.fileSize
+	}
+
+	unixfs = appendProtoVarint(unixfs, 3, fileSize)
+
+	for _, child := range children {
+		unixfs = appendProtoVarint(unixfs, 4, child.fileSize)
+	}
+
+	var node []byte
+
+	for _, child := range children {
+		var link []byte
+
+		link = appendProtoBytes(link, 1, child.cid)
+		link = appendProtoBytes(link, 2, nil)
+		link = appendProtoVarint(link, 3, child.treeSize)
+		node = appendProtoBytes(node, 2, link)
+		treeSize += child.treeSize
+	}
+
+	node = appendProtoBytes(node, 1, unixfs)
+
+	return cidLink{
+		cid:      encodeCID(cidCodecDagPB, node),
+		fileSize: fileSize,
+		treeSize: treeSize + uint64(len(node)),
+	}
+}
+
+func (h *cidHasher) Reset() {
+	h.buffer = h.buffer[:0]
+	h.leaves = nil
+}
+
+func (h *cidHasher) Size() int {
+	return 36
+}
+
+func (h *cidHasher) BlockSize() int {
+	return h.chunkSize
+}
+
+func encodeCID(codec uint64, block []byte) []byte {
+	digest := sha256.Sum256(block)
+
+	cid := binary.AppendUvarint(nil, 1)
+	cid = binary.AppendUvarint(cid, codec)
+	cid = binary.AppendUvarint(cid, cidMultihashSHA256)
+	cid = binary.AppendUvarint(cid, uint64(len(digest)))
+
+	return append(cid, digest[:]...)
+}
+
+func formatCID(cid []byte) string {
+	return "b" + strings.ToLower(cidBase32.EncodeToString(cid))
+}
+
+func appendProtoVarint(b []byte, field int, value uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3))
+
+	return binary.AppendUvarint(b, value)
+}
+
+func appendProtoBytes(b []byte, field int, value []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|2))
+	b = binary.AppendUvarint(b, uint64(len(value)))
+
+	return append(b, value...)
+}
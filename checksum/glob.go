@@ -0,0 +1,67 @@
+package checksum
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ValidateGlob reports patterns that MatchGlob could never match because of
+// malformed syntax, such as an unterminated character class.
+func ValidateGlob(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty pattern")
+	}
+
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// MatchGlob matches a slash-separated relative path against pattern. Patterns
+// without a slash match the base name, others match segment by segment with
+// "**" spanning any number of directories.
+func MatchGlob(pattern string, relativePath string) bool {
+	relativePath = strings.TrimPrefix(relativePath, "./")
+
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(relativePath))
+
+		return matched
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relativePath, "/"))
+}
+
+func matchSegments(pattern []string, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+
+		matched, err := path.Match(pattern[0], segments[0])
+
+		if err != nil || !matched {
+			return false
+		}
+
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+
+	return len(segments) == 0
+}
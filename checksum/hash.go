@@ -0,0 +1,377 @@
+package checksum
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/zeebo/blake3"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+)
+
+const (
+	DefaultAlgorithm = "sha1"
+	DefaultEncoding  = "hex"
+)
+
+// AlgorithmOverride selects a different algorithm for paths matching Pattern.
+type AlgorithmOverride struct {
+	Pattern   string
+	Algorithm string
+}
+
+// Options describes how digests are computed and encoded.
+type Options struct {
+	Algorithm   string
+	Key         []byte
+	Overrides   []AlgorithmOverride
+	DigestBytes int
+	Encoding    string
+	CIDChunker  string
+	// FIPS restricts hashing to the SHA-2 algorithms approved by FIPS 180-4
+	// and bypasses registered hashers.
+	FIPS bool
+}
+
+// ParseAlgorithmOverrides parses pattern=algorithm rules.
+func ParseAlgorithmOverrides(rules []string) ([]AlgorithmOverride, error) {
+	overrides := make([]AlgorithmOverride, 0, len(rules))
+
+	for _, rule := range rules {
+		pattern, algorithm, found := strings.Cut(rule, "=")
+
+		if !found || pattern == "" || algorithm == "" {
+			return nil, fmt.Errorf("invalid algorithm override %q, expected pattern=algorithm", rule)
+		}
+
+		if err := ValidateGlob(pattern); err != nil {
+			return nil, fmt.Errorf("invalid algorithm override %q: %w", rule, err)
+		}
+
+		overrides = append(overrides, AlgorithmOverride{
+			Pattern:   pattern,
+			Algorithm: algorithm,
+		})
+	}
+
+	return overrides, nil
+}
+
+// Validate reports unsupported algorithms, encodings or option combinations.
+func (o Options) Validate() error {
+	if o.DigestBytes < 0 {
+		return fmt.Errorf("digest length must not be negative, got %d", o.DigestBytes)
+	}
+
+	switch o.Encoding {
+	case "", "hex", "base64", "base64url", "multibase":
+	default:
+		return fmt.Errorf("unsupported digest encoding: %s", o.Encoding)
+	}
+
+	for _, algorithm := range o.Algorithms() {
+		if algorithm == "cid" && o.DigestBytes > 0 {
+			return fmt.Errorf("cid does not support truncated digests")
+		}
+	}
+
+	if _, err := o.NewHasher(o.algorithm()); err != nil {
+		return err
+	}
+
+	for _, override := range o.Overrides {
+		if _, err := o.NewHasher(override.Algorithm); err != nil {
+			return fmt.Errorf("override %s: %w", override.Pattern, err)
+		}
+	}
+
+	return nil
+}
+
+func (o Options) algorithm() string {
+	if o.Algorithm == "" {
+		return DefaultAlgorithm
+	}
+
+	return o.Algorithm
+}
+
+// Algorithms returns the default algorithm followed by those of every override.
+func (o Options) Algorithms() []string {
+	algorithms := []string{o.algorithm()}
+
+	for _, override := range o.Overrides {
+		algorithms = append(algorithms, override.Algorithm)
+	}
+
+	return algorithms
+}
+
+// Encode renders a digest, truncated to DigestBytes, in the configured encoding.
+func (o Options) Encode(algorithm string, digest []byte) string {
+	if algorithm == "cid" {
+		return formatCID(digest)
+	}
+
+	if o.DigestBytes > 0 && o.DigestBytes < len(digest) {
+		digest = digest[:o.DigestBytes]
+	}
+
+	switch o.Encoding {
+	case "base64":
+		return base64.StdEncoding.EncodeToString(digest)
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString(digest)
+	case "multibase":
+		return "u" + base64.RawURLEncoding.EncodeToString(digest)
+	}
+
+	return hex.EncodeToString(digest)
+}
+
+// EncodeFull is Encode without truncation.
+func (o Options) EncodeFull(algorithm string, digest []byte) string {
+	o.DigestBytes = 0
+
+	return o.Encode(algorithm, digest)
+}
+
+// AlgorithmFor returns the algorithm of the first override matching relativePath.
+func (o Options) AlgorithmFor(relativePath string) string {
+	relativePath = filepath.ToSlash(relativePath)
+
+	for _, override := range o.Overrides {
+		if MatchGlob(override.Pattern, relativePath) {
+			return override.Algorithm
+		}
+	}
+
+	return o.algorithm()
+}
+
+// DetectAlgorithm fills in the algorithm of an entry hashed before an
+// algorithm migration. An algorithm prefix such as sha256: on the digest is
+// split off; otherwise entries recording no algorithm get the first one whose
+// digests have the same length in the configured encoding, trying the
+// configured algorithm for the path first.
+func (o Options) DetectAlgorithm(entry Entry) Entry {
+	if entry.PresenceOnly {
+		return entry
+	}
+
+	if prefix, digest, found := strings.Cut(entry.Checksum, ":"); found && (entry.Algorithm == "" || entry.Algorithm == prefix) && isKnownAlgorithm(prefix) {
+		entry.Algorithm = prefix
+		entry.Checksum = digest
+	}
+
+	if entry.Algorithm != "" {
+		return entry
+	}
+
+	entry.Algorithm = o.AlgorithmFor(entry.Path)
+
+	if o.DigestBytes > 0 {
+		return entry
+	}
+
+	// Detection only sizes digests, so -fips refuses legacy algorithms later
+	// with a clear error rather than hiding them here.
+	o.FIPS = false
+
+	for _, candidate := range append([]string{entry.Algorithm}, builtinAlgorithms...) {
+		if candidate == "cid" {
+			continue
+		}
+
+		hasher, err := o.NewHasher(candidate)
+
+		if err != nil {
+			continue
+		}
+
+		if len(o.Encode(candidate, make([]byte, hasher.Size()))) == len(entry.Checksum) {
+			entry.Algorithm = candidate
+
+			break
+		}
+	}
+
+	return entry
+}
+
+func isKnownAlgorithm(name string) bool {
+	if _, ok := lookupHasher(name); ok {
+		return true
+	}
+
+	for _, algorithm := range builtinAlgorithms {
+		if algorithm == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewHasher returns a hash for algorithm, keyed with Key when one is set.
+func (o Options) NewHasher(algorithm string) (hash.Hash, error) {
+	if o.FIPS {
+		if !IsFIPSApproved(algorithm) {
+			return nil, fmt.Errorf("algorithm %s is not FIPS approved, use sha256, sha384 or sha512", algorithm)
+		}
+
+		return builtinHasher(algorithm, o.Key)
+	}
+
+	if algorithm != "cid" {
+		return newHasher(algorithm, o.Key)
+	}
+
+	if len(o.Key) > 0 {
+		return nil, fmt.Errorf("algorithm cid does not support keyed hashing")
+	}
+
+	chunker := o.CIDChunker
+
+	if chunker == "" {
+		chunker = DefaultCIDChunker
+	}
+
+	chunkSize, err := parseCIDChunker(chunker)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newCIDHasher(chunkSize), nil
+}
+
+// Digest hashes everything read from r, stopping early when ctx is done.
+func (o Options) Digest(ctx context.Context, r io.Reader, algorithm string) ([]byte, int64, error) {
+	hasher, err := o.NewHasher(algorithm)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size, err := io.Copy(hasher, contextReader{ctx: ctx, reader: r})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return hasher.Sum(nil), size, nil
+}
+
+// DigestFile hashes the named file of fsys.
+func (o Options) DigestFile(ctx context.Context, fsys fs.FS, name string, algorithm string) ([]byte, int64, error) {
+	file, err := fsys.Open(name)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer file.Close()
+
+	return o.Digest(ctx, file, algorithm)
+}
+
+func newHasher(algorithm string, key []byte) (hash.Hash, error) {
+	if hasher, ok := lookupHasher(algorithm); ok {
+		return hasher.New(key)
+	}
+
+	return builtinHasher(algorithm, key)
+}
+
+var builtinAlgorithms = []string{
+	"sha1", "sha256", "sha384", "sha512",
+	"blake2b", "blake2b-384", "blake2b-256", "blake2s", "blake3",
+	"crc32", "crc32c", "crc64", "crc64-iso", "cid",
+}
+
+func builtinHasher(algorithm string, key []byte) (hash.Hash, error) {
+	if len(key) > 0 && !isKeyedAlgorithm(algorithm) {
+		return nil, fmt.Errorf("algorithm %s does not support keyed hashing", algorithm)
+	}
+
+	switch algorithm {
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha384":
+		return sha512.New384(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "blake2b", "blake2b-512":
+		return blake2b.New512(key)
+	case "blake2b-384":
+		return blake2b.New384(key)
+	case "blake2b-256":
+		return blake2b.New256(key)
+	case "blake2s", "blake2s-256":
+		return blake2s.New256(key)
+	case "crc32":
+		return crc32.NewIEEE(), nil
+	case "crc32c":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case "crc64", "crc64-ecma":
+		return crc64.New(crc64.MakeTable(crc64.ECMA)), nil
+	case "crc64-iso":
+		return crc64.New(crc64.MakeTable(crc64.ISO)), nil
+	case "blake3":
+		if len(key) > 0 {
+			return blake3.NewKeyed(key)
+		}
+
+		return blake3.New(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+}
+
+// IsFIPSApproved reports whether algorithm is one of the SHA-2 algorithms
+// approved by FIPS 180-4.
+func IsFIPSApproved(algorithm string) bool {
+	switch algorithm {
+	case "sha256", "sha384", "sha512":
+		return true
+	}
+
+	return false
+}
+
+func isKeyedAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case "blake2b", "blake2b-512", "blake2b-384", "blake2b-256", "blake2s", "blake2s-256", "blake3":
+		return true
+	}
+
+	return false
+}
+
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
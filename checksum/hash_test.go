@@ -0,0 +1,232 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	abcSHA1   = "a9993e364706816aba3e25717850c26c9cd0d89d"
+	abcSHA256 = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	abcMD5    = "900150983cd24fb0d6963f7d28e17f72"
+)
+
+func TestParseAlgorithmOverrides(t *testing.T) {
+	overrides, err := ParseAlgorithmOverrides([]string{"*.iso=sha256", "docs/**=blake3"})
+
+	if err != nil {
+		t.Fatalf("ParseAlgorithmOverrides() error = %v", err)
+	}
+
+	want := []AlgorithmOverride{{Pattern: "*.iso", Algorithm: "sha256"}, {Pattern: "docs/**", Algorithm: "blake3"}}
+
+	if !reflect.DeepEqual(overrides, want) {
+		t.Errorf("ParseAlgorithmOverrides() = %+v, want %+v", overrides, want)
+	}
+
+	for _, rule := range []string{"*.iso", "=sha256", "*.iso="} {
+		if _, err := ParseAlgorithmOverrides([]string{rule}); err == nil || !strings.Contains(err.Error(), "expected pattern=algorithm") {
+			t.Errorf("ParseAlgorithmOverrides(%q) error = %v, want pattern=algorithm refused", rule, err)
+		}
+	}
+}
+
+func TestAlgorithmFor(t *testing.T) {
+	opts := Options{
+		Algorithm: "sha1",
+		Overrides: []AlgorithmOverride{
+			{Pattern: "*.iso", Algorithm: "sha256"},
+			{Pattern: "docs/**", Algorithm: "blake3"},
+			{Pattern: "docs/*.iso", Algorithm: "sha512"},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a.txt", "sha1"},
+		{"disk.iso", "sha256"},
+		{"images/disk.iso", "sha256"},
+		{"docs/a/b.md", "blake3"},
+		{"docs/disk.iso", "sha256"},
+		{"./docs/a.md", "blake3"},
+	}
+
+	for _, test := range tests {
+		if got := opts.AlgorithmFor(test.path); got != test.want {
+			t.Errorf("AlgorithmFor(%q) = %s, want %s", test.path, got, test.want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	digest := sha256.Sum256([]byte("abc"))
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"default is hex", Options{}, abcSHA256},
+		{"hex", Options{Encoding: "hex"}, abcSHA256},
+		{"base64", Options{Encoding: "base64"}, "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0="},
+		{"base64url", Options{Encoding: "base64url"}, "ungWv48Bz-pBQUDeXa4iI7ADYaOWF3qctBD_YfIAFa0"},
+		{"multibase", Options{Encoding: "multibase"}, "uungWv48Bz-pBQUDeXa4iI7ADYaOWF3qctBD_YfIAFa0"},
+		{"truncated", Options{DigestBytes: 4}, "ba7816bf"},
+		{"truncated base64", Options{DigestBytes: 3, Encoding: "base64"}, "ungW"},
+		{"longer than the digest", Options{DigestBytes: 64}, abcSHA256},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.opts.Encode("sha256", digest[:]); got != test.want {
+				t.Errorf("Encode() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEncodeFull(t *testing.T) {
+	digest := sha256.Sum256([]byte("abc"))
+	opts := Options{DigestBytes: 4}
+
+	if got := opts.EncodeFull("sha256", digest[:]); got != abcSHA256 {
+		t.Errorf("EncodeFull() = %q, want %q", got, abcSHA256)
+	}
+}
+
+func TestDetectAlgorithm(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          Options
+		entry         Entry
+		wantAlgorithm string
+		wantChecksum  string
+	}{
+		{
+			name:          "recorded algorithm is kept",
+			opts:          Options{Algorithm: "sha256"},
+			entry:         Entry{Path: "a", Checksum: abcSHA1, Algorithm: "sha1"},
+			wantAlgorithm: "sha1",
+			wantChecksum:  abcSHA1,
+		},
+		{
+			name:          "configured algorithm when the length matches",
+			opts:          Options{Algorithm: "sha256"},
+			entry:         Entry{Path: "a", Checksum: abcSHA256},
+			wantAlgorithm: "sha256",
+			wantChecksum:  abcSHA256,
+		},
+		{
+			name:          "legacy sha1 detected by length",
+			opts:          Options{Algorithm: "sha256"},
+			entry:         Entry{Path: "a", Checksum: abcSHA1},
+			wantAlgorithm: "sha1",
+			wantChecksum:  abcSHA1,
+		},
+		{
+			name:          "unknown length keeps the configured algorithm",
+			opts:          Options{Algorithm: "sha256"},
+			entry:         Entry{Path: "a", Checksum: abcMD5},
+			wantAlgorithm: "sha256",
+			wantChecksum:  abcMD5,
+		},
+		{
+			name:          "legacy algorithm detected under fips",
+			opts:          Options{Algorithm: "sha256", FIPS: true},
+			entry:         Entry{Path: "a", Checksum: abcSHA1},
+			wantAlgorithm: "sha1",
+			wantChecksum:  abcSHA1,
+		},
+		{
+			name:          "prefix is split off",
+			opts:          Options{Algorithm: "sha1"},
+			entry:         Entry{Path: "a", Checksum: "sha256:" + abcSHA256},
+			wantAlgorithm: "sha256",
+			wantChecksum:  abcSHA256,
+		},
+		{
+			name:          "prefix matching the recorded algorithm",
+			opts:          Options{},
+			entry:         Entry{Path: "a", Checksum: "sha256:" + abcSHA256, Algorithm: "sha256"},
+			wantAlgorithm: "sha256",
+			wantChecksum:  abcSHA256,
+		},
+		{
+			name:          "unknown prefix is part of the digest",
+			opts:          Options{Algorithm: "sha256"},
+			entry:         Entry{Path: "a", Checksum: "nope:" + abcSHA256},
+			wantAlgorithm: "sha256",
+			wantChecksum:  "nope:" + abcSHA256,
+		},
+		{
+			name:          "override chooses the algorithm for its paths",
+			opts:          Options{Algorithm: "sha1", Overrides: []AlgorithmOverride{{Pattern: "*.iso", Algorithm: "sha256"}}},
+			entry:         Entry{Path: "disk.iso", Checksum: abcSHA256},
+			wantAlgorithm: "sha256",
+			wantChecksum:  abcSHA256,
+		},
+		{
+			name:          "truncated digests are not sized",
+			opts:          Options{Algorithm: "sha256", DigestBytes: 20},
+			entry:         Entry{Path: "a", Checksum: abcSHA1},
+			wantAlgorithm: "sha256",
+			wantChecksum:  abcSHA1,
+		},
+		{
+			name:          "presence-only entries are left alone",
+			opts:          Options{Algorithm: "sha256"},
+			entry:         Entry{Path: "a", PresenceOnly: true},
+			wantAlgorithm: "",
+			wantChecksum:  "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.opts.DetectAlgorithm(test.entry)
+
+			if got.Algorithm != test.wantAlgorithm || got.Checksum != test.wantChecksum {
+				t.Errorf("DetectAlgorithm() = %s %q, want %s %q", got.Algorithm, got.Checksum, test.wantAlgorithm, test.wantChecksum)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{"defaults", Options{}, ""},
+		{"unsupported encoding", Options{Encoding: "base32"}, "unsupported digest encoding"},
+		{"negative digest length", Options{DigestBytes: -1}, "must not be negative"},
+		{"truncated cid", Options{Algorithm: "cid", DigestBytes: 8}, "cid does not support truncated digests"},
+		{"unknown algorithm", Options{Algorithm: "nope"}, "nope"},
+		{"unknown override", Options{Overrides: []AlgorithmOverride{{Pattern: "*.iso", Algorithm: "nope"}}}, "override *.iso"},
+		{"fips refuses sha1", Options{FIPS: true}, "sha1"},
+		{"fips allows sha256", Options{Algorithm: "sha256", FIPS: true}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.opts.Validate()
+
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Validate() = %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
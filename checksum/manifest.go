@@ -0,0 +1,192 @@
+package checksum
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Entry types. TypeDir marks an empty directory, which has no digest; the
+// others are only recorded on request.
+const (
+	TypeRegular = "regular"
+	TypeSymlink = "symlink"
+	TypeDir     = "dir"
+	TypeSocket  = "socket"
+	TypeFIFO    = "fifo"
+	TypeDevice  = "device"
+)
+
+// FileType returns the entry type of a file with mode, as reported by Lstat.
+func FileType(mode fs.FileMode) string {
+	switch {
+	case mode.IsRegular():
+		return TypeRegular
+	case mode&fs.ModeSymlink != 0:
+		return TypeSymlink
+	case mode.IsDir():
+		return TypeDir
+	case mode&fs.ModeSocket != 0:
+		return TypeSocket
+	case mode&fs.ModeNamedPipe != 0:
+		return TypeFIFO
+	default:
+		return TypeDevice
+	}
+}
+
+// Entry is a single file recorded in a manifest.
+type Entry struct {
+	Path      string `json:"path"`
+	Checksum  string `json:"checksum"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Size      int64  `json:"size"`
+
+	PresenceOnly bool `json:"presenceOnly,omitempty"`
+	LinkGroup    int  `json:"linkGroup,omitempty"`
+	// ModTime is the modification time in Unix nanoseconds, recorded for quick checks.
+	ModTime int64 `json:"modTime,omitempty"`
+	// Tags are labels such as critical or generated attached by path rules.
+	Tags []string `json:"tags,omitempty"`
+	// Type is TypeDir for empty directories and otherwise only set when file
+	// types are recorded.
+	Type string `json:"type,omitempty"`
+	// ContentType is the media type detected from the first bytes of the file.
+	ContentType string `json:"contentType,omitempty"`
+	// Encoding is the detected text encoding: ascii, utf-8, utf-16le,
+	// utf-16be, latin-1 or binary.
+	Encoding string `json:"encoding,omitempty"`
+	// Entropy is the Shannon entropy of the content in bits per byte.
+	Entropy float64 `json:"entropy,omitempty"`
+	// Secrets are the possible credentials found in the content.
+	Secrets []SecretHit `json:"secrets,omitempty"`
+	// IOC names the indicator of compromise the digest matched.
+	IOC string `json:"ioc,omitempty"`
+	// KnownGood names the known-good file the digest matched.
+	KnownGood string `json:"knownGood,omitempty"`
+	// Enrichment holds what each -enrich service knows about the digest,
+	// keyed by the -enrich value naming the service.
+	Enrichment map[string]json.RawMessage `json:"enrichment,omitempty"`
+	// Times are the timestamps recorded with -forensic.
+	Times *Timestamps `json:"times,omitempty"`
+	// Sketch holds the smallest hashes of the content-defined chunks of a
+	// text file, from which the similarity of modified content is estimated.
+	Sketch string `json:"sketch,omitempty"`
+	// Deleted is when the file was first found missing. Such tombstones keep
+	// the last-known entry of a deleted file and are not verified.
+	Deleted string `json:"deleted,omitempty"`
+}
+
+// Timestamps are the modified, accessed, changed and birth times of a file in
+// UTC, as 2006-01-02T15:04:05.000000000Z. Times the platform or file system
+// does not record are left out.
+type Timestamps struct {
+	Modified string `json:"modified,omitempty"`
+	Accessed string `json:"accessed,omitempty"`
+	Changed  string `json:"changed,omitempty"`
+	Born     string `json:"born,omitempty"`
+}
+
+// SecretHit is a possible credential found in a file by a named rule.
+type SecretHit struct {
+	Rule string `json:"rule"`
+	Line int    `json:"line"`
+}
+
+// Prefixed returns the entry with its digest written as algorithm:digest, the
+// form DetectAlgorithm splits again.
+func (e Entry) Prefixed() Entry {
+	if !e.PresenceOnly && e.Algorithm != "" && !strings.HasPrefix(e.Checksum, e.Algorithm+":") {
+		e.Checksum = e.Algorithm + ":" + e.Checksum
+	}
+
+	return e
+}
+
+// Manifest is a set of entries together with the options they were hashed with.
+type Manifest struct {
+	Entries []Entry
+	Options Options
+}
+
+// ParseManifest decodes a JSON or SRI manifest whose digests were produced with opts.
+func ParseManifest(data []byte, opts Options) (Manifest, error) {
+	entries, err := ParseEntries(data)
+
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	return Manifest{Entries: entries, Options: opts}, nil
+}
+
+// ManifestFromMap builds a manifest from a path to digest map, such as the one
+// generated by -format gosrc.
+func ManifestFromMap(digests map[string]string, opts Options) Manifest {
+	entries := make([]Entry, 0, len(digests))
+
+	for path, digest := range digests {
+		entries = append(entries, Entry{Path: path, Checksum: digest})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return Manifest{Entries: entries, Options: opts}
+}
+
+// ParseEntries decodes the entries of a versioned JSON manifest, a legacy
+// JSON array, an SRI map manifest or a checksum file read by ParseSums.
+func ParseEntries(data []byte) ([]Entry, error) {
+	if !isJSON(data) {
+		return ParseSums(data, "")
+	}
+
+	var document struct {
+		SchemaVersion *int    `json:"schemaVersion"`
+		Entries       []Entry `json:"entries"`
+	}
+
+	if err := json.Unmarshal(data, &document); err == nil && document.SchemaVersion != nil {
+		if *document.SchemaVersion > SchemaVersion {
+			return nil, fmt.Errorf("unsupported manifest schema version %d, this version supports up to %d", *document.SchemaVersion, SchemaVersion)
+		}
+
+		return document.Entries, nil
+	}
+
+	var entries []Entry
+
+	if err := json.Unmarshal(data, &entries); err == nil {
+		return entries, nil
+	}
+
+	var integrity map[string]string
+
+	if err := json.Unmarshal(data, &integrity); err != nil {
+		return nil, fmt.Errorf("failed to parse checksums file: %w", err)
+	}
+
+	for path, value := range integrity {
+		algorithm, checksum, found := strings.Cut(value, "-")
+
+		if !found {
+			return nil, fmt.Errorf("failed to parse integrity value for %s: %q", path, value)
+		}
+
+		entries = append(entries, Entry{
+			Path:      path,
+			Checksum:  checksum,
+			Algorithm: algorithm,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries, nil
+}
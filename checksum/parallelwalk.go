@@ -0,0 +1,253 @@
+package checksum
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sync"
+)
+
+// listingsPerWalker bounds how many directory listings each walker may read
+// ahead of the caller, so prefetching cannot hold the whole tree in memory.
+const listingsPerWalker = 256
+
+type dirListing struct {
+	dir     string
+	entries []fs.DirEntry
+	err     error
+	queued  bool
+	done    chan struct{}
+}
+
+// parallelWalker reads directories with a pool of goroutines ahead of a
+// single consumer that visits them in lexical order. Workers prefer the
+// listing the consumer waits for, then the most recently discovered
+// directory, which keeps prefetching close to the consumer's position.
+type parallelWalker struct {
+	fsys fs.FS
+	opts WalkOptions
+
+	mu       sync.Mutex
+	wake     *sync.Cond
+	urgent   []*dirListing
+	pending  []*dirListing
+	listings map[string]*dirListing
+	ahead    int
+	limit    int
+	stopped  bool
+}
+
+func newParallelWalker(fsys fs.FS, opts WalkOptions) *parallelWalker {
+	w := &parallelWalker{
+		fsys:     fsys,
+		opts:     opts,
+		listings: make(map[string]*dirListing),
+		limit:    opts.Concurrency * listingsPerWalker,
+	}
+
+	w.wake = sync.NewCond(&w.mu)
+
+	return w
+}
+
+func (w *parallelWalker) run(ctx context.Context, root string, fn fs.WalkDirFunc) error {
+	var workers sync.WaitGroup
+
+	for i := 0; i < w.opts.Concurrency; i++ {
+		workers.Add(1)
+
+		go func() {
+			defer workers.Done()
+			w.work()
+		}()
+	}
+
+	defer func() {
+		w.mu.Lock()
+		w.stopped = true
+		w.mu.Unlock()
+		w.wake.Broadcast()
+		workers.Wait()
+	}()
+
+	info, err := fs.Stat(w.fsys, root)
+
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = w.walkDir(ctx, root, fs.FileInfoToDirEntry(info), fn)
+	}
+
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+
+	return err
+}
+
+func (w *parallelWalker) walkDir(ctx context.Context, dir string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(dir, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			err = nil
+		}
+
+		return err
+	}
+
+	listing, err := w.wait(ctx, dir)
+
+	if err != nil {
+		return err
+	}
+
+	if listing.err != nil {
+		if err := fn(dir, d, listing.err); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				err = nil
+			}
+
+			return err
+		}
+	}
+
+	for _, entry := range listing.entries {
+		name := path.Join(dir, entry.Name())
+
+		if err := w.walkDir(ctx, name, entry, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				break
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// wait returns the listing of dir, reading it with priority if no worker has
+// picked it up yet.
+func (w *parallelWalker) wait(ctx context.Context, dir string) (*dirListing, error) {
+	w.mu.Lock()
+
+	listing, ok := w.listings[dir]
+
+	if !ok {
+		listing = w.schedule(dir)
+	}
+
+	if listing.queued {
+		w.urgent = append(w.urgent, listing)
+		w.wake.Broadcast()
+	}
+
+	w.mu.Unlock()
+
+	select {
+	case <-listing.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	w.mu.Lock()
+	delete(w.listings, dir)
+	w.ahead--
+	w.mu.Unlock()
+	w.wake.Broadcast()
+
+	return listing, nil
+}
+
+// schedule queues dir for reading and must be called with w.mu held.
+func (w *parallelWalker) schedule(dir string) *dirListing {
+	listing := &dirListing{dir: dir, queued: true, done: make(chan struct{})}
+
+	w.listings[dir] = listing
+	w.pending = append(w.pending, listing)
+	w.ahead++
+	w.wake.Signal()
+
+	return listing
+}
+
+func (w *parallelWalker) next() *dirListing {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for {
+		if w.stopped {
+			return nil
+		}
+
+		for len(w.urgent) > 0 {
+			listing := w.urgent[len(w.urgent)-1]
+			w.urgent = w.urgent[:len(w.urgent)-1]
+
+			if listing.queued {
+				listing.queued = false
+
+				return listing
+			}
+		}
+
+		for len(w.pending) > 0 && w.ahead <= w.limit {
+			listing := w.pending[len(w.pending)-1]
+			w.pending = w.pending[:len(w.pending)-1]
+
+			if listing.queued {
+				listing.queued = false
+
+				return listing
+			}
+		}
+
+		w.wake.Wait()
+	}
+}
+
+func (w *parallelWalker) work() {
+	for {
+		listing := w.next()
+
+		if listing == nil {
+			return
+		}
+
+		entries, err := fs.ReadDir(w.fsys, listing.dir)
+
+		// Stat every entry here, where it runs in parallel, so Skip and
+		// SkipDir callbacks reading Info do not stat again on the consumer.
+		for i, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				entries[i] = fs.FileInfoToDirEntry(info)
+			}
+		}
+
+		listing.entries = entries
+		listing.err = err
+
+		w.mu.Lock()
+
+		// Children are queued last-first so the next pop is the first one the
+		// consumer will visit.
+		for i := len(entries) - 1; i >= 0; i-- {
+			entry := entries[i]
+			name := path.Join(listing.dir, entry.Name())
+
+			if !entry.IsDir() || w.opts.isIgnored(name) || w.listings[name] != nil {
+				continue
+			}
+
+			if w.opts.SkipDir != nil && w.opts.SkipDir(name, entry) {
+				continue
+			}
+
+			w.schedule(name)
+		}
+
+		w.mu.Unlock()
+
+		close(listing.done)
+	}
+}
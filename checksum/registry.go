@@ -0,0 +1,279 @@
+package checksum
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+)
+
+// Hasher creates hash states for an algorithm registered with RegisterHasher.
+type Hasher interface {
+	New(key []byte) (hash.Hash, error)
+}
+
+// HasherFunc adapts a function to a Hasher.
+type HasherFunc func(key []byte) (hash.Hash, error)
+
+func (f HasherFunc) New(key []byte) (hash.Hash, error) {
+	return f(key)
+}
+
+// Formatter renders manifest entries for an output format registered with RegisterFormatter.
+type Formatter interface {
+	Format(entries []Entry) ([]byte, error)
+}
+
+// FormatterFunc adapts a function to a Formatter.
+type FormatterFunc func(entries []Entry) ([]byte, error)
+
+func (f FormatterFunc) Format(entries []Entry) ([]byte, error) {
+	return f(entries)
+}
+
+// Sink receives every written manifest, e.g. to upload it to an internal store.
+// data holds the manifest rendered in the configured output format.
+type Sink interface {
+	Send(ctx context.Context, entries []Entry, data []byte) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, entries []Entry, data []byte) error
+
+func (f SinkFunc) Send(ctx context.Context, entries []Entry, data []byte) error {
+	return f(ctx, entries, data)
+}
+
+// Enricher looks digests up in an external service, such as a reputation
+// service. It is given a batch of digests computed with algorithm and returns
+// what it knows per digest, leaving out the digests it knows nothing about.
+// The findings are recorded in the entries under the enricher's name.
+type Enricher interface {
+	Enrich(ctx context.Context, algorithm string, digests []string) (map[string]json.RawMessage, error)
+}
+
+// EnricherFunc adapts a function to an Enricher.
+type EnricherFunc func(ctx context.Context, algorithm string, digests []string) (map[string]json.RawMessage, error)
+
+func (f EnricherFunc) Enrich(ctx context.Context, algorithm string, digests []string) (map[string]json.RawMessage, error) {
+	return f(ctx, algorithm, digests)
+}
+
+// CredentialProvider resolves credential references of the form scheme:name,
+// so keys can be fetched from a secret store instead of a file on disk.
+type CredentialProvider interface {
+	Secret(ctx context.Context, name string) ([]byte, error)
+}
+
+// SigningProvider is implemented by credential providers that keep private
+// keys remotely and sign digests on request, such as a KMS.
+type SigningProvider interface {
+	Signer(ctx context.Context, name string) (crypto.Signer, error)
+}
+
+var registry = struct {
+	sync.RWMutex
+	hashers     map[string]Hasher
+	formatters  map[string]Formatter
+	sinks       map[string]Sink
+	enrichers   map[string]Enricher
+	credentials map[string]CredentialProvider
+}{
+	hashers:     make(map[string]Hasher),
+	formatters:  make(map[string]Formatter),
+	sinks:       make(map[string]Sink),
+	enrichers:   make(map[string]Enricher),
+	credentials: make(map[string]CredentialProvider),
+}
+
+// RegisterHasher makes a custom algorithm available under name. It panics if
+// name is empty, built in or already registered.
+func RegisterHasher(name string, hasher Hasher) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, err := builtinHasher(name, nil); err == nil || name == "cid" {
+		panic("checksum: cannot replace built-in algorithm " + name)
+	}
+
+	register(registry.hashers, "hasher", name, hasher)
+}
+
+// RegisterFormatter makes a custom output format available under name.
+func RegisterFormatter(name string, formatter Formatter) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	register(registry.formatters, "formatter", name, formatter)
+}
+
+// RegisterSink makes a custom manifest destination available under name.
+func RegisterSink(name string, sink Sink) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	register(registry.sinks, "sink", name, sink)
+}
+
+// RegisterEnricher makes a custom digest lookup available under name.
+func RegisterEnricher(name string, enricher Enricher) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	register(registry.enrichers, "enricher", name, enricher)
+}
+
+// RegisterCredentialProvider makes a custom credential source available under
+// scheme. It may also implement SigningProvider.
+func RegisterCredentialProvider(scheme string, provider CredentialProvider) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	register(registry.credentials, "credential provider", scheme, provider)
+}
+
+func register[T any](plugins map[string]T, kind string, name string, plugin T) {
+	if name == "" {
+		panic("checksum: " + kind + " name must not be empty")
+	}
+
+	if _, ok := plugins[name]; ok {
+		panic("checksum: " + kind + " " + name + " registered twice")
+	}
+
+	plugins[name] = plugin
+}
+
+// SupportedAlgorithms lists the built-in algorithms, without aliases, followed
+// by the registered ones in name order.
+func SupportedAlgorithms() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	algorithms := append([]string(nil), builtinAlgorithms...)
+	registered := make([]string, 0, len(registry.hashers))
+
+	for name := range registry.hashers {
+		registered = append(registered, name)
+	}
+
+	sort.Strings(registered)
+
+	return append(algorithms, registered...)
+}
+
+func lookupHasher(name string) (Hasher, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	hasher, ok := registry.hashers[name]
+
+	return hasher, ok
+}
+
+// LookupFormatter returns the formatter registered under name.
+func LookupFormatter(name string) (Formatter, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	formatter, ok := registry.formatters[name]
+
+	return formatter, ok
+}
+
+// LookupSink returns the sink registered under name.
+func LookupSink(name string) (Sink, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	sink, ok := registry.sinks[name]
+
+	return sink, ok
+}
+
+// LookupEnricher returns the enricher registered under name.
+func LookupEnricher(name string) (Enricher, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	enricher, ok := registry.enrichers[name]
+
+	return enricher, ok
+}
+
+// LookupCredentialProvider returns the credential provider registered under scheme.
+func LookupCredentialProvider(scheme string) (CredentialProvider, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	provider, ok := registry.credentials[scheme]
+
+	return provider, ok
+}
+
+// CommandSink returns a Sink that runs an external program with the rendered
+// manifest on stdin, for destinations implemented outside this module.
+func CommandSink(name string, args ...string) Sink {
+	return SinkFunc(func(ctx context.Context, entries []Entry, data []byte) error {
+		return RunCommand(ctx, data, name, args...)
+	})
+}
+
+// CommandEnricher returns an Enricher that runs an external program for every
+// batch, with {"algorithm": "sha256", "digests": [...]} on stdin, reading an
+// object from digest to findings from its stdout.
+func CommandEnricher(name string, args ...string) Enricher {
+	return EnricherFunc(func(ctx context.Context, algorithm string, digests []string) (map[string]json.RawMessage, error) {
+		input, err := json.Marshal(map[string]any{"algorithm": algorithm, "digests": digests})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var stdout bytes.Buffer
+
+		if err := runCommand(ctx, input, &stdout, name, args...); err != nil {
+			return nil, err
+		}
+
+		var findings map[string]json.RawMessage
+
+		if err := json.Unmarshal(stdout.Bytes(), &findings); err != nil {
+			return nil, fmt.Errorf("failed to parse %s output: %w", name, err)
+		}
+
+		return findings, nil
+	})
+}
+
+// RunCommand runs an external program with input on stdin, forwarding its
+// stdout and including its stderr in the returned error.
+func RunCommand(ctx context.Context, input []byte, name string, args ...string) error {
+	return runCommand(ctx, input, os.Stdout, name, args...)
+}
+
+func runCommand(ctx context.Context, input []byte, stdout io.Writer, name string, args ...string) error {
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if output := bytes.TrimSpace(stderr.Bytes()); len(output) > 0 {
+			return fmt.Errorf("command %s failed: %w: %s", name, err, output)
+		}
+
+		return fmt.Errorf("command %s failed: %w", name, err)
+	}
+
+	return nil
+}
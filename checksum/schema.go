@@ -0,0 +1,198 @@
+package checksum
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SchemaVersion is the version of the JSON manifest layout written by
+// MarshalManifest. It changes whenever fields change incompatibly.
+const SchemaVersion = 1
+
+// Schema is the JSON Schema describing the current manifest layout.
+//
+//go:embed manifest.schema.json
+var Schema []byte
+
+// Header holds the manifest fields written ahead of the entries, and how the
+// entries themselves are written.
+type Header struct {
+	// Compliance names the mode that restricted the algorithms, e.g. fips.
+	Compliance string
+	// Explain is a free-form JSON object describing how the manifest was
+	// produced.
+	Explain json.RawMessage
+	// DigestPrefix writes every digest as algorithm:digest, as OCI does.
+	DigestPrefix bool
+}
+
+type manifestDocument struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Compliance    string          `json:"compliance,omitempty"`
+	Explain       json.RawMessage `json:"explain,omitempty"`
+	Entries       []Entry         `json:"entries"`
+}
+
+// MarshalManifest encodes entries as a versioned JSON manifest.
+func MarshalManifest(entries []Entry) ([]byte, error) {
+	return MarshalManifestWithHeader(entries, Header{})
+}
+
+// MarshalExplainedManifest is MarshalManifest with a free-form JSON object
+// describing how the manifest was produced, placed ahead of the entries.
+func MarshalExplainedManifest(entries []Entry, explain json.RawMessage) ([]byte, error) {
+	return MarshalManifestWithHeader(entries, Header{Explain: explain})
+}
+
+// MarshalManifestWithHeader is MarshalManifest with the header fields set.
+func MarshalManifestWithHeader(entries []Entry, header Header) ([]byte, error) {
+	if entries == nil {
+		entries = []Entry{}
+	}
+
+	if header.DigestPrefix {
+		prefixed := make([]Entry, len(entries))
+
+		for i, entry := range entries {
+			prefixed[i] = entry.Prefixed()
+		}
+
+		entries = prefixed
+	}
+
+	return json.MarshalIndent(manifestDocument{SchemaVersion: SchemaVersion, Compliance: header.Compliance, Explain: header.Explain, Entries: entries}, "", "  ")
+}
+
+// SchemaError lists every structural problem found in a manifest.
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%d schema problems: %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// ValidateManifest checks that data is a well-formed versioned JSON manifest
+// and returns a *SchemaError describing every problem found. Unversioned
+// manifests written before schema versioning are reported as such.
+func ValidateManifest(data []byte) error {
+	var document map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &document); err != nil {
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+			return &SchemaError{Problems: []string{"manifest has no schemaVersion, regenerate it to upgrade from the unversioned array layout"}}
+		}
+
+		return &SchemaError{Problems: []string{fmt.Sprintf("manifest is not a JSON object: %v", err)}}
+	}
+
+	var problems []string
+
+	for field := range document {
+		if field != "schemaVersion" && field != "entries" && field != "explain" && field != "compliance" {
+			problems = append(problems, fmt.Sprintf("unknown field %q", field))
+		}
+	}
+
+	var version int
+
+	if raw, ok := document["schemaVersion"]; !ok {
+		problems = append(problems, "missing schemaVersion")
+	} else if err := json.Unmarshal(raw, &version); err != nil {
+		problems = append(problems, fmt.Sprintf("schemaVersion must be an integer: %s", raw))
+	} else if version != SchemaVersion {
+		problems = append(problems, fmt.Sprintf("unsupported schemaVersion %d, expected %d", version, SchemaVersion))
+	}
+
+	if raw, ok := document["compliance"]; ok {
+		var compliance string
+
+		if err := json.Unmarshal(raw, &compliance); err != nil {
+			problems = append(problems, "compliance must be a string")
+		}
+	}
+
+	if raw, ok := document["explain"]; ok {
+		var explain map[string]json.RawMessage
+
+		if err := json.Unmarshal(raw, &explain); err != nil {
+			problems = append(problems, "explain must be an object")
+		}
+	}
+
+	var entries []json.RawMessage
+
+	if raw, ok := document["entries"]; !ok {
+		problems = append(problems, "missing entries")
+	} else if err := json.Unmarshal(raw, &entries); err != nil || entries == nil {
+		problems = append(problems, "entries must be an array")
+	}
+
+	seen := make(map[string]bool, len(entries))
+
+	for i, raw := range entries {
+		for _, problem := range validateEntry(raw, seen) {
+			problems = append(problems, fmt.Sprintf("entries[%d]: %s", i, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+
+	return nil
+}
+
+func validateEntry(raw json.RawMessage, seen map[string]bool) []string {
+	var fields map[string]json.RawMessage
+
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return []string{"entry must be an object"}
+	}
+
+	var problems []string
+
+	for _, field := range []string{"path", "checksum", "size"} {
+		if _, ok := fields[field]; !ok {
+			problems = append(problems, "missing "+field)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+
+	var entry Entry
+
+	if err := decoder.Decode(&entry); err != nil {
+		return append(problems, err.Error())
+	}
+
+	switch {
+	case entry.Path == "":
+		problems = append(problems, "empty path")
+	case path.IsAbs(entry.Path) || entry.Path == ".." || strings.HasPrefix(entry.Path, "../") || path.Clean(entry.Path) != entry.Path:
+		problems = append(problems, fmt.Sprintf("path %q is not a clean relative path", entry.Path))
+	case seen[entry.Path]:
+		problems = append(problems, fmt.Sprintf("duplicate path %q", entry.Path))
+	}
+
+	seen[entry.Path] = true
+
+	if entry.Checksum == "" && !entry.PresenceOnly {
+		problems = append(problems, "empty checksum")
+	}
+
+	if entry.Size < 0 {
+		problems = append(problems, "negative size")
+	}
+
+	if entry.LinkGroup < 0 {
+		problems = append(problems, "negative linkGroup")
+	}
+
+	return problems
+}
@@ -0,0 +1,160 @@
+package checksum
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var bsdSumLine = regexp.MustCompile(`^([A-Za-z0-9-]+) \((.*)\) = ([0-9A-Fa-f]+)$`)
+
+// sumsAlgorithms maps hints in checksum file names, such as SHA256SUMS or
+// release.sha512, to algorithms.
+var sumsAlgorithms = []struct {
+	hint      string
+	algorithm string
+}{
+	{"sha512", "sha512"},
+	{"sha384", "sha384"},
+	{"sha256", "sha256"},
+	{"sha1", "sha1"},
+	{"b2", "blake2b"},
+	{"blake3", "blake3"},
+	{"md5", "md5"},
+}
+
+// ParseSums decodes GNU coreutils checksum files (sha256sum and friends),
+// BSD-tagged "SHA256 (path) = digest" files and SFV files. name is the file
+// name, used to tell the algorithm of untagged GNU files; the digest length
+// decides when it carries no hint.
+func ParseSums(data []byte, name string) ([]Entry, error) {
+	sfv := strings.HasSuffix(strings.ToLower(name), ".sfv")
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var entries []Entry
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, ";") || (!sfv && strings.HasPrefix(text, "#")) {
+			continue
+		}
+
+		var entry Entry
+		var err error
+
+		switch {
+		case sfv:
+			entry, err = parseSFVLine(text)
+		case bsdSumLine.MatchString(text):
+			match := bsdSumLine.FindStringSubmatch(text)
+			entry = Entry{Path: match[2], Checksum: strings.ToLower(match[3]), Algorithm: strings.ToLower(match[1])}
+		default:
+			entry, err = parseGNULine(text, name)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse checksums file line %d: %w", line, err)
+		}
+
+		entry.Path = strings.TrimPrefix(entry.Path, "./")
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checksums file: %w", err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries, nil
+}
+
+func parseGNULine(text string, name string) (Entry, error) {
+	escaped := strings.HasPrefix(text, "\\")
+
+	if escaped {
+		text = text[1:]
+	}
+
+	digest, path, found := strings.Cut(text, " ")
+
+	if !found || len(path) < 2 || (path[0] != ' ' && path[0] != '*') {
+		return Entry{}, fmt.Errorf("expected \"<digest>  <path>\", got %q", text)
+	}
+
+	path = path[1:]
+
+	if escaped {
+		path = strings.NewReplacer("\\\\", "\\", "\\n", "\n").Replace(path)
+	}
+
+	algorithm, err := sumsAlgorithm(name, digest)
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{Path: path, Checksum: strings.ToLower(digest), Algorithm: algorithm}, nil
+}
+
+func parseSFVLine(text string) (Entry, error) {
+	separator := strings.LastIndex(text, " ")
+
+	if separator <= 0 || len(text)-separator-1 != 8 {
+		return Entry{}, fmt.Errorf("expected \"<path> <crc32>\", got %q", text)
+	}
+
+	return Entry{
+		Path:      strings.TrimRight(text[:separator], " "),
+		Checksum:  strings.ToLower(text[separator+1:]),
+		Algorithm: "crc32",
+	}, nil
+}
+
+func sumsAlgorithm(name string, digest string) (string, error) {
+	lower := strings.ToLower(name)
+
+	for _, candidate := range sumsAlgorithms {
+		if strings.Contains(lower, candidate.hint) {
+			return candidate.algorithm, nil
+		}
+	}
+
+	switch len(digest) {
+	case 32:
+		return "md5", nil
+	case 40:
+		return "sha1", nil
+	case 64:
+		return "sha256", nil
+	case 96:
+		return "sha384", nil
+	case 128:
+		return "sha512", nil
+	}
+
+	return "", fmt.Errorf("cannot tell the algorithm of a %d character digest, name the file after it, e.g. SHA256SUMS", len(digest))
+}
+
+// ParseFile is ParseEntries for a file called name, which hints ParseSums at
+// the algorithm of checksum files.
+func ParseFile(data []byte, name string) ([]Entry, error) {
+	if isJSON(data) {
+		return ParseEntries(data)
+	}
+
+	return ParseSums(data, name)
+}
+
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
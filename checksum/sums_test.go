@@ -0,0 +1,139 @@
+package checksum
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSums(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		want []Entry
+	}{
+		{
+			name: "gnu text and binary mode",
+			file: "SHA256SUMS",
+			data: abcSHA256 + "  b.txt\n" + abcSHA256 + " *a.bin\n",
+			want: []Entry{
+				{Path: "a.bin", Checksum: abcSHA256, Algorithm: "sha256"},
+				{Path: "b.txt", Checksum: abcSHA256, Algorithm: "sha256"},
+			},
+		},
+		{
+			name: "algorithm from the digest length",
+			file: "checksums.txt",
+			data: abcSHA1 + "  a\n" + abcMD5 + "  b\n",
+			want: []Entry{
+				{Path: "a", Checksum: abcSHA1, Algorithm: "sha1"},
+				{Path: "b", Checksum: abcMD5, Algorithm: "md5"},
+			},
+		},
+		{
+			name: "name hint wins over the digest length",
+			file: "release.blake3",
+			data: abcSHA256 + "  a\n",
+			want: []Entry{{Path: "a", Checksum: abcSHA256, Algorithm: "blake3"}},
+		},
+		{
+			name: "digests are lowercased",
+			file: "SHA1SUMS",
+			data: strings.ToUpper(abcSHA1) + "  a\n",
+			want: []Entry{{Path: "a", Checksum: abcSHA1, Algorithm: "sha1"}},
+		},
+		{
+			name: "escaped names",
+			file: "SHA1SUMS",
+			data: "\\" + abcSHA1 + "  dir\\\\new\\nline\n",
+			want: []Entry{{Path: "dir\\new\nline", Checksum: abcSHA1, Algorithm: "sha1"}},
+		},
+		{
+			name: "spaces in names",
+			file: "SHA1SUMS",
+			data: abcSHA1 + "   leading space\n",
+			want: []Entry{{Path: " leading space", Checksum: abcSHA1, Algorithm: "sha1"}},
+		},
+		{
+			name: "dot slash prefix is dropped",
+			file: "SHA1SUMS",
+			data: abcSHA1 + "  ./d/a\n",
+			want: []Entry{{Path: "d/a", Checksum: abcSHA1, Algorithm: "sha1"}},
+		},
+		{
+			name: "comments, blank lines and crlf",
+			file: "SHA1SUMS",
+			data: "# generated\r\n\r\n" + abcSHA1 + "  a\r\n",
+			want: []Entry{{Path: "a", Checksum: abcSHA1, Algorithm: "sha1"}},
+		},
+		{
+			name: "bsd tagged",
+			file: "CHECKSUMS",
+			data: "SHA256 (a (1).txt) = " + abcSHA256 + "\nMD5 (b) = " + strings.ToUpper(abcMD5) + "\n",
+			want: []Entry{
+				{Path: "a (1).txt", Checksum: abcSHA256, Algorithm: "sha256"},
+				{Path: "b", Checksum: abcMD5, Algorithm: "md5"},
+			},
+		},
+		{
+			name: "sfv",
+			file: "release.SFV",
+			data: "; generated by cksfv\n#hash.txt 352441C2\nname with spaces.txt   352441c2\n",
+			want: []Entry{
+				{Path: "#hash.txt", Checksum: "352441c2", Algorithm: "crc32"},
+				{Path: "name with spaces.txt", Checksum: "352441c2", Algorithm: "crc32"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseSums([]byte(test.data), test.file)
+
+			if err != nil {
+				t.Fatalf("ParseSums() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseSums() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSumsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		wantErr string
+	}{
+		{"missing separator", "SHA1SUMS", abcSHA1 + "\n", "line 1"},
+		{"single space", "SHA1SUMS", abcSHA1 + " a\n", "expected"},
+		{"unknown digest length", "sums.txt", "# header\nabcdef  a\n", "line 2: cannot tell the algorithm of a 6 character digest"},
+		{"short sfv digest", "a.sfv", "a 1234\n", "expected \"<path> <crc32>\""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseSums([]byte(test.data), test.file)
+
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("ParseSums() error = %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	entries, err := ParseFile([]byte(`[{"path":"a","checksum":"`+abcSHA1+`","size":3}]`), "SHA256SUMS")
+
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Path != "a" || entries[0].Algorithm != "" {
+		t.Errorf("ParseFile() = %+v, want the JSON entry as recorded", entries)
+	}
+}
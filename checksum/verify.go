@@ -0,0 +1,105 @@
+package checksum
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Mismatch describes a manifest entry that did not verify.
+type Mismatch struct {
+	Path     string
+	Expected string
+	Actual   string
+	Err      error
+}
+
+// VerifyError lists every entry that failed verification.
+type VerifyError struct {
+	Mismatches []Mismatch
+}
+
+func (e *VerifyError) Error() string {
+	parts := make([]string, 0, len(e.Mismatches))
+
+	for _, mismatch := range e.Mismatches {
+		if mismatch.Err != nil {
+			parts = append(parts, fmt.Sprintf("%s: %v", mismatch.Path, mismatch.Err))
+
+			continue
+		}
+
+		parts = append(parts, fmt.Sprintf("%s: expected %s, got %s", mismatch.Path, mismatch.Expected, mismatch.Actual))
+	}
+
+	return fmt.Sprintf("%d files failed verification: %s", len(e.Mismatches), strings.Join(parts, "; "))
+}
+
+// VerifyFS checks every manifest entry against fsys, which may be an embed.FS.
+// Files present in fsys but missing from the manifest are ignored, and so are
+// tombstones of deleted files. It returns a *VerifyError when any entry is
+// missing or has a different digest.
+func VerifyFS(fsys fs.FS, manifest Manifest) error {
+	return VerifyFSContext(context.Background(), fsys, manifest)
+}
+
+// VerifyFSContext is VerifyFS with cancellation.
+func VerifyFSContext(ctx context.Context, fsys fs.FS, manifest Manifest) error {
+	var mismatches []Mismatch
+
+	for _, entry := range manifest.Entries {
+		mismatch, ok, err := verifyEntry(ctx, fsys, manifest.Options, entry)
+
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return &VerifyError{Mismatches: mismatches}
+	}
+
+	return nil
+}
+
+func verifyEntry(ctx context.Context, fsys fs.FS, opts Options, entry Entry) (Mismatch, bool, error) {
+	entry = opts.DetectAlgorithm(entry)
+	mismatch := Mismatch{Path: entry.Path, Expected: entry.Checksum}
+
+	if entry.Deleted != "" {
+		return mismatch, true, nil
+	}
+
+	if entry.PresenceOnly {
+		if _, err := fs.Stat(fsys, entry.Path); err != nil {
+			mismatch.Err = err
+
+			return mismatch, false, nil
+		}
+
+		return mismatch, true, nil
+	}
+
+	digest, _, err := opts.DigestFile(ctx, fsys, entry.Path, entry.Algorithm)
+
+	if ctx.Err() != nil {
+		return mismatch, false, ctx.Err()
+	}
+
+	if err != nil {
+		mismatch.Err = err
+
+		return mismatch, false, nil
+	}
+
+	if mismatch.Actual = opts.Encode(entry.Algorithm, digest); mismatch.Actual != entry.Checksum {
+		return mismatch, false, nil
+	}
+
+	return mismatch, true, nil
+}
@@ -0,0 +1,115 @@
+package checksum
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVerifyFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a":     {Data: []byte("abc")},
+		"d/b":   {Data: []byte("abc")},
+		"extra": {Data: []byte("not in the manifest")},
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		entries []Entry
+		want    []string
+	}{
+		{
+			name:    "all match",
+			opts:    Options{Algorithm: "sha256"},
+			entries: []Entry{{Path: "a", Checksum: abcSHA256}, {Path: "d/b", Checksum: abcSHA256}},
+		},
+		{
+			name:    "legacy algorithm",
+			opts:    Options{Algorithm: "sha256"},
+			entries: []Entry{{Path: "a", Checksum: abcSHA1}, {Path: "d/b", Checksum: "sha1:" + abcSHA1}},
+		},
+		{
+			name:    "modified",
+			opts:    Options{Algorithm: "sha256"},
+			entries: []Entry{{Path: "a", Checksum: abcSHA256}, {Path: "d/b", Checksum: "sha256:" + abcSHA1 + abcSHA1[:24]}},
+			want:    []string{"d/b"},
+		},
+		{
+			name:    "missing",
+			opts:    Options{Algorithm: "sha256"},
+			entries: []Entry{{Path: "gone", Checksum: abcSHA256}, {Path: "d/gone", PresenceOnly: true}},
+			want:    []string{"gone", "d/gone"},
+		},
+		{
+			name:    "presence only",
+			opts:    Options{Algorithm: "sha256"},
+			entries: []Entry{{Path: "a", PresenceOnly: true}},
+		},
+		{
+			name:    "tombstones are not verified",
+			opts:    Options{Algorithm: "sha256"},
+			entries: []Entry{{Path: "gone", Checksum: abcSHA256, Deleted: "2024-01-02T03:04:05Z"}},
+		},
+		{
+			name:    "truncated digests",
+			opts:    Options{Algorithm: "sha256", DigestBytes: 4},
+			entries: []Entry{{Path: "a", Checksum: abcSHA256[:8]}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := VerifyFS(fsys, Manifest{Entries: test.entries, Options: test.opts})
+
+			if len(test.want) == 0 {
+				if err != nil {
+					t.Fatalf("VerifyFS() = %v, want nil", err)
+				}
+
+				return
+			}
+
+			var verifyErr *VerifyError
+
+			if !errors.As(err, &verifyErr) {
+				t.Fatalf("VerifyFS() = %v, want a *VerifyError", err)
+			}
+
+			var got []string
+
+			for _, mismatch := range verifyErr.Mismatches {
+				got = append(got, mismatch.Path)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("VerifyFS() mismatches = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyFSReportsDigests(t *testing.T) {
+	fsys := fstest.MapFS{"a": {Data: []byte("abc")}}
+	expected := abcSHA256[:63] + "0"
+
+	err := VerifyFS(fsys, Manifest{Entries: []Entry{{Path: "a", Checksum: expected}}, Options: Options{Algorithm: "sha256"}})
+
+	var verifyErr *VerifyError
+
+	if !errors.As(err, &verifyErr) || len(verifyErr.Mismatches) != 1 {
+		t.Fatalf("VerifyFS() = %v, want one mismatch", err)
+	}
+
+	want := Mismatch{Path: "a", Expected: expected, Actual: abcSHA256}
+
+	if got := verifyErr.Mismatches[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("mismatch = %+v, want %+v", got, want)
+	}
+
+	if missing := VerifyFS(fsys, Manifest{Entries: []Entry{{Path: "b", Checksum: abcSHA256}}, Options: Options{Algorithm: "sha256"}}); !errors.Is(missing.(*VerifyError).Mismatches[0].Err, fs.ErrNotExist) {
+		t.Errorf("missing file error = %v, want fs.ErrNotExist", missing)
+	}
+}
@@ -0,0 +1,160 @@
+package checksum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// WalkOptions controls which files Walk visits.
+type WalkOptions struct {
+	// Ignore skips every path starting with one of these relative prefixes.
+	Ignore []string
+	// Skip, when set, excludes single files by their slash-separated relative
+	// path and directory entry, whose Info exposes ownership and mode bits.
+	Skip func(relativePath string, d fs.DirEntry) bool
+	// SkipDir, when set, prunes whole directories below the root.
+	SkipDir func(relativePath string, d fs.DirEntry) bool
+	// EmptyDir, when set, is called with every directory below the root that
+	// holds nothing at all and is not excluded by Skip.
+	EmptyDir func(relativePath string) error
+	// Root, when set, restricts the walk to this slash-separated subtree.
+	// Reported paths stay relative to fsys and a missing Root yields no files.
+	Root string
+	// Concurrency, when above 1, reads and stats directories with this many
+	// goroutines ahead of fn, which helps on high-latency file systems. fn is
+	// still called from a single goroutine in lexical order.
+	Concurrency int
+}
+
+// ScanOptions configures Calculate.
+type ScanOptions struct {
+	WalkOptions
+
+	Hash         Options
+	PresenceOnly []string
+	// OnEntry, when set, is called with every entry as soon as it is computed.
+	// Returning an error stops the scan.
+	OnEntry func(entry Entry) error
+}
+
+// Walk calls fn with the slash-separated relative path of every file in fsys,
+// in lexical order, honouring opts and stopping when ctx is done.
+func Walk(ctx context.Context, fsys fs.FS, opts WalkOptions, fn func(relativePath string) error) error {
+	root := opts.Root
+
+	if root == "" {
+		root = "."
+	}
+
+	if _, err := fs.Stat(fsys, root); root != "." && errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	walkFn := func(relativePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if opts.isIgnored(relativePath) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() && relativePath != root && opts.SkipDir != nil && opts.SkipDir(relativePath, d) {
+			return fs.SkipDir
+		}
+
+		if d.IsDir() && relativePath != root && opts.EmptyDir != nil {
+			entries, err := fs.ReadDir(fsys, relativePath)
+
+			if err != nil {
+				return err
+			}
+
+			if len(entries) == 0 && (opts.Skip == nil || !opts.Skip(relativePath, d)) {
+				return opts.EmptyDir(relativePath)
+			}
+		}
+
+		if d.IsDir() || (opts.Skip != nil && opts.Skip(relativePath, d)) {
+			return nil
+		}
+
+		return fn(relativePath)
+	}
+
+	if opts.Concurrency > 1 {
+		return newParallelWalker(fsys, opts).run(ctx, root, walkFn)
+	}
+
+	return fs.WalkDir(fsys, root, walkFn)
+}
+
+func (o WalkOptions) isIgnored(relativePath string) bool {
+	for _, pattern := range o.Ignore {
+		if strings.HasPrefix(relativePath, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Calculate hashes every file in fsys. On error it returns the entries
+// computed so far alongside the error.
+func Calculate(ctx context.Context, fsys fs.FS, opts ScanOptions) ([]Entry, error) {
+	var entries []Entry
+
+	err := Walk(ctx, fsys, opts.WalkOptions, func(relativePath string) error {
+		entry := Entry{Path: relativePath, PresenceOnly: true}
+
+		if !opts.isPresenceOnly(relativePath) {
+			algorithm := opts.Hash.AlgorithmFor(relativePath)
+
+			digest, size, err := opts.Hash.DigestFile(ctx, fsys, relativePath, algorithm)
+
+			if err != nil {
+				return fmt.Errorf("failed to calculate checksum for %s: %w", relativePath, err)
+			}
+
+			entry = Entry{
+				Path:      relativePath,
+				Checksum:  opts.Hash.Encode(algorithm, digest),
+				Algorithm: algorithm,
+				Size:      size,
+			}
+		}
+
+		if opts.OnEntry != nil {
+			if err := opts.OnEntry(entry); err != nil {
+				return err
+			}
+		}
+
+		entries = append(entries, entry)
+
+		return nil
+	})
+
+	return entries, err
+}
+
+func (o ScanOptions) isPresenceOnly(relativePath string) bool {
+	for _, pattern := range o.PresenceOnly {
+		if MatchGlob(pattern, relativePath) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,137 @@
+package checksum
+
+import (
+	"context"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWalk(t *testing.T) {
+	fsys := fstest.MapFS{
+		"b":              {Data: []byte("b")},
+		"a":              {Data: []byte("a")},
+		"d/c":            {Data: []byte("c")},
+		"d/e/f":          {Data: []byte("f")},
+		"skip.tmp":       {Data: []byte("tmp")},
+		"vendor/x":       {Data: []byte("x")},
+		"empty":          {Mode: fs.ModeDir},
+		"d/empty":        {Mode: fs.ModeDir},
+		".git/HEAD":      {Data: []byte("ref")},
+		"node_modules/y": {Data: []byte("y")},
+	}
+
+	isTemp := func(relativePath string, d fs.DirEntry) bool {
+		return strings.HasSuffix(relativePath, ".tmp")
+	}
+
+	isModules := func(relativePath string, d fs.DirEntry) bool {
+		return d.Name() == "node_modules"
+	}
+
+	tests := []struct {
+		name string
+		opts WalkOptions
+		want []string
+	}{
+		{
+			name: "every file in lexical order",
+			want: []string{".git/HEAD", "a", "b", "d/c", "d/e/f", "node_modules/y", "skip.tmp", "vendor/x"},
+		},
+		{
+			name: "ignored prefixes",
+			opts: WalkOptions{Ignore: []string{".git", "vendor"}},
+			want: []string{"a", "b", "d/c", "d/e/f", "node_modules/y", "skip.tmp"},
+		},
+		{
+			name: "skipped files",
+			opts: WalkOptions{Skip: isTemp},
+			want: []string{".git/HEAD", "a", "b", "d/c", "d/e/f", "node_modules/y", "vendor/x"},
+		},
+		{
+			name: "pruned directories",
+			opts: WalkOptions{SkipDir: isModules},
+			want: []string{".git/HEAD", "a", "b", "d/c", "d/e/f", "skip.tmp", "vendor/x"},
+		},
+		{
+			name: "subtree",
+			opts: WalkOptions{Root: "d"},
+			want: []string{"d/c", "d/e/f"},
+		},
+		{
+			name: "missing subtree",
+			opts: WalkOptions{Root: "nope"},
+		},
+		{
+			name: "concurrent",
+			opts: WalkOptions{Concurrency: 4, Ignore: []string{".git"}, Skip: isTemp, SkipDir: isModules},
+			want: []string{"a", "b", "d/c", "d/e/f", "vendor/x"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+
+			err := Walk(context.Background(), fsys, test.opts, func(relativePath string) error {
+				got = append(got, relativePath)
+
+				return nil
+			})
+
+			if err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Walk() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWalkEmptyDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a":         {Data: []byte("a")},
+		"empty":     {Mode: fs.ModeDir},
+		"d/empty":   {Mode: fs.ModeDir},
+		"d/skipped": {Mode: fs.ModeDir},
+	}
+
+	for _, concurrency := range []int{0, 4} {
+		var got []string
+
+		opts := WalkOptions{
+			Concurrency: concurrency,
+			Skip: func(relativePath string, d fs.DirEntry) bool {
+				return relativePath == "d/skipped"
+			},
+			EmptyDir: func(relativePath string) error {
+				got = append(got, relativePath)
+
+				return nil
+			},
+		}
+
+		if err := Walk(context.Background(), fsys, opts, func(string) error { return nil }); err != nil {
+			t.Fatalf("Walk() error = %v", err)
+		}
+
+		if want := []string{"d/empty", "empty"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Walk() with concurrency %d reported empty directories %v, want %v", concurrency, got, want)
+		}
+	}
+}
+
+func TestWalkCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := Walk(ctx, fstest.MapFS{"a": {Data: []byte("a")}}, WalkOptions{}, func(string) error { return nil })
+
+	if err != context.Canceled {
+		t.Errorf("Walk() error = %v, want context.Canceled", err)
+	}
+}
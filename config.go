@@ -0,0 +1,527 @@
+package main
+
+import (
+	"crypto"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix         = "CHECKSUM_"
+	defaultOutputBase = "root"
+)
+
+var commands = []string{"verify-file", "query", "scan-container", "scan-k8s", "compare-remote", "validate-manifest", "convert", "bench", "test-ignore", "release-checksums", "verify-restore", "fim", "install-service", "verify-audit-log"}
+
+type config struct {
+	command           string
+	args              []string
+	manifest          string
+	convertIn         string
+	convertOut        string
+	benchSample       string
+	releaseChecksums  string
+	sample            string
+	sampling          sampleSpec
+	sampleSeed        uint64
+	scrubOlderThan    string
+	par2              string
+	par2Redundancy    int
+	par2Dir           string
+	par2Command       string
+	scrubAge          time.Duration
+	scrubStateFile    string
+	verifyOrder       string
+	failFast          bool
+	emptyDirs         bool
+	recordType        bool
+	forensic          bool
+	tombstones        bool
+	contentType       bool
+	detectEncoding    bool
+	similarity        bool
+	entropy           bool
+	entropyThreshold  float64
+	scanSecrets       bool
+	iocBlocklist      string
+	iocURL            string
+	iocToken          string
+	failOnIOC         bool
+	knownGoodFiles    stringList
+	knownGood         map[string]string
+	knownGoodFilter   bool
+	enrichers         stringList
+	enrichBatch       int
+	enrichRate        float64
+	fimInterval       time.Duration
+	fimWatch          bool
+	fimAlert          string
+	fimDatabase       string
+	serviceName       string
+	serviceDir        string
+	serviceSchedule   string
+	serviceMode       string
+	expectMinFiles    int
+	expectMaxFiles    int
+	expectMinBytes    string
+	expectMaxBytes    string
+	expect            expectations
+	pprofAddr         string
+	cpuProfile        string
+	memProfile        string
+	where             string
+	sshCommand        string
+	summaryFile       string
+	header            checksum.Header
+	rekorSigner       crypto.Signer
+	manifestSigner    manifestSigner
+	manifestVerifier  manifestVerifier
+	severityRules     []severityRule
+	fromEnv           map[string]bool
+	fromConfig        map[string]bool
+	configFile        string
+	startedAt         time.Time
+	rootDir           string
+	outputFile        string
+	outputBase        string
+	splitOutput       bool
+	createOutputDir   bool
+	maxEntries        int
+	ignorePaths       string
+	algorithm         string
+	fips              bool
+	digestPrefix      bool
+	algorithmRules    stringList
+	presenceOnly      stringList
+	allowEmpty        stringList
+	failOnEmpty       bool
+	tagRules          stringList
+	tagPolicyRules    stringList
+	policyFile        string
+	regoPolicy        string
+	opaCommand        string
+	quarantineDir     string
+	keyFile           string
+	hashPaths         bool
+	pathKeyFile       string
+	digestBytes       int
+	encoding          string
+	cidChunker        string
+	format            string
+	goPackage         string
+	sinks             stringList
+	ociSubject        string
+	ociUsername       string
+	ociPassword       string
+	onFile            string
+	rekor             bool
+	rekorURL          string
+	rekorKey          string
+	signKMS           string
+	signKey           string
+	signKeyPassword   string
+	signatureFormat   string
+	requireSignature  bool
+	publicKey         string
+	ownedBy           stringList
+	skipWorldWritable bool
+	hardLinks         bool
+	sidecar           bool
+	sidecarExtension  string
+	sidecarFormat     string
+	oneFileSystem     bool
+	walkers           int
+	storageProfile    string
+	readPath          string
+	canonicalize      string
+	maxMemory         string
+	quickCheck        bool
+	onComplete        string
+	treeDigest        string
+	dirhashPrefix     string
+	verify            bool
+	explain           bool
+	scope             string
+	coverageCheck     bool
+	resume            bool
+	waitLock          bool
+	timeout           time.Duration
+	reportFile        string
+	reportFormat      string
+	notifyWebhook     string
+	notifyFormat      string
+	notifyAlways      bool
+	syslog            string
+	syslogFacility    string
+	auditLog          string
+	auditChain        bool
+	smtpHost          string
+	smtpPort          int
+	smtpUsername      string
+	smtpPassword      string
+	emailFrom         string
+	emailTo           string
+	fileIssue         bool
+	issueTitle        string
+	githubToken       string
+	baselineBranch    string
+	baselineUpdate    bool
+}
+
+func parseFlags() config {
+	cfg := config{startedAt: time.Now()}
+
+	flag.StringVar(&cfg.configFile, "config", "", "YAML file of flag values, e.g. algo: sha256, used for the flags given neither on the command line nor in the environment")
+	flag.StringVar(&cfg.rootDir, "dir", ".", "Root directory to calculate checksums")
+	flag.StringVar(&cfg.outputFile, "output", "checksums.json", "Output file to save checksums")
+	flag.StringVar(&cfg.outputBase, "output-base", defaultOutputBase, "Directory relative output paths are resolved against (root, cwd); absolute paths are used as is")
+	flag.BoolVar(&cfg.createOutputDir, "create-output-dir", true, "Create missing parent directories of the output file")
+	flag.BoolVar(&cfg.splitOutput, "split-output", false, "Write one manifest per top-level directory plus an index, next to the output file")
+	flag.IntVar(&cfg.maxEntries, "max-entries", 0, "Split manifests into numbered parts of at most N entries plus an index (0 disables)")
+	flag.StringVar(&cfg.ignorePaths, "ignore", "", "Comma-separated list of paths to ignore (relative to root)")
+	flag.StringVar(&cfg.algorithm, "algo", checksum.DefaultAlgorithm, "Checksum algorithm (sha1, sha256, sha384, sha512, blake2b, blake2b-384, blake2b-256, blake2s, blake3, crc32, crc32c, crc64, crc64-iso, cid)")
+	flag.BoolVar(&cfg.fips, "fips", fipsBuild, "Only allow the FIPS approved SHA-2 algorithms, defaulting -algo to sha256, and record the mode in the manifest header (always on in builds tagged fips)")
+	flag.Var(&cfg.algorithmRules, "algo-for", "Per-path algorithm override as pattern=algorithm (repeatable or comma-separated)")
+	flag.Var(&cfg.presenceOnly, "presence-only", "Record only the existence of files matching these patterns, without hashing (repeatable or comma-separated)")
+	flag.Var(&cfg.tagRules, "tag", "Tag entries matching a pattern in the manifest as pattern=tag, e.g. 'vendor/**=third-party' (repeatable or comma-separated)")
+	flag.Var(&cfg.tagPolicyRules, "tag-policy", "How verification treats changes to tagged files as tag=fatal or tag=warning; untagged changes are fatal (repeatable or comma-separated)")
+	flag.StringVar(&cfg.policyFile, "policy-file", "", "YAML file of rules mapping change kinds, paths and tags to the severities fail, warn or ignore; -tag-policy rules are checked first")
+	flag.StringVar(&cfg.regoPolicy, "rego-policy", "", "Rego policy file or directory in package checksum whose deny messages fail verification instead of the changes themselves; the report is its input")
+	flag.StringVar(&cfg.quarantineDir, "quarantine-dir", "", "Copy added and modified files into this directory, with the changes and their expected digests in changes.json, when verification fails")
+	flag.StringVar(&cfg.opaCommand, "opa-command", defaultOPACommand, "OPA binary evaluating -rego-policy, including any options")
+	flag.Var(&cfg.ownedBy, "only-owned-by", "Only include files owned by uid:N, gid:N, user:NAME or group:NAME (repeatable or comma-separated)")
+	flag.BoolVar(&cfg.skipWorldWritable, "skip-world-writable", false, "Skip files writable by everyone")
+	flag.BoolVar(&cfg.hardLinks, "hard-links", false, "Hash hard-linked files once and record their link groups in the manifest")
+	flag.IntVar(&cfg.walkers, "walkers", 0, "Number of goroutines reading directories ahead of hashing (0 derives it from -storage-profile)")
+	flag.StringVar(&cfg.storageProfile, "storage-profile", defaultStorageProfile, "Storage the tree lives on, used to tune -walkers (auto, ssd, hdd, network)")
+	flag.StringVar(&cfg.canonicalize, "canonicalize", "", "Hash files in a canonical form, given to both generation and verification: text normalizes line endings to LF and trims trailing whitespace in text files")
+	flag.StringVar(&cfg.readPath, "read-path", defaultReadPath, "How files are read for hashing (standard, fadvise); fadvise adds Linux read-ahead hints and falls back to standard elsewhere")
+	flag.StringVar(&cfg.maxMemory, "max-memory", "", "Spill manifest entries to a temporary file beyond roughly this much memory, e.g. 512MB (empty keeps all in memory)")
+	flag.BoolVar(&cfg.oneFileSystem, "one-file-system", false, "Do not descend into directories on other file systems than the root")
+	flag.StringVar(&cfg.keyFile, "key-file", "", "File or credential reference (env:, vault:) holding the key for keyed BLAKE2/BLAKE3 hashing")
+	flag.BoolVar(&cfg.hashPaths, "hash-paths", false, "Store an HMAC of each relative path instead of the literal path")
+	flag.StringVar(&cfg.pathKeyFile, "path-key-file", "", "File or credential reference (env:, vault:) holding the HMAC key used by -hash-paths")
+	flag.IntVar(&cfg.digestBytes, "digest-bytes", 0, "Truncate emitted digests to the first N bytes (0 keeps full digests)")
+	flag.BoolVar(&cfg.digestPrefix, "digest-prefix", false, "Write JSON manifest digests as algorithm:digest, e.g. sha256:<hex>, so the algorithm can be told from the value")
+	flag.StringVar(&cfg.encoding, "encoding", checksum.DefaultEncoding, "Digest encoding (hex, base64, base64url, multibase)")
+	flag.StringVar(&cfg.cidChunker, "cid-chunker", checksum.DefaultCIDChunker, "Chunker used by the cid algorithm (size-<bytes>)")
+	flag.StringVar(&cfg.format, "format", defaultFormat, "Output format (json, sri, sums, gosrc)")
+	flag.StringVar(&cfg.goPackage, "gosrc-package", defaultGoPackage, "Package name of the Go source written by -format gosrc")
+	flag.StringVar(&cfg.par2, "par2", "", "Create PAR2 recovery data of this redundancy for every hashed file, e.g. 10%, so damaged files can be repaired")
+	flag.StringVar(&cfg.par2Dir, "par2-dir", "", "Directory holding the -par2 recovery data (defaults to the output file with a .recovery suffix)")
+	flag.StringVar(&cfg.par2Command, "par2-command", defaultPAR2Command, "par2cmdline binary creating the -par2 recovery data, including any options")
+	flag.BoolVar(&cfg.sidecar, "sidecar", false, "Also write a checksum file next to every hashed file, e.g. app.tar.gz.sha256")
+	flag.StringVar(&cfg.sidecarExtension, "sidecar-extension", "", "Extension of sidecar files (defaults to the algorithm name)")
+	flag.StringVar(&cfg.sidecarFormat, "sidecar-format", defaultSidecarFormat, "Sidecar file format (gnu, bsd, plain)")
+	flag.Var(&cfg.sinks, "sink", "Send the written manifest to a registered sink, to exec:<command> on stdin or to an OCI registry as oci:<reference> (repeatable or comma-separated)")
+	flag.StringVar(&cfg.ociSubject, "oci-subject", "", "Image the manifest pushed by an oci: sink refers to, e.g. ghcr.io/org/app:1.0 (must be in the same repository)")
+	flag.StringVar(&cfg.ociUsername, "oci-username", "", "Registry username used by oci: sinks")
+	flag.StringVar(&cfg.ociPassword, "oci-password", "", "Registry password or token used by oci: sinks (defaults to CHECKSUM_OCI_PASSWORD)")
+	flag.StringVar(&cfg.onFile, "on-file", "", "Command run for every hashed file with its entry as JSON on stdin")
+	flag.StringVar(&cfg.onComplete, "on-complete", "", "Command run after the manifest is written with all entries as JSON on stdin")
+	flag.BoolVar(&cfg.rekor, "rekor", false, "Sign the aggregate digest of the manifest and record it in the Rekor transparency log")
+	flag.StringVar(&cfg.rekorURL, "rekor-url", defaultRekorURL, "Rekor instance used by -rekor")
+	flag.StringVar(&cfg.rekorKey, "rekor-key", "", "PEM encoded ECDSA or RSA private key signing the Rekor entry, as a file, credential reference (env:, vault:) or KMS key (awskms:, gcpkms:, azurekms:)")
+	flag.StringVar(&cfg.signKMS, "sign-kms", "", "KMS key signing the written manifest into <output>.sig, e.g. awskms:///alias/release-key, gcpkms://projects/.../cryptoKeyVersions/1 or azurekms://vault.vault.azure.net/key")
+	flag.StringVar(&cfg.signKey, "sign-key", "", "Private key signing the written manifest, as a file or credential reference: PEM ECDSA or RSA for cosign, or a minisign or signify secret key")
+	flag.StringVar(&cfg.signKeyPassword, "sign-key-password", "", "Password of an encrypted minisign -sign-key (defaults to CHECKSUM_SIGN_KEY_PASSWORD)")
+	flag.StringVar(&cfg.signatureFormat, "signature-format", defaultSignatureFormat, "Format of detached manifest signatures (cosign into <output>.sig, minisign into <output>.minisig, signify into <output>.sig)")
+	flag.BoolVar(&cfg.requireSignature, "require-signature", false, "Refuse to verify against a manifest or baseline whose detached signature does not validate with -pubkey")
+	flag.StringVar(&cfg.publicKey, "pubkey", "", "Public key checking manifest signatures, as a file, credential reference or KMS key: PEM for cosign, or a minisign or signify public key")
+	flag.StringVar(&cfg.treeDigest, "tree-digest", "", "Also compute a whole-tree digest (tar, dirhash)")
+	flag.StringVar(&cfg.dirhashPrefix, "dirhash-prefix", "", "Path prefix for dirhash tree digests, e.g. module@version")
+	flag.BoolVar(&cfg.verify, "verify", false, "Verify files against the existing output file instead of generating it")
+	flag.StringVar(&cfg.scope, "scope", "", "Only verify manifest entries below this relative path prefix, walking just that subtree")
+	flag.BoolVar(&cfg.coverageCheck, "coverage-check", false, "Report manifest entries skipped by the current ignore rules, and unlisted files older than the manifest, separately from changes")
+	flag.BoolVar(&cfg.explain, "explain", false, "Embed the options, patterns, default exclusions and environment inputs of the run in the JSON manifest header")
+	flag.BoolVar(&cfg.resume, "resume", false, "Checkpoint progress next to the output file and continue an interrupted run from it")
+	flag.BoolVar(&cfg.waitLock, "wait-lock", false, "Wait for a concurrent run to release the output lock instead of failing, for at most -timeout when it is set")
+	flag.DurationVar(&cfg.timeout, "timeout", 0, "Stop the run cleanly after this duration, e.g. 30m (0 disables)")
+	flag.BoolVar(&cfg.quickCheck, "quick-check", false, "Record modification times and skip hashing files whose size and modification time still match when verifying (omit to force full hashing)")
+	flag.StringVar(&cfg.reportFile, "report-file", "", "Write a verification report to this file")
+	flag.StringVar(&cfg.reportFormat, "report", defaultReportFormat, "Verification report format (json, sarif, junit, cef, leef)")
+	flag.StringVar(&cfg.notifyWebhook, "notify-webhook", "", "Webhook URL notified with verification results")
+	flag.StringVar(&cfg.notifyFormat, "notify-format", defaultNotifyFormat, "Webhook payload format (generic, slack, teams)")
+	flag.BoolVar(&cfg.notifyAlways, "notify-always", false, "Send notifications even when verification passes")
+	flag.StringVar(&cfg.auditLog, "audit-log", "", "Append a record of every generation and verification run, with the user, flags and a digest of the result, to this file")
+	flag.BoolVar(&cfg.auditChain, "audit-chain", false, "Chain every -audit-log record to the one before by its SHA-256, checked by the verify-audit-log command")
+	flag.StringVar(&cfg.syslog, "syslog", "", "Syslog server sent an RFC 5424 message per change and a summary of verification results, as udp://host:514, tcp://host:601 or unix:///dev/log")
+	flag.StringVar(&cfg.syslogFacility, "syslog-facility", defaultSyslogFacility, "Facility of the -syslog messages, e.g. daemon, auth or local0")
+	flag.StringVar(&cfg.smtpHost, "smtp-host", "", "SMTP server used for email notifications")
+	flag.IntVar(&cfg.smtpPort, "smtp-port", 587, "SMTP server port")
+	flag.StringVar(&cfg.smtpUsername, "smtp-username", "", "SMTP username")
+	flag.StringVar(&cfg.smtpPassword, "smtp-password", "", "SMTP password (defaults to CHECKSUM_SMTP_PASSWORD)")
+	flag.StringVar(&cfg.emailFrom, "email-from", "", "Sender address for email notifications")
+	flag.StringVar(&cfg.emailTo, "email-to", "", "Comma-separated recipients for email notifications")
+	flag.BoolVar(&cfg.fileIssue, "file-issue", false, "Open or update a GitHub issue when verification fails")
+	flag.StringVar(&cfg.issueTitle, "issue-title", defaultIssueTitle, "Title used to deduplicate filed GitHub issues")
+	flag.StringVar(&cfg.githubToken, "github-token", "", "GitHub token used to file issues and store baselines (defaults to GITHUB_TOKEN)")
+	flag.StringVar(&cfg.baselineBranch, "baseline-branch", "", "Branch storing the baseline manifest for scheduled drift detection")
+	flag.BoolVar(&cfg.baselineUpdate, "baseline-update", false, "Replace the stored baseline, on -baseline-branch or in the fim database, with the current tree (approval step)")
+
+	flag.StringVar(&cfg.manifest, "manifest", "", "Manifest read by the query and verify-restore commands (defaults to the output file)")
+	flag.StringVar(&cfg.sample, "sample", "", "Hash only a random sample of the manifest entries when verifying, as a percentage like 5% or a number of entries; all are still checked for presence")
+	flag.StringVar(&cfg.verifyOrder, "verify-order", verifyOrderManifest, "Order entries are verified in: manifest, recent (most recently modified first) or priority (tagged files first, then recent), so likely failures surface before a timeout")
+	flag.IntVar(&cfg.expectMinFiles, "expect-min-files", 0, "Fail unless the tree, or the manifest when verifying, holds at least this many files")
+	flag.IntVar(&cfg.expectMaxFiles, "expect-max-files", 0, "Fail if the tree, or the manifest when verifying, holds more than this many files")
+	flag.StringVar(&cfg.expectMinBytes, "expect-total-bytes-min", "", "Fail unless the files add up to at least this size, e.g. 1GB")
+	flag.StringVar(&cfg.expectMaxBytes, "expect-total-bytes-max", "", "Fail if the files add up to more than this size, e.g. 10GB")
+	flag.BoolVar(&cfg.emptyDirs, "empty-dirs", false, "Record empty directories as entries of type dir, so verification notices when one disappears")
+	flag.BoolVar(&cfg.recordType, "record-type", false, "Record the type of every entry: regular, symlink, dir, socket, fifo or device; sockets, FIFOs and devices are recorded without a digest")
+	flag.BoolVar(&cfg.forensic, "forensic", false, "Record the modified, accessed, changed and birth times of every entry where the platform keeps them, captured before reading, for timeline evidence")
+	flag.BoolVar(&cfg.tombstones, "tombstones", false, "Keep files gone since the previous manifest at the output file as tombstone entries with their last-known digest and the time they were found missing")
+	flag.BoolVar(&cfg.contentType, "content-type", false, "Record the media type of every file, detected from its first bytes")
+	flag.BoolVar(&cfg.detectEncoding, "detect-encoding", false, "Record the text encoding of every file: ascii, utf-8, utf-16le, utf-16be, latin-1 or binary")
+	flag.BoolVar(&cfg.similarity, "similarity", false, "Record a sketch of the content-defined chunks of every text file and report how similar modified files still are to it when verifying")
+	flag.BoolVar(&cfg.entropy, "entropy", false, "Record the Shannon entropy of every file and flag new or changed files that look packed or encrypted")
+	flag.Float64Var(&cfg.entropyThreshold, "entropy-threshold", defaultEntropyThreshold, "Entropy in bits per byte from which -entropy flags a file of at least 1KB")
+	flag.BoolVar(&cfg.scanSecrets, "scan-secrets", false, "Look for credentials such as AWS keys and private key headers while hashing and report the files holding them")
+	flag.StringVar(&cfg.iocBlocklist, "ioc-blocklist", "", "File of known-bad digests, one per line optionally followed by a name, flagged when a hashed file matches")
+	flag.StringVar(&cfg.iocURL, "ioc-url", "", "Threat intel API URL asked in batches which computed digests are known indicators of compromise")
+	flag.StringVar(&cfg.iocToken, "ioc-token", "", "Bearer token sent to -ioc-url")
+	flag.BoolVar(&cfg.failOnIOC, "fail-on-ioc", false, "Fail without writing the manifest when a hashed file matches -ioc-blocklist or -ioc-url")
+	flag.Var(&cfg.knownGoodFiles, "known-good", "Known-good hash set, such as the NSRL RDS NSRLFile.txt or a digest allowlist, whose matches are annotated (repeatable or comma-separated)")
+	flag.Var(&cfg.enrichers, "enrich", "Service looking up the computed digests, a registered enricher or exec:command, whose findings are recorded in the entries (repeatable or comma-separated)")
+	flag.IntVar(&cfg.enrichBatch, "enrich-batch", defaultEnrichBatch, "Number of digests given to an -enrich service per lookup")
+	flag.Float64Var(&cfg.enrichRate, "enrich-rate", 0, "Most -enrich lookups per second (0 leaves them unlimited)")
+	flag.BoolVar(&cfg.knownGoodFilter, "known-good-filter", false, "Leave files matching -known-good out of manifests and out of verification reports as added files")
+	flag.BoolVar(&cfg.failOnEmpty, "fail-on-empty", false, "Fail when zero-byte files outside -allow-empty are found while generating, or appear or replace content when verifying, whatever the severity policies say")
+	flag.Var(&cfg.allowEmpty, "allow-empty", "Patterns of files expected to be empty, such as '**/__init__.py', which -fail-on-empty accepts (repeatable or comma-separated)")
+	flag.BoolVar(&cfg.failFast, "fail-fast", false, "Stop verifying at the first change that fails instead of reporting every change")
+	flag.StringVar(&cfg.scrubOlderThan, "scrub-older-than", "", "Only hash the entries not verified within this age, e.g. 30d, tracked next to the output file; with -sample, at most that many of the longest unverified entries per run")
+	flag.Uint64Var(&cfg.sampleSeed, "sample-seed", 0, "Seed choosing the -sample entries, to check the same ones again (random by default)")
+	flag.StringVar(&cfg.where, "where", "", "Filter expression for the query command, e.g. 'path glob \"assets/**\" and size > 1MB'")
+
+	flag.StringVar(&cfg.convertIn, "in", "", "Manifest read by the convert command (defaults to the output file)")
+	flag.StringVar(&cfg.convertOut, "out", "", "File written by the convert command in the format given by -format")
+
+	flag.StringVar(&cfg.benchSample, "bench-sample", defaultBenchSample, "Amount of data hashed per algorithm by the bench command")
+
+	flag.DurationVar(&cfg.fimInterval, "fim-interval", defaultFIMInterval, "How often the fim command verifies the whole tree")
+	flag.BoolVar(&cfg.fimWatch, "fim-watch", true, "Also verify shortly after inotify reports a change, on Linux")
+	flag.StringVar(&cfg.fimAlert, "fim-alert", "", "Command run by the fim command with the new changes of a failed verification as JSON on stdin")
+	flag.StringVar(&cfg.fimDatabase, "fim-db", "", "SQLite database the fim command keeps its baseline and drift in, approved with fim -baseline-update (defaults to the output file with a .db extension)")
+
+	flag.StringVar(&cfg.serviceName, "service-name", defaultServiceName, "Name of the systemd units or Windows service written by the install-service command")
+	flag.StringVar(&cfg.serviceDir, "service-dir", defaultServiceDir, "Directory the install-service command writes the systemd units into")
+	flag.StringVar(&cfg.serviceSchedule, "service-schedule", defaultServiceSchedule, "OnCalendar schedule of the scan timer written by the install-service command, e.g. hourly")
+	flag.StringVar(&cfg.serviceMode, "service-mode", serviceModeScan, "Units written by the install-service command: scan for a timer running this configuration, fim for a long-running fim service")
+
+	flag.StringVar(&cfg.releaseChecksums, "release-checksums-name", defaultReleaseChecksumsName, "Asset name of the checksums file uploaded by the release-checksums command")
+
+	flag.StringVar(&cfg.pprofAddr, "pprof", "", "Serve pprof debug endpoints on this address, e.g. :6060")
+	flag.StringVar(&cfg.cpuProfile, "cpuprofile", "", "Write a CPU profile of the run to this file")
+	flag.StringVar(&cfg.memProfile, "memprofile", "", "Write a heap profile to this file when the run ends")
+
+	flag.StringVar(&cfg.sshCommand, "ssh-command", defaultSSHCommand, "SSH client used by the compare-remote command, including any options")
+
+	args := os.Args[1:]
+
+	if len(args) > 0 && isCommand(args[0]) {
+		cfg.command = args[0]
+		args = args[1:]
+	}
+
+	flag.Usage = usage
+	flag.CommandLine.Parse(args)
+
+	given := make(map[string]bool)
+
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	fromEnv, err := applyEnvironment(given)
+
+	if err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg.fromEnv = fromEnv
+
+	fromConfig, err := applyConfigFile(cfg.configFile)
+
+	if err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), err)
+		os.Exit(2)
+	}
+
+	cfg.fromConfig = fromConfig
+
+	cfg.args = flag.Args()
+
+	return cfg
+}
+
+func usage() {
+	output := flag.CommandLine.Output()
+
+	fmt.Fprintf(output, "Usage: %s [command] [flags] [args]\n\nCommands: %s\n\nFlags:\n", os.Args[0], strings.Join(commands, ", "))
+	flag.PrintDefaults()
+	fmt.Fprintf(output, "\nEvery flag can also be set with a %sNAME environment variable, e.g. %s for -dir.\nFlags given on the command line take precedence over the environment, which\ntakes precedence over the -config file.\n", envPrefix, envName("dir"))
+}
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnvironment fills the flags not given on the command line from their
+// CHECKSUM_* variables and returns the names of the flags it set. A flag given
+// empty on the command line still wins; the action entrypoint leaves out the
+// inputs that are not set, so those fall back to the environment.
+func applyEnvironment(given map[string]bool) (map[string]bool, error) {
+	applied := make(map[string]bool)
+
+	var err error
+
+	flag.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+
+		if !ok || given[f.Name] || err != nil {
+			return
+		}
+
+		if setErr := flag.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, envName(f.Name), setErr)
+		}
+
+		applied[f.Name] = true
+	})
+
+	return applied, err
+}
+
+// applyConfigFile fills the flags set neither on the command line nor in the
+// environment from a YAML mapping of flag names to values, and returns the
+// names of the flags it set. A sequence sets a flag once per item, which list
+// flags such as -ignore and -tag collect.
+func applyConfigFile(configFile string) (map[string]bool, error) {
+	if configFile == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(configFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document yaml.Node
+
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configFile, err)
+	}
+
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+
+	mapping := document.Content[0]
+
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid config file %s: expected a mapping of flag names to values", configFile)
+	}
+
+	set := make(map[string]bool)
+
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	applied := make(map[string]bool)
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		name, value := mapping.Content[i].Value, mapping.Content[i+1]
+
+		if name == "config" || flag.Lookup(name) == nil {
+			return nil, fmt.Errorf("invalid config file %s: unknown flag %s", configFile, name)
+		}
+
+		if set[name] {
+			continue
+		}
+
+		values := []*yaml.Node{value}
+
+		if value.Kind == yaml.SequenceNode {
+			values = value.Content
+		}
+
+		for _, item := range values {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("invalid config file %s: %s must be a value or a list of values", configFile, name)
+			}
+
+			if err := flag.Set(name, item.Value); err != nil {
+				return nil, fmt.Errorf("invalid value %q for %s in %s: %w", item.Value, name, configFile, err)
+			}
+		}
+
+		applied[name] = true
+	}
+
+	return applied, nil
+}
+
+// outputPath resolves the output file against the scanned root or the working
+// directory, so the manifest can live outside the tree it fingerprints.
+func (c config) outputPath(projectDir string) (string, error) {
+	if filepath.IsAbs(c.outputFile) {
+		return filepath.Clean(c.outputFile), nil
+	}
+
+	switch c.outputBase {
+	case "root":
+		return filepath.Join(projectDir, c.outputFile), nil
+	case "cwd":
+		return filepath.Abs(c.outputFile)
+	}
+
+	return "", fmt.Errorf("unsupported output base: %s", c.outputBase)
+}
+
+func isFlagSet(name string) bool {
+	set := false
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+func (c config) email() emailOptions {
+	var recipients stringList
+
+	recipients.Set(c.emailTo)
+
+	return emailOptions{
+		host:     c.smtpHost,
+		port:     c.smtpPort,
+		username: c.smtpUsername,
+		password: c.smtpPassword,
+		from:     c.emailFrom,
+		to:       recipients,
+	}
+}
+
+func isCommand(name string) bool {
+	for _, command := range commands {
+		if command == name {
+			return true
+		}
+	}
+
+	return false
+}
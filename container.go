@@ -0,0 +1,348 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+type dockerClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+type dockerError struct {
+	status  int
+	message string
+}
+
+func (e *dockerError) Error() string {
+	return fmt.Sprintf("docker API returned %d: %s", e.status, e.message)
+}
+
+func newDockerClient() (*dockerClient, error) {
+	host := os.Getenv("DOCKER_HOST")
+
+	if host == "" {
+		host = defaultDockerHost
+	}
+
+	parsed, err := url.Parse(host)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOCKER_HOST %q: %w", host, err)
+	}
+
+	switch parsed.Scheme {
+	case "unix":
+		socket := parsed.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+
+		return &dockerClient{baseURL: "http://docker", http: &http.Client{Transport: transport}}, nil
+	case "tcp", "http":
+		return &dockerClient{baseURL: "http://" + parsed.Host, http: http.DefaultClient}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported DOCKER_HOST scheme: %s", parsed.Scheme)
+}
+
+func (c *dockerClient) do(ctx context.Context, method string, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+
+		var message struct {
+			Message string `json:"message"`
+		}
+
+		data, _ := io.ReadAll(resp.Body)
+
+		if json.Unmarshal(data, &message) != nil || message.Message == "" {
+			message.Message = strings.TrimSpace(string(data))
+		}
+
+		return nil, &dockerError{status: resp.StatusCode, message: message.Message}
+	}
+
+	return resp, nil
+}
+
+// exportRootFS streams the root filesystem of a container as a tar archive.
+// When ref names an image instead, a stopped container is created from it for
+// the export and removed again by the returned cleanup function.
+func (c *dockerClient) exportRootFS(ctx context.Context, ref string) (io.ReadCloser, func(), error) {
+	id := ref
+	cleanup := func() {}
+
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(ref)+"/json", nil)
+
+	var apiErr *dockerError
+
+	switch {
+	case err == nil:
+		resp.Body.Close()
+	case errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound:
+		resp, err := c.do(ctx, http.MethodPost, "/containers/create", map[string]any{
+			"Image":      ref,
+			"Entrypoint": []string{""},
+			"Cmd":        []string{"true"},
+		})
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create container from image %s: %w", ref, err)
+		}
+
+		var created struct {
+			ID string `json:"Id"`
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(&created)
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode created container: %w", err)
+		}
+
+		id = created.ID
+		cleanup = func() {
+			resp, err := c.do(context.Background(), http.MethodDelete, "/containers/"+id+"?force=1", nil)
+
+			if err != nil {
+				fmt.Println("Error removing temporary container:", err)
+
+				return
+			}
+
+			resp.Body.Close()
+		}
+	default:
+		return nil, nil, fmt.Errorf("failed to inspect container %s: %w", ref, err)
+	}
+
+	resp, err = c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/export", nil)
+
+	if err != nil {
+		cleanup()
+
+		return nil, nil, fmt.Errorf("failed to export container %s: %w", id, err)
+	}
+
+	return resp.Body, cleanup, nil
+}
+
+// hashArchive computes manifest entries for the regular files of a tar stream.
+// algorithms optionally pins the algorithm per path, e.g. to match a manifest
+// that is being verified.
+func hashArchive(ctx context.Context, r io.Reader, opts scanOptions, algorithms map[string]string) ([]FileChecksum, error) {
+	archive := tar.NewReader(r)
+	hashed := make(map[string]FileChecksum)
+
+	var checksums []FileChecksum
+
+	for {
+		header, err := archive.Next()
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return checksums, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		relativePath := archivePath(header.Name)
+
+		if relativePath == "" || isArchiveIgnored(relativePath, opts.ignorePatterns) {
+			continue
+		}
+
+		var entry FileChecksum
+
+		switch {
+		case header.Typeflag == tar.TypeLink:
+			linked, ok := hashed[archivePath(header.Linkname)]
+
+			if !ok {
+				continue
+			}
+
+			entry = linked
+			entry.Path = relativePath
+		case header.Typeflag != tar.TypeReg:
+			continue
+		case opts.isPresenceOnly(relativePath):
+			entry = FileChecksum{Path: relativePath, PresenceOnly: true}
+		default:
+			algorithm, ok := algorithms[relativePath]
+
+			if !ok || algorithm == "" {
+				algorithm = opts.hash.AlgorithmFor(relativePath)
+			}
+
+			digest, size, err := opts.hash.Digest(ctx, archive, algorithm)
+
+			if err != nil {
+				return checksums, fmt.Errorf("failed to calculate checksum for %s: %w", relativePath, err)
+			}
+
+			entry = FileChecksum{
+				Path:      relativePath,
+				Checksum:  opts.hash.Encode(algorithm, digest),
+				Algorithm: algorithm,
+				Size:      size,
+			}
+		}
+
+		if err := opts.runFileHook(ctx, entry); err != nil {
+			return checksums, err
+		}
+
+		hashed[relativePath] = entry
+		checksums = append(checksums, entry)
+	}
+
+	sort.Slice(checksums, func(i, j int) bool {
+		return checksums[i].Path < checksums[j].Path
+	})
+
+	return checksums, nil
+}
+
+func archivePath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func isArchiveIgnored(relativePath string, ignorePatterns []string) bool {
+	for _, pattern := range ignorePatterns {
+		if strings.HasPrefix(relativePath, strings.TrimPrefix(pattern, "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func runScanContainer(ctx context.Context, cfg config, checksumsFilePath string, opts scanOptions) bool {
+	if len(cfg.args) != 1 {
+		fmt.Println("Error scanning container: scan-container expects an image or container ID")
+
+		return false
+	}
+
+	ref := cfg.args[0]
+
+	var expected []FileChecksum
+
+	algorithms := make(map[string]string)
+
+	if cfg.verify {
+		loaded, err := loadExpected(cfg, checksumsFilePath, opts.hash)
+
+		if err != nil {
+			fmt.Println("Error loading checksums:", err)
+
+			return false
+		}
+
+		expected = loaded
+
+		for _, entry := range expected {
+			algorithms[entry.Path] = entry.Algorithm
+		}
+	}
+
+	client, err := newDockerClient()
+
+	if err != nil {
+		fmt.Println("Error connecting to Docker:", err)
+
+		return false
+	}
+
+	archive, cleanup, err := client.exportRootFS(ctx, ref)
+
+	if err != nil {
+		fmt.Println("Error exporting container filesystem:", err)
+
+		return false
+	}
+
+	defer cleanup()
+
+	checksums, err := hashArchive(ctx, archive, opts, algorithms)
+	archive.Close()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println("Scan interrupted:", interruptReason(ctx))
+
+			return false
+		}
+
+		fmt.Println("Error calculating checksums:", err)
+
+		return false
+	}
+
+	if cfg.verify {
+		return reportVerification(ctx, cfg, ref, compareChecksums(expected, checksums), len(expected))
+	}
+
+	if err := saveChecksums(cfg, checksums, checksumsFilePath); err != nil {
+		fmt.Println("Error saving checksums:", err)
+
+		return false
+	}
+
+	saveRunSummary(cfg, generationSummary(cfg, checksums, nil))
+
+	fmt.Printf("Saved checksums of %d files from %s\n", len(checksums), ref)
+
+	return true
+}
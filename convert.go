@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+func runConvert(cfg config, checksumsFilePath string, opts scanOptions) bool {
+	if cfg.convertOut == "" {
+		fmt.Println("Error converting manifest: convert expects -out")
+
+		return false
+	}
+
+	in := checksumsFilePath
+
+	if cfg.convertIn != "" {
+		in = cfg.convertIn
+	}
+
+	checksums, err := loadFromFile(in)
+
+	if err != nil {
+		fmt.Println("Error loading checksums:", err)
+
+		return false
+	}
+
+	checksums = removeTombstones(checksums)
+
+	for i, entry := range checksums {
+		checksums[i] = opts.hash.DetectAlgorithm(entry)
+	}
+
+	out, err := filepath.Abs(cfg.convertOut)
+
+	if err != nil {
+		fmt.Println("Error resolving output file:", err)
+
+		return false
+	}
+
+	if err := saveChecksums(cfg, checksums, out); err != nil {
+		fmt.Println("Error saving checksums:", err)
+
+		return false
+	}
+
+	fmt.Printf("Converted %d entries from %s to %s (%s)\n", len(checksums), in, cfg.convertOut, cfg.format)
+
+	return true
+}
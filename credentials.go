@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// builtinCredentials are the credential sources accepted wherever a key file
+// is, as scheme:name. A value without a known scheme is read as a file.
+var builtinCredentials = map[string]checksum.CredentialProvider{
+	"env":      envCredentials{},
+	"file":     fileCredentials{},
+	"vault":    vaultCredentials{},
+	"awskms":   awsKMSCredentials{},
+	"gcpkms":   gcpKMSCredentials{},
+	"azurekms": azureKMSCredentials{},
+}
+
+type envCredentials struct{}
+
+func (envCredentials) Secret(ctx context.Context, name string) ([]byte, error) {
+	value, ok := os.LookupEnv(name)
+
+	if !ok {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return []byte(value), nil
+}
+
+type fileCredentials struct{}
+
+func (fileCredentials) Secret(ctx context.Context, name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func lookupCredentialProvider(ref string) (checksum.CredentialProvider, string) {
+	if scheme, name, found := strings.Cut(ref, ":"); found {
+		if provider, ok := builtinCredentials[scheme]; ok {
+			return provider, name
+		}
+
+		if provider, ok := checksum.LookupCredentialProvider(scheme); ok {
+			return provider, name
+		}
+	}
+
+	return fileCredentials{}, ref
+}
+
+func readSecret(ctx context.Context, ref string) ([]byte, error) {
+	provider, name := lookupCredentialProvider(ref)
+
+	return provider.Secret(ctx, name)
+}
+
+// resolveSigner returns a signer for ref. Providers keeping keys remotely sign
+// there, others must yield a PEM encoded ECDSA or RSA private key. Ed25519 keys
+// are rejected because the signed payloads are prehashed digests.
+func resolveSigner(ctx context.Context, ref string) (crypto.Signer, error) {
+	provider, name := lookupCredentialProvider(ref)
+
+	if signing, ok := provider.(checksum.SigningProvider); ok {
+		return signing.Signer(ctx, name)
+	}
+
+	data, err := provider.Secret(ctx, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePrivateKey(data)
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return nil, fmt.Errorf("not a PEM encoded private key")
+	}
+
+	var (
+		key any
+		err error
+	)
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %T, use an ECDSA or RSA key", key)
+}
+
+// githubIDToken requests an OIDC token for audience from the Actions runtime,
+// available to jobs granted the id-token: write permission.
+func githubIDToken(ctx context.Context, audience string) (string, error) {
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+
+	if requestURL == "" || requestToken == "" {
+		return "", fmt.Errorf("GitHub OIDC is not available, grant the job id-token: write")
+	}
+
+	if audience != "" {
+		requestURL += "&audience=" + url.QueryEscape(audience)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+requestToken)
+
+	var token struct {
+		Value string `json:"value"`
+	}
+
+	if err := doCredentialRequest(request, &token); err != nil {
+		return "", fmt.Errorf("failed to request GitHub OIDC token: %w", err)
+	}
+
+	return token.Value, nil
+}
+
+// doCredentialRequest sends a request to a credential or key service and
+// decodes its JSON response into result.
+func doCredentialRequest(request *http.Request, result any) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+		return fmt.Errorf("%s %s responded with %d: %s", request.Method, request.URL.Redacted(), response.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// normalizeDigest strips an algorithm prefix and lowercases hex digests, so
+// digests from other tools compare equal.
+func normalizeDigest(digest string) string {
+	if i := strings.LastIndex(digest, ":"); i >= 0 {
+		digest = digest[i+1:]
+	}
+
+	if _, err := hex.DecodeString(digest); err == nil {
+		return strings.ToLower(digest)
+	}
+
+	return digest
+}
+
+// loadDigestList reads a list of digests into a map from the normalized
+// digest to the name it is listed under, unnamed for those listed without
+// one. Two layouts are read:
+//
+//   - one digest per line, optionally followed by a name, as in sha256sum
+//     output or threat intel exports, with blank lines and # comments
+//     skipped;
+//   - quoted CSV with a header row, as in the NSRL RDS NSRLFile.txt, where
+//     every MD5, SHA-1, SHA-256 and SHA-512 column is read and FileName names
+//     the digests.
+func loadDigestList(path string, unnamed string) (map[string]string, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	if first, err := reader.Peek(1); err == nil && first[0] == '"' {
+		return readDigestCSV(reader, unnamed)
+	}
+
+	digests := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		digest, name, _ := strings.Cut(line, " ")
+		name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "*"))
+
+		if name == "" {
+			name = unnamed
+		}
+
+		digests[normalizeDigest(digest)] = name
+	}
+
+	return digests, scanner.Err()
+}
+
+func readDigestCSV(input io.Reader, unnamed string) (map[string]string, error) {
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	var columns []int
+
+	nameColumn := -1
+
+	for i, column := range header {
+		switch strings.ToLower(strings.ReplaceAll(column, "-", "")) {
+		case "md5", "sha1", "sha256", "sha512":
+			columns = append(columns, i)
+		case "filename", "file_name", "name":
+			nameColumn = i
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("csv header names no MD5, SHA-1, SHA-256 or SHA-512 column")
+	}
+
+	digests := make(map[string]string)
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			return digests, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		name := unnamed
+
+		if nameColumn >= 0 && nameColumn < len(record) && record[nameColumn] != "" {
+			name = record[nameColumn]
+		}
+
+		for _, column := range columns {
+			if column < len(record) && record[column] != "" {
+				digests[normalizeDigest(record[column])] = name
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type emailOptions struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (o emailOptions) enabled() bool {
+	return o.host != "" && len(o.to) > 0
+}
+
+func (o emailOptions) validate() error {
+	if o.host == "" && len(o.to) == 0 {
+		return nil
+	}
+
+	if o.host == "" {
+		return fmt.Errorf("email recipients require an SMTP host")
+	}
+
+	if len(o.to) == 0 {
+		return fmt.Errorf("SMTP host requires at least one email recipient")
+	}
+
+	if o.from == "" {
+		return fmt.Errorf("SMTP host requires a sender address")
+	}
+
+	return nil
+}
+
+func reportExtension(format string) string {
+	switch format {
+	case "sarif":
+		return "sarif"
+	case "junit":
+		return "xml"
+	case "cef", "leef":
+		return format
+	}
+
+	return "json"
+}
+
+func buildEmail(opts emailOptions, report verifyReport, reportFormat string) ([]byte, error) {
+	attachment, err := formatReport(report, reportFormat)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+
+	subject := fmt.Sprintf("Checksum drift detected in %s", report.Root)
+
+	if len(report.Changes) == 0 {
+		subject = fmt.Sprintf("Checksum verification passed for %s", report.Root)
+	}
+
+	fmt.Fprintf(&body, "From: %s\r\n", opts.from)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(opts.to, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&body, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&body, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	text, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(text, summarizeReport(report))
+
+	file, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/octet-stream"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=\"checksum-report.%s\"", reportExtension(reportFormat))},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(attachment)
+
+	for len(encoded) > 76 {
+		fmt.Fprintf(file, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+
+	fmt.Fprintf(file, "%s\r\n", encoded)
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return body.Bytes(), nil
+}
+
+func sendEmail(opts emailOptions, report verifyReport, reportFormat string) error {
+	message, err := buildEmail(opts, report, reportFormat)
+
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+
+	var auth smtp.Auth
+
+	if opts.username != "" {
+		auth = smtp.PlainAuth("", opts.username, opts.password, opts.host)
+	}
+
+	address := net.JoinHostPort(opts.host, strconv.Itoa(opts.port))
+
+	if err := smtp.SendMail(address, auth, opts.from, opts.to, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// emptyListLimit is how many zero-byte paths a message names before it only
+// counts the rest.
+const emptyListLimit = 10
+
+// listEmpty joins the first emptyListLimit paths for a message, so a tree
+// full of placeholder files cannot flood the log.
+func listEmpty(paths []string) string {
+	if len(paths) <= emptyListLimit {
+		return strings.Join(paths, ", ")
+	}
+
+	return fmt.Sprintf("%s and %d more", strings.Join(paths[:emptyListLimit], ", "), len(paths)-emptyListLimit)
+}
+
+// isZeroByte reports whether entry records a hashed regular file without
+// content.
+func isZeroByte(entry FileChecksum) bool {
+	return entry.Size == 0 && !entry.PresenceOnly && (entry.Type == "" || entry.Type == checksum.TypeRegular)
+}
+
+// zeroByteFiles lists the paths of the zero-byte files of a new manifest,
+// spilled entries included.
+func zeroByteFiles(spool *entrySpool, checksums []FileChecksum) ([]string, error) {
+	var empty []string
+
+	collect := func(entry FileChecksum) error {
+		if isZeroByte(entry) {
+			empty = append(empty, entry.Path)
+		}
+
+		return nil
+	}
+
+	if spool != nil {
+		return empty, spool.each(checksums, collect)
+	}
+
+	for _, entry := range checksums {
+		collect(entry)
+	}
+
+	return empty, nil
+}
+
+// unexpectedEmpty leaves out the paths -allow-empty expects to be empty.
+func unexpectedEmpty(allowed []string, paths []string) []string {
+	var unexpected []string
+
+	for _, path := range paths {
+		if !matchesAnyGlob(allowed, filepath.ToSlash(path)) {
+			unexpected = append(unexpected, path)
+		}
+	}
+
+	return unexpected
+}
+
+// failOnEmptyRule fails verification on files -fail-on-empty finds empty,
+// whatever the other severity rules say.
+func failOnEmptyRule() severityRule {
+	empty := true
+
+	return severityRule{Empty: &empty, Severity: severityFail}
+}
+
+// markEmpty marks the changes whose new content is zero bytes, outside the
+// -allow-empty patterns: truncation to zero is a common sign of corruption
+// that a digest mismatch alone undersells, and new empty files are reported
+// alongside as placeholders that may have never been filled.
+func markEmpty(result *verifyResult, allowed []string) {
+	marked := make([]fileChange, len(result.changes))
+
+	for i, change := range result.changes {
+		switch change.Kind {
+		case changeModified, changeAdded, changeUnlisted, changeRenamed:
+			change.Empty = change.Actual != "" && change.ActualSize == 0 && !matchesAnyGlob(allowed, filepath.ToSlash(change.Path))
+		}
+
+		marked[i] = change
+	}
+
+	result.changes = marked
+}
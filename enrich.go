@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// defaultEnrichBatch is the number of digests given to an -enrich service
+// per lookup.
+const defaultEnrichBatch = 100
+
+func resolveEnricher(name string) (checksum.Enricher, error) {
+	if command, ok := strings.CutPrefix(name, "exec:"); ok {
+		fields := strings.Fields(command)
+
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("enricher %q has no command", name)
+		}
+
+		return checksum.CommandEnricher(fields[0], fields[1:]...), nil
+	}
+
+	enricher, ok := checksum.LookupEnricher(name)
+
+	if !ok {
+		return nil, fmt.Errorf("unknown enricher: %s", name)
+	}
+
+	return enricher, nil
+}
+
+// rateLimiter spaces lookups at least interval apart, as reputation services
+// commonly cap the requests per second of a key.
+type rateLimiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return &rateLimiter{}
+	}
+
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if delay := time.Until(l.next); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	l.next = time.Now().Add(l.interval)
+
+	return nil
+}
+
+// enrichEntries looks the digests of checksums up in every -enrich service,
+// in batches of -enrich-batch and at most -enrich-rate lookups per second,
+// and records the findings in the entries. It returns how many entries gained
+// findings.
+func enrichEntries(ctx context.Context, cfg config, checksums []FileChecksum) (int, error) {
+	byAlgorithm := make(map[string][]string)
+	seen := make(map[string]bool)
+
+	for _, entry := range checksums {
+		key := entry.Algorithm + ":" + entry.Checksum
+
+		if entry.PresenceOnly || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		byAlgorithm[entry.Algorithm] = append(byAlgorithm[entry.Algorithm], entry.Checksum)
+	}
+
+	batchSize := cfg.enrichBatch
+
+	if batchSize <= 0 {
+		batchSize = defaultEnrichBatch
+	}
+
+	limiter := newRateLimiter(cfg.enrichRate)
+	enriched := make(map[string]bool)
+
+	for _, name := range cfg.enrichers {
+		enricher, err := resolveEnricher(name)
+
+		if err != nil {
+			return 0, err
+		}
+
+		findings := make(map[string]map[string]json.RawMessage)
+
+		for algorithm, digests := range byAlgorithm {
+			findings[algorithm] = make(map[string]json.RawMessage)
+
+			for start := 0; start < len(digests); start += batchSize {
+				if err := limiter.wait(ctx); err != nil {
+					return 0, err
+				}
+
+				batch, err := enricher.Enrich(ctx, algorithm, digests[start:min(start+batchSize, len(digests))])
+
+				if err != nil {
+					return 0, fmt.Errorf("enricher %s: %w", name, err)
+				}
+
+				for digest, finding := range batch {
+					findings[algorithm][digest] = finding
+				}
+			}
+		}
+
+		for i, entry := range checksums {
+			finding, ok := findings[entry.Algorithm][entry.Checksum]
+
+			if !ok || entry.PresenceOnly {
+				continue
+			}
+
+			if checksums[i].Enrichment == nil {
+				checksums[i].Enrichment = make(map[string]json.RawMessage)
+			}
+
+			checksums[i].Enrichment[name] = finding
+			enriched[entry.Path] = true
+		}
+	}
+
+	return len(enriched), nil
+}
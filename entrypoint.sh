@@ -1,3 +1,35 @@
 #!/bin/sh
 
-/app/app --dir="$1" --output="$2" --ignore="$3"
\ No newline at end of file
+# The action passes every input positionally, in the order of the flags below.
+# Inputs left empty are not passed, so CHECKSUM_* variables, the config file
+# and the binary's defaults apply to them, including the defaults that follow
+# from other flags, such as sha256 with fips and sha384 for sri.
+for flag in \
+  dir output ignore algo key-file algo-for verify digest-bytes encoding \
+  format cid-chunker tree-digest dirhash-prefix report-file report \
+  notify-webhook notify-format notify-always smtp-host smtp-port \
+  smtp-username smtp-password email-from email-to file-issue issue-title \
+  github-token baseline-branch baseline-update presence-only hash-paths \
+  path-key-file split-output max-entries resume timeout wait-lock \
+  gosrc-package sink on-file on-complete only-owned-by skip-world-writable \
+  hard-links one-file-system quick-check create-output-dir output-base scope \
+  coverage-check walkers read-path storage-profile max-memory explain sidecar \
+  sidecar-extension sidecar-format oci-subject oci-username oci-password \
+  rekor rekor-url rekor-key sign-kms require-signature pubkey sign-key \
+  sign-key-password signature-format fips digest-prefix tag tag-policy \
+  policy-file rego-policy opa-command quarantine-dir sample sample-seed \
+  verify-order fail-fast expect-min-files expect-max-files \
+  expect-total-bytes-min expect-total-bytes-max empty-dirs record-type \
+  content-type detect-encoding entropy entropy-threshold scan-secrets \
+  ioc-blocklist ioc-url ioc-token fail-on-ioc known-good known-good-filter \
+  enrich enrich-batch enrich-rate syslog syslog-facility audit-log audit-chain \
+  forensic tombstones similarity canonicalize fail-on-empty allow-empty \
+  scrub-older-than par2 par2-dir config; do
+  if [ -n "$1" ]; then
+    set -- "$@" "--$flag=$1"
+  fi
+
+  shift
+done
+
+/app/app "$@"
\ No newline at end of file
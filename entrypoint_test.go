@@ -0,0 +1,246 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type actionMetadata struct {
+	Inputs map[string]struct {
+		Default string `yaml:"default"`
+	} `yaml:"inputs"`
+	Runs struct {
+		Args []string `yaml:"args"`
+	} `yaml:"runs"`
+}
+
+// actionArgs are the positional arguments the action hands entrypoint.sh,
+// every input at its action.yaml default unless inputs sets it.
+func actionArgs(t *testing.T, inputs map[string]string) []string {
+	data, err := os.ReadFile("action.yaml")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var metadata actionMetadata
+
+	if err := yaml.Unmarshal(data, &metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	args := make([]string, 0, len(metadata.Runs.Args))
+
+	for _, arg := range metadata.Runs.Args {
+		name := strings.TrimSuffix(strings.TrimPrefix(arg, "${{ inputs."), " }}")
+		input, ok := metadata.Inputs[name]
+
+		if !ok {
+			t.Fatalf("action.yaml passes %s, which is not an input", arg)
+		}
+
+		if value, ok := inputs[name]; ok {
+			args = append(args, value)
+
+			continue
+		}
+
+		args = append(args, input.Default)
+	}
+
+	return args
+}
+
+// buildApp builds the binary from this tree for the tests that run it the way
+// a job does, and skips them under -short.
+func buildApp(t *testing.T) string {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("builds the binary")
+	}
+
+	goCommand, err := exec.LookPath("go")
+
+	if err != nil {
+		t.Skip("go not found")
+	}
+
+	app := filepath.Join(t.TempDir(), "app")
+
+	if output, err := exec.Command(goCommand, "build", "-o", app, ".").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, output)
+	}
+
+	return app
+}
+
+// TestEntrypoint runs entrypoint.sh the way the action does, against a binary
+// built from this tree, so inputs whose defaults the binary derives from other
+// inputs are checked end to end.
+func TestEntrypoint(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("runs entrypoint.sh")
+	}
+
+	app := buildApp(t)
+	binDir := filepath.Dir(app)
+
+	script, err := os.ReadFile("entrypoint.sh")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entrypoint := filepath.Join(binDir, "entrypoint.sh")
+
+	if err := os.WriteFile(entrypoint, []byte(strings.ReplaceAll(string(script), "/app/app", app)), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		inputs map[string]string
+		env    map[string]string
+		config string
+		want   []string
+	}{
+		{
+			name: "defaults",
+			want: []string{`"algorithm": "sha1"`},
+		},
+		{
+			name:   "fips defaults to sha256",
+			inputs: map[string]string{"fips": "true"},
+			want:   []string{`"compliance": "fips"`, `"algorithm": "sha256"`},
+		},
+		{
+			name:   "sri defaults to sha384",
+			inputs: map[string]string{"format": "sri"},
+			want:   []string{`"a": "sha384-`},
+		},
+		{
+			name:   "algo input",
+			inputs: map[string]string{"algo": "blake3"},
+			want:   []string{`"algorithm": "blake3"`},
+		},
+		{
+			name: "environment fills inputs left empty",
+			env:  map[string]string{"CHECKSUM_ALGO": "sha256", "CHECKSUM_DIGEST_BYTES": "4", "CHECKSUM_RECORD_TYPE": "true"},
+			want: []string{`"algorithm": "sha256"`, `"checksum": "ba7816bf"`, `"type": "regular"`},
+		},
+		{
+			name:   "inputs win over the environment",
+			inputs: map[string]string{"algo": "blake3"},
+			env:    map[string]string{"CHECKSUM_ALGO": "sha256"},
+			want:   []string{`"algorithm": "blake3"`},
+		},
+		{
+			name:   "environment wins over the config file",
+			inputs: map[string]string{"config": "checksum.yaml", "ignore": "checksum.yaml"},
+			env:    map[string]string{"CHECKSUM_ALGO": "sha256"},
+			config: "algo: sha512\ndigest-bytes: 4\n",
+			want:   []string{`"algorithm": "sha256"`, `"checksum": "ba7816bf"`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			workspace := t.TempDir()
+
+			if err := os.WriteFile(filepath.Join(workspace, "a"), []byte("abc"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.config != "" {
+				if err := os.WriteFile(filepath.Join(workspace, "checksum.yaml"), []byte(test.config), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			command := exec.Command("sh", append([]string{entrypoint}, actionArgs(t, test.inputs)...)...)
+			command.Dir = workspace
+			command.Env = withoutChecksumVariables(os.Environ())
+
+			for name, value := range test.env {
+				command.Env = append(command.Env, name+"="+value)
+			}
+
+			if output, err := command.CombinedOutput(); err != nil {
+				t.Fatalf("entrypoint.sh: %v\n%s", err, output)
+			}
+
+			manifest, err := os.ReadFile(filepath.Join(workspace, "checksums.json"))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range test.want {
+				if !strings.Contains(string(manifest), want) {
+					t.Errorf("manifest %s does not contain %s", manifest, want)
+				}
+			}
+		})
+	}
+}
+
+func withoutChecksumVariables(environment []string) []string {
+	var kept []string
+
+	for _, variable := range environment {
+		if !strings.HasPrefix(variable, envPrefix) {
+			kept = append(kept, variable)
+		}
+	}
+
+	return kept
+}
+
+// TestEntrypointFlags checks entrypoint.sh names the flags in the order the
+// action passes its inputs, so no value lands on the wrong flag.
+func TestEntrypointFlags(t *testing.T) {
+	data, err := os.ReadFile("action.yaml")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var metadata actionMetadata
+
+	if err := yaml.Unmarshal(data, &metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	var inputs []string
+
+	for _, arg := range metadata.Runs.Args {
+		inputs = append(inputs, strings.TrimSuffix(strings.TrimPrefix(arg, "${{ inputs."), " }}"))
+	}
+
+	script, err := os.ReadFile("entrypoint.sh")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, list, found := strings.Cut(string(script), "for flag in")
+	list, _, _ = strings.Cut(list, "; do")
+
+	if !found {
+		t.Fatal("entrypoint.sh has no flag list")
+	}
+
+	flags := strings.Fields(strings.ReplaceAll(list, "\\", " "))
+
+	if !reflect.DeepEqual(flags, inputs) {
+		t.Errorf("entrypoint.sh flags = %v, want the action inputs %v", flags, inputs)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+)
+
+// expectations bound the size of the tree, so an empty or wrongly mounted
+// directory fails instead of producing a tiny manifest. Zero leaves a bound
+// unset.
+type expectations struct {
+	minFiles int
+	maxFiles int
+	minBytes int64
+	maxBytes int64
+}
+
+func parseExpectations(cfg config) (expectations, error) {
+	expect := expectations{minFiles: cfg.expectMinFiles, maxFiles: cfg.expectMaxFiles}
+
+	if expect.minFiles < 0 || expect.maxFiles < 0 {
+		return expectations{}, fmt.Errorf("-expect-min-files and -expect-max-files cannot be negative")
+	}
+
+	var err error
+
+	if cfg.expectMinBytes != "" {
+		if expect.minBytes, err = parseSize(cfg.expectMinBytes); err != nil {
+			return expectations{}, err
+		}
+	}
+
+	if cfg.expectMaxBytes != "" {
+		if expect.maxBytes, err = parseSize(cfg.expectMaxBytes); err != nil {
+			return expectations{}, err
+		}
+	}
+
+	if expect.maxFiles > 0 && expect.minFiles > expect.maxFiles {
+		return expectations{}, fmt.Errorf("-expect-min-files %d is above -expect-max-files %d", expect.minFiles, expect.maxFiles)
+	}
+
+	if expect.maxBytes > 0 && expect.minBytes > expect.maxBytes {
+		return expectations{}, fmt.Errorf("-expect-total-bytes-min %s is above -expect-total-bytes-max %s", cfg.expectMinBytes, cfg.expectMaxBytes)
+	}
+
+	return expect, nil
+}
+
+func (e expectations) check(files int, bytes int64) error {
+	switch {
+	case e.minFiles > 0 && files < e.minFiles:
+		return fmt.Errorf("found %d files, expected at least %d (-expect-min-files)", files, e.minFiles)
+	case e.maxFiles > 0 && files > e.maxFiles:
+		return fmt.Errorf("found %d files, expected at most %d (-expect-max-files)", files, e.maxFiles)
+	case e.minBytes > 0 && bytes < e.minBytes:
+		return fmt.Errorf("found %d bytes, expected at least %d (-expect-total-bytes-min)", bytes, e.minBytes)
+	case e.maxBytes > 0 && bytes > e.maxBytes:
+		return fmt.Errorf("found %d bytes, expected at most %d (-expect-total-bytes-max)", bytes, e.maxBytes)
+	}
+
+	return nil
+}
+
+func totalBytes(entries []FileChecksum) int64 {
+	var total int64
+
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	return total
+}
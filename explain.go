@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+type explainedOption struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// runExplanation lists everything besides file content that shapes a
+// manifest. It holds no timestamps and exclusions inside the root are
+// relative to it, so the same inputs on two machines explain identically;
+// only files outside the root, such as an absolute -output, keep their
+// absolute paths.
+type runExplanation struct {
+	Options     []explainedOption `json:"options"`
+	Ignore      []string          `json:"ignore"`
+	Excluded    []string          `json:"excluded"`
+	Environment map[string]string `json:"environment"`
+	Platform    string            `json:"platform"`
+}
+
+func explainRun(cfg config, projectDir string, opts scanOptions) (json.RawMessage, error) {
+	given := make(map[string]bool)
+
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	explanation := runExplanation{
+		Ignore:      append([]string{}, opts.ignorePatterns...),
+		Excluded:    []string{},
+		Environment: make(map[string]string),
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH + " " + runtime.Version(),
+	}
+
+	flag.VisitAll(func(f *flag.Flag) {
+		source := "default"
+
+		switch {
+		case cfg.fromEnv[f.Name]:
+			source = "env"
+		case cfg.fromConfig[f.Name]:
+			source = "config"
+		case given[f.Name]:
+			source = "flag"
+		}
+
+		explanation.Options = append(explanation.Options, explainedOption{
+			Name:   f.Name,
+			Value:  redactedValue(f.Name, f.Value.String()),
+			Source: source,
+		})
+	})
+
+	for _, excluded := range append(append([]string{}, opts.excludedFiles...), opts.excludedGlobs...) {
+		if relative, err := filepath.Rel(projectDir, excluded); err == nil && !strings.HasPrefix(relative, "..") {
+			excluded = filepath.ToSlash(relative)
+		}
+
+		explanation.Excluded = append(explanation.Excluded, excluded)
+	}
+
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+
+		if strings.HasPrefix(name, envPrefix) {
+			explanation.Environment[name] = redactedValue(strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, envPrefix), "_", "-")), value)
+		}
+	}
+
+	sort.Strings(explanation.Excluded)
+
+	data, err := json.Marshal(explanation)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func redactedValue(flagName string, value string) string {
+	for _, name := range redactedFlags {
+		if name == flagName && value != "" {
+			return "REDACTED"
+		}
+	}
+
+	return value
+}
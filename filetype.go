@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// contentSniffLength is how much of a file content type detection reads.
+const contentSniffLength = 512
+
+// fileType returns the type -record-type records for the file at path, or an
+// empty string when types are not recorded.
+func (o scanOptions) fileType(path string) (string, error) {
+	if !o.recordType {
+		return "", nil
+	}
+
+	info, err := os.Lstat(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return checksum.FileType(info.Mode()), nil
+}
+
+// isSpecialType reports whether files of fileType are recorded without a
+// digest, because reading a socket, FIFO or device could block or never end.
+func isSpecialType(fileType string) bool {
+	switch fileType {
+	case checksum.TypeSocket, checksum.TypeFIFO, checksum.TypeDevice:
+		return true
+	default:
+		return false
+	}
+}
+
+// detectContentType sniffs the media type of the file at path from its first
+// bytes with -content-type, the way net/http does.
+func (o scanOptions) detectContentType(path string) (string, error) {
+	if !o.contentType {
+		return "", nil
+	}
+
+	file, err := openScanned(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	data := make([]byte, contentSniffLength)
+
+	n, err := io.ReadFull(file, data)
+
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+
+	return http.DetectContentType(data[:n]), nil
+}
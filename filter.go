@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+type ownerFilter struct {
+	uid int
+	gid int
+}
+
+type metadataFilter struct {
+	owners            []ownerFilter
+	skipWorldWritable bool
+}
+
+func parseMetadataFilter(owners []string, skipWorldWritable bool) (metadataFilter, error) {
+	filter := metadataFilter{skipWorldWritable: skipWorldWritable}
+
+	if len(owners) > 0 && !ownershipSupported {
+		return metadataFilter{}, fmt.Errorf("-only-owned-by is not supported on this platform")
+	}
+
+	for _, spec := range owners {
+		owner, err := parseOwnerFilter(spec)
+
+		if err != nil {
+			return metadataFilter{}, err
+		}
+
+		filter.owners = append(filter.owners, owner)
+	}
+
+	return filter, nil
+}
+
+func parseOwnerFilter(spec string) (ownerFilter, error) {
+	kind, value, found := strings.Cut(spec, ":")
+
+	if !found || value == "" {
+		return ownerFilter{}, fmt.Errorf("invalid owner filter %q, expected uid:N, gid:N, user:NAME or group:NAME", spec)
+	}
+
+	switch kind {
+	case "user":
+		u, err := user.Lookup(value)
+
+		if err != nil {
+			return ownerFilter{}, err
+		}
+
+		kind, value = "uid", u.Uid
+	case "group":
+		g, err := user.LookupGroup(value)
+
+		if err != nil {
+			return ownerFilter{}, err
+		}
+
+		kind, value = "gid", g.Gid
+	}
+
+	id, err := strconv.Atoi(value)
+
+	if err != nil {
+		return ownerFilter{}, fmt.Errorf("invalid owner filter %q: %w", spec, err)
+	}
+
+	switch kind {
+	case "uid":
+		return ownerFilter{uid: id, gid: -1}, nil
+	case "gid":
+		return ownerFilter{uid: -1, gid: id}, nil
+	}
+
+	return ownerFilter{}, fmt.Errorf("invalid owner filter %q, expected uid:N, gid:N, user:NAME or group:NAME", spec)
+}
+
+func (f metadataFilter) matches(d fs.DirEntry) bool {
+	if len(f.owners) == 0 && !f.skipWorldWritable {
+		return true
+	}
+
+	info, err := d.Info()
+
+	if err != nil {
+		return true
+	}
+
+	if f.skipWorldWritable && info.Mode().Perm()&0o002 != 0 {
+		return false
+	}
+
+	if len(f.owners) == 0 {
+		return true
+	}
+
+	uid, gid, ok := fileOwner(info)
+
+	if !ok {
+		return false
+	}
+
+	for _, owner := range f.owners {
+		if (owner.uid < 0 || owner.uid == uid) && (owner.gid < 0 || owner.gid == gid) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type deviceFilter struct {
+	device uint64
+}
+
+func newDeviceFilter(rootDir string) (*deviceFilter, error) {
+	info, err := os.Stat(rootDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	device, ok := fileDevice(info)
+
+	if !ok {
+		return nil, fmt.Errorf("-one-file-system is not supported on this platform")
+	}
+
+	return &deviceFilter{device: device}, nil
+}
+
+func (f *deviceFilter) contains(d fs.DirEntry) bool {
+	info, err := d.Info()
+
+	if err != nil {
+		return true
+	}
+
+	device, ok := fileDevice(info)
+
+	return !ok || device == f.device
+}
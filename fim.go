@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// defaultFIMInterval is how often the fim command verifies the whole tree.
+const defaultFIMInterval = time.Hour
+
+// fimSettle is how long fim waits after a watched change before verifying,
+// so a burst of writes is checked once.
+const fimSettle = 2 * time.Second
+
+// runFIM monitors the tree until it is interrupted, like a lightweight
+// Tripwire. Unless the -fim-db database already holds one, it records a
+// baseline, then verifies the tree against it every -fim-interval and, with
+// -fim-watch, shortly after inotify reports a change. Failed verifications
+// alert like -verify does, and through -fim-alert for drift not seen before.
+// Drift is never accepted on its own: it is reported on every check until
+// fim -baseline-update approves the tree.
+func runFIM(ctx context.Context, cfg config, projectDir string, opts scanOptions) bool {
+	store, ok := openFIM(cfg)
+
+	if !ok {
+		return false
+	}
+
+	defer store.close()
+
+	if _, found, err := store.recorded(); err != nil {
+		fmt.Println("Error loading baseline:", err)
+
+		return false
+	} else if !found {
+		if err := recordBaseline(ctx, cfg, projectDir, store, opts); err != nil {
+			fmt.Println("Error recording baseline:", err)
+
+			return false
+		}
+	}
+
+	var changed <-chan struct{}
+
+	if cfg.fimWatch {
+		var err error
+
+		changed, err = watchTree(ctx, projectDir)
+
+		if err != nil {
+			fmt.Println("Error watching files, verifying every -fim-interval only:", err)
+		}
+	}
+
+	ticker := time.NewTicker(cfg.fimInterval)
+	defer ticker.Stop()
+
+	settle := time.NewTimer(fimSettle)
+	settle.Stop()
+
+	fmt.Printf("Monitoring %s every %s\n", projectDir, cfg.fimInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopped monitoring:", interruptReason(ctx))
+
+			return true
+		case <-changed:
+			settle.Reset(fimSettle)
+
+			continue
+		case <-ticker.C:
+		case <-settle.C:
+		}
+
+		if err := checkBaseline(ctx, cfg, projectDir, store, opts); err != nil && ctx.Err() == nil {
+			fmt.Println("Error verifying checksums:", err)
+		}
+
+		// Recording drift changes the watched tree too.
+		select {
+		case <-changed:
+		default:
+		}
+	}
+}
+
+// runFIMApproval is fim -baseline-update: it records the current tree as the
+// baseline, approving every change found so far. It runs beside a monitoring
+// fim, which verifies against the new baseline from its next check.
+func runFIMApproval(ctx context.Context, cfg config, projectDir string, opts scanOptions) bool {
+	store, ok := openFIM(cfg)
+
+	if !ok {
+		return false
+	}
+
+	defer store.close()
+
+	if err := recordBaseline(ctx, cfg, projectDir, store, opts); err != nil {
+		fmt.Println("Error recording baseline:", err)
+
+		return false
+	}
+
+	return true
+}
+
+func openFIM(cfg config) (*fimStore, bool) {
+	if cfg.splitOutput || cfg.maxEntries > 0 || cfg.manifestVerifier != nil {
+		fmt.Println("Error validating flags: fim keeps its baseline in the -fim-db database and cannot be combined with -split-output, -max-entries or -require-signature")
+
+		return nil, false
+	}
+
+	if cfg.fimInterval <= 0 {
+		fmt.Println("Error validating flags: -fim-interval must be positive")
+
+		return nil, false
+	}
+
+	store, err := openFIMStore(cfg.fimDatabase)
+
+	if err != nil {
+		fmt.Println("Error opening fim database:", err)
+
+		return nil, false
+	}
+
+	return store, true
+}
+
+func recordBaseline(ctx context.Context, cfg config, projectDir string, store *fimStore, opts scanOptions) error {
+	checksums, err := calculateChecksums(ctx, projectDir, opts)
+
+	if err != nil {
+		return err
+	}
+
+	entries := checksums
+
+	if cfg.tombstones {
+		previous, err := store.loadBaseline()
+
+		if err != nil {
+			return err
+		}
+
+		var deleted int
+
+		entries, deleted = addTombstones(projectDir, checksums, previous, opts)
+
+		if deleted > 0 {
+			fmt.Printf("Recorded %d deleted files as tombstones\n", deleted)
+		}
+	}
+
+	if err := store.replaceBaseline(entries, time.Now()); err != nil {
+		return err
+	}
+
+	fmt.Printf("Recorded baseline of %d files into %s\n", len(checksums), cfg.fimDatabase)
+
+	return nil
+}
+
+// checkBaseline verifies the tree against the baseline once, records the
+// drift found and alerts on failure. The baseline itself is left alone.
+func checkBaseline(ctx context.Context, cfg config, projectDir string, store *fimStore, opts scanOptions) error {
+	recorded, _, err := store.recorded()
+
+	if err != nil {
+		return fmt.Errorf("failed to load baseline: %w", err)
+	}
+
+	expected, err := store.loadBaseline()
+
+	if err != nil {
+		return fmt.Errorf("failed to load baseline: %w", err)
+	}
+
+	expected = removeTombstones(expected)
+
+	for i, entry := range expected {
+		expected[i] = opts.hash.DetectAlgorithm(entry)
+	}
+
+	if cfg.fips {
+		if err := checkFIPSManifest(expected); err != nil {
+			return fmt.Errorf("failed to load baseline: %w", err)
+		}
+	}
+
+	opts.manifestTime = recorded
+
+	result, err := verifyChecksums(ctx, projectDir, expected, opts)
+
+	if err != nil {
+		return err
+	}
+
+	passed := reportVerification(ctx, cfg, cfg.rootDir, result, len(expected))
+
+	fresh, err := store.recordDrift(result.changes, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	if len(result.changes) > 0 {
+		fmt.Printf("%d changes await approval with fim -baseline-update, %d of them new\n", len(result.changes), len(fresh))
+	}
+
+	if !passed && len(fresh) > 0 {
+		if err := runAlertHook(ctx, cfg, fresh); err != nil {
+			fmt.Println("Error running alert hook:", err)
+		}
+	}
+
+	return nil
+}
+
+// runAlertHook runs -fim-alert with the new changes of a failed verification
+// on stdin.
+func runAlertHook(ctx context.Context, cfg config, changes []fileChange) error {
+	command := strings.Fields(cfg.fimAlert)
+
+	if len(command) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(changes)
+
+	if err != nil {
+		return fmt.Errorf("failed to marshal changes for alert hook: %w", err)
+	}
+
+	return checksum.RunCommand(ctx, append(data, '\n'), command[0], command[1:]...)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// fimSchema holds the baseline the fim command verifies against and the
+// drift found since. Drift stays until -baseline-update approves the tree.
+const fimSchema = `
+CREATE TABLE IF NOT EXISTS baseline (
+	path      TEXT PRIMARY KEY,
+	algorithm TEXT NOT NULL,
+	digest    TEXT NOT NULL,
+	size      INTEGER NOT NULL,
+	entry     TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS drift (
+	path       TEXT NOT NULL,
+	kind       TEXT NOT NULL,
+	actual     TEXT NOT NULL,
+	first_seen TEXT NOT NULL,
+	PRIMARY KEY (path, kind, actual)
+);
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+// fimRecordedKey is the meta key holding when the baseline was recorded.
+const fimRecordedKey = "recorded"
+
+func fimDatabasePath(outputFile string) string {
+	return splitOutputDir(outputFile) + ".db"
+}
+
+// fimDatabaseFiles are the database and the journals SQLite keeps beside it,
+// all left out of the monitored tree.
+func fimDatabaseFiles(databasePath string) []string {
+	return []string{databasePath, databasePath + "-journal", databasePath + "-wal", databasePath + "-shm"}
+}
+
+type fimStore struct {
+	db *sql.DB
+}
+
+func openFIMStore(databasePath string) (*fimStore, error) {
+	// The busy timeout lets an approval run beside the monitoring daemon.
+	db, err := sql.Open("sqlite", "file:"+databasePath+"?_pragma=busy_timeout(10000)")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fim database: %w", err)
+	}
+
+	if _, err := db.Exec(fimSchema); err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("failed to create fim database: %w", err)
+	}
+
+	return &fimStore{db: db}, nil
+}
+
+func (s *fimStore) close() error {
+	return s.db.Close()
+}
+
+// recorded returns when the baseline was recorded, and false when there is
+// none yet.
+func (s *fimStore) recorded() (time.Time, bool, error) {
+	var value string
+
+	err := s.db.QueryRow("SELECT value FROM meta WHERE key = ?", fimRecordedKey).Scan(&value)
+
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read fim database: %w", err)
+	}
+
+	recorded, err := time.Parse(time.RFC3339Nano, value)
+
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse baseline time: %w", err)
+	}
+
+	return recorded, true, nil
+}
+
+// loadBaseline returns every baseline entry, tombstones included.
+func (s *fimStore) loadBaseline() ([]FileChecksum, error) {
+	rows, err := s.db.Query("SELECT entry FROM baseline ORDER BY path")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	defer rows.Close()
+
+	var entries []FileChecksum
+
+	for rows.Next() {
+		var data string
+
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read baseline: %w", err)
+		}
+
+		var entry FileChecksum
+
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse baseline entry: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	return entries, nil
+}
+
+// replaceBaseline makes entries the baseline and forgets all drift, which is
+// what approving the tree means.
+func (s *fimStore) replaceBaseline(entries []FileChecksum, recorded time.Time) error {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return fmt.Errorf("failed to update baseline: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	for _, statement := range []string{"DELETE FROM baseline", "DELETE FROM drift"} {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to update baseline: %w", err)
+		}
+	}
+
+	insert, err := tx.Prepare("INSERT INTO baseline (path, algorithm, digest, size, entry) VALUES (?, ?, ?, ?, ?)")
+
+	if err != nil {
+		return fmt.Errorf("failed to update baseline: %w", err)
+	}
+
+	defer insert.Close()
+
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal baseline entry: %w", err)
+		}
+
+		if _, err := insert.Exec(entry.Path, entry.Algorithm, entry.Checksum, entry.Size, string(data)); err != nil {
+			return fmt.Errorf("failed to update baseline: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec("INSERT OR REPLACE INTO meta (key, value) VALUES (?, ?)", fimRecordedKey, recorded.UTC().Format(time.RFC3339Nano)); err != nil {
+		return fmt.Errorf("failed to update baseline: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update baseline: %w", err)
+	}
+
+	return nil
+}
+
+// recordDrift stores the changes found by a verification and returns the ones
+// not seen before, which alert. Drift that went away, such as a restored
+// file, is forgotten, so it alerts again if it comes back. Nothing is written
+// when the drift is unchanged, which would wake the watcher again.
+func (s *fimStore) recordDrift(changes []fileChange, now time.Time) ([]fileChange, error) {
+	type driftKey struct {
+		path   string
+		kind   changeKind
+		actual string
+	}
+
+	rows, err := s.db.Query("SELECT path, kind, actual FROM drift")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read drift: %w", err)
+	}
+
+	known := make(map[driftKey]bool)
+
+	for rows.Next() {
+		var key driftKey
+
+		if err := rows.Scan(&key.path, &key.kind, &key.actual); err != nil {
+			rows.Close()
+
+			return nil, fmt.Errorf("failed to read drift: %w", err)
+		}
+
+		known[key] = true
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read drift: %w", err)
+	}
+
+	current := make(map[driftKey]bool, len(changes))
+
+	var fresh []fileChange
+
+	for _, change := range changes {
+		key := driftKey{path: change.Path, kind: change.Kind, actual: change.Actual}
+		current[key] = true
+
+		if !known[key] {
+			fresh = append(fresh, change)
+		}
+	}
+
+	var gone []driftKey
+
+	for key := range known {
+		if !current[key] {
+			gone = append(gone, key)
+		}
+	}
+
+	if len(fresh) == 0 && len(gone) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to record drift: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	for _, key := range gone {
+		if _, err := tx.Exec("DELETE FROM drift WHERE path = ? AND kind = ? AND actual = ?", key.path, key.kind, key.actual); err != nil {
+			return nil, fmt.Errorf("failed to record drift: %w", err)
+		}
+	}
+
+	for _, change := range fresh {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO drift (path, kind, actual, first_seen) VALUES (?, ?, ?, ?)", change.Path, change.Kind, change.Actual, now.UTC().Format(time.RFC3339Nano)); err != nil {
+			return nil, fmt.Errorf("failed to record drift: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to record drift: %w", err)
+	}
+
+	return fresh, nil
+}
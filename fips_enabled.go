@@ -0,0 +1,6 @@
+//go:build fips
+
+package main
+
+// fipsBuild turns -fips on for good in binaries built with -tags fips.
+const fipsBuild = true
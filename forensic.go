@@ -0,0 +1,37 @@
+package main
+
+import (
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// timestampLayout renders -forensic timestamps in UTC with a fixed number of
+// digits, so they sort and compare as strings.
+const timestampLayout = "2006-01-02T15:04:05.000000000Z"
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(timestampLayout)
+}
+
+// fileTimes returns the timestamps -forensic records for the file at path,
+// or nil when they are not recorded. They are captured before the file is
+// read, so a file system ignoring O_NOATIME still yields the access time
+// from before the scan.
+func (o scanOptions) fileTimes(path string) (*checksum.Timestamps, error) {
+	if !o.forensic {
+		return nil, nil
+	}
+
+	times, err := captureTimes(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &times, nil
+}
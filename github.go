@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultIssueTitle   = "Checksum verification failed"
+	githubMaxIssuePages = 10
+)
+
+type githubClient struct {
+	apiURL     string
+	repository string
+	token      string
+	http       *http.Client
+}
+
+type githubIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+
+	PullRequest *struct{} `json:"pull_request,omitempty"`
+}
+
+type githubError struct {
+	method  string
+	path    string
+	status  int
+	message string
+}
+
+func (e *githubError) Error() string {
+	return fmt.Sprintf("%s %s responded with %d: %s", e.method, e.path, e.status, e.message)
+}
+
+func isGithubNotFound(err error) bool {
+	var githubErr *githubError
+
+	return errors.As(err, &githubErr) && githubErr.status == http.StatusNotFound
+}
+
+func newGithubClient(token string) (*githubClient, error) {
+	repository := os.Getenv("GITHUB_REPOSITORY")
+
+	if repository == "" {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY is not set")
+	}
+
+	if token == "" {
+		return nil, fmt.Errorf("a GitHub token is required")
+	}
+
+	apiURL := os.Getenv("GITHUB_API_URL")
+
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+
+	return &githubClient{
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		repository: repository,
+		token:      token,
+		http:       &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (c *githubClient) do(method string, path string, body any, result any) error {
+	var payload io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+
+		if err != nil {
+			return err
+		}
+
+		payload = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, c.apiURL+path, payload)
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Accept", "application/vnd.github+json")
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.send(c.http, request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// send authenticates the request and turns error statuses into a githubError.
+// The caller closes the body of a successful response.
+func (c *githubClient) send(client *http.Client, request *http.Request) (*http.Response, error) {
+	request.Header.Set("Authorization", "Bearer "+c.token)
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode >= 300 {
+		defer response.Body.Close()
+
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+		return nil, &githubError{
+			method:  request.Method,
+			path:    request.URL.Path,
+			status:  response.StatusCode,
+			message: strings.TrimSpace(string(message)),
+		}
+	}
+
+	return response, nil
+}
+
+func (c *githubClient) findIssue(title string) (*githubIssue, error) {
+	for page := 1; page <= githubMaxIssuePages; page++ {
+		var issues []githubIssue
+
+		path := fmt.Sprintf("/repos/%s/issues?state=open&per_page=100&page=%d", c.repository, page)
+
+		if err := c.do(http.MethodGet, path, nil, &issues); err != nil {
+			return nil, err
+		}
+
+		for _, issue := range issues {
+			if issue.PullRequest == nil && issue.Title == title {
+				return &issue, nil
+			}
+		}
+
+		if len(issues) < 100 {
+			break
+		}
+	}
+
+	return nil, nil
+}
+
+func issueBody(report verifyReport) string {
+	var builder strings.Builder
+
+	builder.WriteString(summarizeReport(report))
+	builder.WriteString("\n\n| Kind | Path | Size delta |\n| --- | --- | --- |\n")
+
+	for _, change := range report.Changes {
+		fmt.Fprintf(&builder, "| %s | `%s` | %+d |\n", change.Kind, change.Path, change.SizeDelta)
+	}
+
+	return builder.String()
+}
+
+func fileGithubIssue(token string, title string, report verifyReport) (*githubIssue, error) {
+	client, err := newGithubClient(token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := client.findIssue(title)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to search issues: %w", err)
+	}
+
+	body := map[string]string{
+		"title": title,
+		"body":  issueBody(report),
+	}
+
+	var issue githubIssue
+
+	if existing != nil {
+		path := fmt.Sprintf("/repos/%s/issues/%d", client.repository, existing.Number)
+
+		if err := client.do(http.MethodPatch, path, body, &issue); err != nil {
+			return nil, fmt.Errorf("failed to update issue: %w", err)
+		}
+
+		return &issue, nil
+	}
+
+	if err := client.do(http.MethodPost, fmt.Sprintf("/repos/%s/issues", client.repository), body, &issue); err != nil {
+		return nil, fmt.Errorf("failed to create issue: %w", err)
+	}
+
+	return &issue, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+)
+
+const defaultGoPackage = "checksums"
+
+func validateGoPackage(name string) error {
+	if !token.IsIdentifier(name) {
+		return fmt.Errorf("invalid Go package name: %q", name)
+	}
+
+	return nil
+}
+
+func formatGoSource(checksums []FileChecksum, goPackage string) ([]byte, error) {
+	sorted := make([]FileChecksum, 0, len(checksums))
+
+	for _, checksum := range checksums {
+		if !checksum.PresenceOnly {
+			sorted = append(sorted, checksum)
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by checksum-action. DO NOT EDIT.\n\npackage %s\n\n", goPackage)
+	fmt.Fprintf(&buf, "// Checksums maps each file path to its expected digest.\nvar Checksums = map[string]string{\n")
+
+	for _, checksum := range sorted {
+		fmt.Fprintf(&buf, "%s: %s,\n", strconv.Quote(checksum.Path), strconv.Quote(checksum.Checksum))
+	}
+
+	buf.WriteString("}\n")
+
+	return format.Source(buf.Bytes())
+}
+
+func saveGoSource(checksums []FileChecksum, outputFile string, goPackage string) error {
+	outputData, err := formatGoSource(checksums, goPackage)
+
+	if err != nil {
+		return fmt.Errorf("failed to format Go source: %w", err)
+	}
+
+	if err := writeFileAtomic(outputFile, outputData, 0644); err != nil {
+		return fmt.Errorf("failed to write checksums to file: %w", err)
+	}
+
+	return nil
+}
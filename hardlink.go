@@ -0,0 +1,64 @@
+package main
+
+import "os"
+
+type inodeID struct {
+	device uint64
+	inode  uint64
+}
+
+type linkGroup struct {
+	index int
+	id    int
+}
+
+type hardLinks struct {
+	groups map[inodeID]*linkGroup
+	next   int
+}
+
+func newHardLinks() *hardLinks {
+	return &hardLinks{groups: make(map[inodeID]*linkGroup)}
+}
+
+// lookup returns the link group of a file with more than one hard link, or
+// nil for files that are not hard-linked. The group's index is negative until
+// the first member has been recorded.
+func (h *hardLinks) lookup(path string) (*linkGroup, error) {
+	info, err := os.Lstat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := fileInode(info)
+
+	if !ok {
+		return nil, nil
+	}
+
+	group, ok := h.groups[id]
+
+	if !ok {
+		group = &linkGroup{index: -1}
+		h.groups[id] = group
+	}
+
+	return group, nil
+}
+
+func (h *hardLinks) join(group *linkGroup, checksums []FileChecksum) int {
+	if group.id == 0 {
+		h.next++
+		group.id = h.next
+		checksums[group.index].LinkGroup = group.id
+	}
+
+	return group.id
+}
+
+func (g *linkGroup) add(index int) {
+	if g != nil && g.index < 0 {
+		g.index = index
+	}
+}
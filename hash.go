@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func generateDigest(ctx context.Context, filePath string, opts checksum.Options, algorithm string) ([]byte, int64, error) {
+	file, err := openScanned(filePath)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer file.Close()
+
+	return opts.Digest(ctx, file, algorithm)
+}
+
+const defaultReadPath = "standard"
+
+func validateReadPath(readPath string) error {
+	switch readPath {
+	case "standard", "fadvise":
+		return nil
+	}
+
+	return fmt.Errorf("unsupported read path: %s", readPath)
+}
+
+// digest hashes a file using the configured read path. The fadvise path
+// passes read-ahead hints on Linux and falls back to plain reads elsewhere.
+func (o scanOptions) digest(ctx context.Context, filePath string, algorithm string) ([]byte, int64, error) {
+	return o.digestInspected(ctx, filePath, algorithm, nil)
+}
+
+// digestInspected hashes a file like digest and writes every byte read to the
+// inspectors too, so they cost no second pass.
+func (o scanOptions) digestInspected(ctx context.Context, filePath string, algorithm string, inspectors []contentInspector) ([]byte, int64, error) {
+	if o.readPath != "fadvise" && len(inspectors) == 0 && o.canonicalize == "" {
+		return generateDigest(ctx, filePath, o.hash, algorithm)
+	}
+
+	file, err := openScanned(filePath)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer file.Close()
+
+	if o.readPath == "fadvise" {
+		adviseSequential(file)
+		defer adviseDone(file)
+	}
+
+	reader := inspectReader(file, inspectors)
+
+	if o.canonicalize == canonicalizeText {
+		// Sizes stay those of the files on disk, so quick checks still match.
+		canonical := newTextCanonicalizer(reader)
+
+		digest, _, err := o.hash.Digest(ctx, canonical, algorithm)
+
+		return digest, canonical.size, err
+	}
+
+	return o.hash.Digest(ctx, reader, algorithm)
+}
+
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
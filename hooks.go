@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func (o scanOptions) runFileHook(ctx context.Context, entry FileChecksum) error {
+	if len(o.fileHook) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+
+	if err != nil {
+		return fmt.Errorf("failed to marshal entry for file hook: %w", err)
+	}
+
+	if err := checksum.RunCommand(ctx, append(data, '\n'), o.fileHook[0], o.fileHook[1:]...); err != nil {
+		return fmt.Errorf("file hook for %s: %w", entry.Path, err)
+	}
+
+	return nil
+}
+
+func runCompleteHook(ctx context.Context, cfg config, checksums []FileChecksum) error {
+	command := strings.Fields(cfg.onComplete)
+
+	if len(command) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(checksums)
+
+	if err != nil {
+		return fmt.Errorf("failed to marshal entries for completion hook: %w", err)
+	}
+
+	return checksum.RunCommand(ctx, append(data, '\n'), command[0], command[1:]...)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+type manifestPart struct {
+	Name  string `json:"name"`
+	File  string `json:"file"`
+	Files int    `json:"files"`
+}
+
+type manifestIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Compliance    string          `json:"compliance,omitempty"`
+	Explain       json.RawMessage `json:"explain,omitempty"`
+	Parts         []manifestPart  `json:"parts"`
+}
+
+func isManifestIndex(data []byte) bool {
+	var fields map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+
+	_, ok := fields["parts"]
+
+	return ok
+}
+
+func saveIndex(index manifestIndex, indexFile string) error {
+	index.SchemaVersion = checksum.SchemaVersion
+
+	indexData, err := json.MarshalIndent(index, "", "  ")
+
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest index to JSON: %w", err)
+	}
+
+	if err := writeFileAtomic(indexFile, indexData, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest index to file: %w", err)
+	}
+
+	return nil
+}
+
+func loadIndex(indexFile string, indexData []byte) ([]FileChecksum, error) {
+	return loadIndexParts(indexFile, indexData, map[string]bool{filepath.Clean(indexFile): true})
+}
+
+func loadIndexParts(indexFile string, indexData []byte, seen map[string]bool) ([]FileChecksum, error) {
+	var index manifestIndex
+
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest index: %w", err)
+	}
+
+	partFiles, err := indexPartFiles(indexFile, index, seen)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var checksums []FileChecksum
+
+	for i, part := range index.Parts {
+		partChecksums, err := loadIndexPart(partFiles[i], seen)
+
+		if err != nil {
+			return nil, fmt.Errorf("part %s: %w", part.Name, err)
+		}
+
+		checksums = append(checksums, partChecksums...)
+	}
+
+	return checksums, nil
+}
+
+func loadIndexPart(partFile string, seen map[string]bool) ([]FileChecksum, error) {
+	partData, err := os.ReadFile(partFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums file: %w", err)
+	}
+
+	if isManifestIndex(partData) {
+		return loadIndexParts(partFile, partData, seen)
+	}
+
+	return checksum.ParseFile(partData, filepath.Base(partFile))
+}
+
+// indexPartFiles resolves the files listed by an index next to it. seen holds
+// the index files and parts already listed, so an index listing itself, an
+// index listing the one above it or a part listed twice is refused instead of
+// recursing forever or counting entries twice.
+func indexPartFiles(indexFile string, index manifestIndex, seen map[string]bool) ([]string, error) {
+	partFiles := make([]string, 0, len(index.Parts))
+
+	for _, part := range index.Parts {
+		partFile := filepath.Join(filepath.Dir(indexFile), filepath.FromSlash(part.File))
+
+		if seen[partFile] {
+			return nil, fmt.Errorf("invalid manifest index %s: part %s refers to %s, which is already listed", indexFile, part.Name, part.File)
+		}
+
+		seen[partFile] = true
+		partFiles = append(partFiles, partFile)
+	}
+
+	return partFiles, nil
+}
+
+func pageFileName(outputFile string, page int) string {
+	extension := filepath.Ext(outputFile)
+
+	return fmt.Sprintf("%s-%04d%s", strings.TrimSuffix(outputFile, extension), page, extension)
+}
+
+// writtenPages lists the pages written with -max-entries for the index at
+// outputFile. Only parts named as saveManifest names them count, so user files
+// that merely look like pages are never mistaken for them.
+func writtenPages(outputFile string) []string {
+	data, err := os.ReadFile(outputFile)
+
+	if err != nil || !isManifestIndex(data) {
+		return nil
+	}
+
+	var index manifestIndex
+
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil
+	}
+
+	var pages []string
+
+	for i, part := range index.Parts {
+		if page := pageFileName(outputFile, i+1); part.File == filepath.Base(page) {
+			pages = append(pages, page)
+		}
+	}
+
+	return pages
+}
+
+// partHeader is the header of the parts listed by an index, which holds the
+// explanation once for all of them.
+func partHeader(header checksum.Header) checksum.Header {
+	header.Explain = nil
+
+	return header
+}
+
+func saveManifest(checksums []FileChecksum, outputFile string, format string, maxEntries int, header checksum.Header) error {
+	stale := writtenPages(outputFile)
+
+	if maxEntries <= 0 || len(checksums) <= maxEntries {
+		if err := saveToFile(checksums, outputFile, format, header); err != nil {
+			return err
+		}
+
+		return removePages(stale)
+	}
+
+	index := manifestIndex{Compliance: header.Compliance, Explain: header.Explain}
+
+	for start := 0; start < len(checksums); start += maxEntries {
+		page := checksums[start:min(start+maxEntries, len(checksums))]
+		pageFile := pageFileName(outputFile, len(index.Parts)+1)
+
+		if err := saveToFile(page, pageFile, format, partHeader(header)); err != nil {
+			return err
+		}
+
+		index.Parts = append(index.Parts, manifestPart{
+			Name:  fmt.Sprintf("%04d", len(index.Parts)+1),
+			File:  filepath.Base(pageFile),
+			Files: len(page),
+		})
+	}
+
+	if err := saveIndex(index, outputFile); err != nil {
+		return err
+	}
+
+	return removePages(stale[min(len(index.Parts), len(stale)):])
+}
+
+// removePages removes pages a previous run wrote beyond the ones written now,
+// which would otherwise linger in the tree and be hashed as ordinary files.
+func removePages(pages []string) error {
+	for _, page := range pages {
+		if err := os.Remove(page); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale manifest page: %w", err)
+		}
+	}
+
+	return nil
+}
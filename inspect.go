@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// contentInspector looks at the bytes of a file while it is hashed and
+// records what it found on the entry.
+type contentInspector interface {
+	io.Writer
+	record(entry *FileChecksum)
+}
+
+// newInspectors returns fresh inspectors for one file, one per enabled
+// content check.
+func (o scanOptions) newInspectors() []contentInspector {
+	var inspectors []contentInspector
+
+	if o.detectEncoding {
+		inspectors = append(inspectors, &encodingInspector{ascii: true, valid: true})
+	}
+
+	if o.entropy {
+		inspectors = append(inspectors, &entropyInspector{})
+	}
+
+	if o.scanSecrets {
+		inspectors = append(inspectors, newSecretInspector())
+	}
+
+	if o.similarity {
+		inspectors = append(inspectors, &sketchInspector{})
+	}
+
+	return inspectors
+}
+
+func inspectReader(r io.Reader, inspectors []contentInspector) io.Reader {
+	if len(inspectors) == 0 {
+		return r
+	}
+
+	writers := make([]io.Writer, len(inspectors))
+
+	for i, inspector := range inspectors {
+		writers[i] = inspector
+	}
+
+	return io.TeeReader(r, io.MultiWriter(writers...))
+}
+
+const (
+	encodingASCII   = "ascii"
+	encodingUTF8    = "utf-8"
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+	encodingLatin1  = "latin-1"
+	encodingBinary  = "binary"
+)
+
+// encodingInspector tells text encodings apart. UTF-16 is only recognised by
+// its byte order mark, a NUL byte otherwise makes a file binary, and bytes
+// that are not valid UTF-8 are taken for latin-1.
+type encodingInspector struct {
+	started  bool
+	encoding string
+	binary   bool
+	ascii    bool
+	valid    bool
+	// pending holds a rune split across two writes.
+	pending []byte
+}
+
+func (i *encodingInspector) Write(p []byte) (int, error) {
+	if !i.started {
+		i.started = true
+
+		switch {
+		case len(p) >= 2 && p[0] == 0xFF && p[1] == 0xFE:
+			i.encoding = encodingUTF16LE
+		case len(p) >= 2 && p[0] == 0xFE && p[1] == 0xFF:
+			i.encoding = encodingUTF16BE
+		}
+	}
+
+	if i.encoding != "" || i.binary {
+		return len(p), nil
+	}
+
+	for _, b := range p {
+		if b == 0 {
+			i.binary = true
+
+			return len(p), nil
+		}
+
+		if b >= utf8.RuneSelf {
+			i.ascii = false
+		}
+	}
+
+	if i.ascii || !i.valid {
+		return len(p), nil
+	}
+
+	data := p
+
+	if len(i.pending) > 0 {
+		data = append(i.pending, p...)
+		i.pending = nil
+	}
+
+	split := incompleteRune(data)
+
+	if !utf8.Valid(data[:len(data)-split]) {
+		i.valid = false
+
+		return len(p), nil
+	}
+
+	i.pending = append([]byte{}, data[len(data)-split:]...)
+
+	return len(p), nil
+}
+
+// incompleteRune returns the length of the unfinished rune ending data.
+func incompleteRune(data []byte) int {
+	for n := 1; n <= utf8.UTFMax-1 && n <= len(data); n++ {
+		if utf8.RuneStart(data[len(data)-n]) {
+			if utf8.FullRune(data[len(data)-n:]) {
+				return 0
+			}
+
+			return n
+		}
+	}
+
+	return 0
+}
+
+func (i *encodingInspector) record(entry *FileChecksum) {
+	switch {
+	case i.encoding != "":
+		entry.Encoding = i.encoding
+	case i.binary:
+		entry.Encoding = encodingBinary
+	case i.ascii:
+		entry.Encoding = encodingASCII
+	case i.valid && len(i.pending) == 0:
+		entry.Encoding = encodingUTF8
+	default:
+		entry.Encoding = encodingLatin1
+	}
+}
+
+// minEntropySize is the size below which files are never flagged for high
+// entropy, since a short file cannot show the byte distribution.
+const minEntropySize = 1024
+
+const defaultEntropyThreshold = 7.5
+
+// entropyInspector measures the Shannon entropy of a file in bits per byte,
+// from 0 for a single repeated byte to 8 for uniformly random data.
+type entropyInspector struct {
+	counts [256]int64
+	total  int64
+}
+
+func (i *entropyInspector) Write(p []byte) (int, error) {
+	for _, b := range p {
+		i.counts[b]++
+	}
+
+	i.total += int64(len(p))
+
+	return len(p), nil
+}
+
+func (i *entropyInspector) entropy() float64 {
+	var entropy float64
+
+	for _, count := range i.counts {
+		if count > 0 {
+			p := float64(count) / float64(i.total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+
+	return math.Round(entropy*1000) / 1000
+}
+
+func (i *entropyInspector) record(entry *FileChecksum) {
+	entry.Entropy = i.entropy()
+}
+
+// isHighEntropy reports whether a file of size with entropy looks packed or
+// encrypted under -entropy-threshold.
+func (o scanOptions) isHighEntropy(size int64, entropy float64) bool {
+	return o.entropy && size >= minEntropySize && entropy >= o.entropyThreshold
+}
+
+// printHighEntropy lists the files of a new manifest that look packed or
+// encrypted.
+func printHighEntropy(opts scanOptions, checksums []FileChecksum) {
+	var paths []string
+
+	for _, entry := range checksums {
+		if opts.isHighEntropy(entry.Size, entry.Entropy) {
+			paths = append(paths, entry.Path)
+		}
+	}
+
+	if len(paths) > 0 {
+		fmt.Printf("%d files look packed or encrypted (entropy of at least %.2f bits per byte): %s\n", len(paths), opts.entropyThreshold, strings.Join(paths, ", "))
+	}
+}
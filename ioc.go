@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// iocBatchSize is the number of digests sent to -ioc-url per request.
+const iocBatchSize = 100
+
+// iocUnnamed names blocklist matches listed without a name.
+const iocUnnamed = "blocklisted"
+
+// lookupIOCs asks the -ioc-url API which digests are known indicators. It is
+// sent {"algorithm": "sha256", "digests": [...]} and answers with
+// {"matches": {"<digest>": "<name>"}}.
+func lookupIOCs(ctx context.Context, url string, token string, algorithm string, digests []string) (map[string]string, error) {
+	matches := make(map[string]string)
+
+	for start := 0; start < len(digests); start += iocBatchSize {
+		batch := digests[start:min(start+iocBatchSize, len(digests))]
+
+		body, err := json.Marshal(map[string]any{"algorithm": algorithm, "digests": batch})
+
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Content-Type", "application/json")
+
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		var response struct {
+			Matches map[string]string `json:"matches"`
+		}
+
+		if err := doCredentialRequest(request, &response); err != nil {
+			return nil, fmt.Errorf("failed to look up digests: %w", withoutURL(err))
+		}
+
+		for digest, name := range response.Matches {
+			if name == "" {
+				name = iocUnnamed
+			}
+
+			matches[normalizeDigest(digest)] = name
+		}
+	}
+
+	return matches, nil
+}
+
+// sweepIOCs marks the entries whose digest is a known indicator of
+// compromise, on the -ioc-blocklist or according to -ioc-url, and returns how
+// many match.
+func sweepIOCs(ctx context.Context, cfg config, checksums []FileChecksum) (int, error) {
+	indicators := make(map[string]string)
+
+	if cfg.iocBlocklist != "" {
+		blocklist, err := loadDigestList(cfg.iocBlocklist, iocUnnamed)
+
+		if err != nil {
+			return 0, fmt.Errorf("failed to load blocklist: %w", err)
+		}
+
+		indicators = blocklist
+	}
+
+	if cfg.iocURL != "" {
+		byAlgorithm := make(map[string][]string)
+
+		for _, entry := range checksums {
+			if !entry.PresenceOnly {
+				byAlgorithm[entry.Algorithm] = append(byAlgorithm[entry.Algorithm], normalizeDigest(entry.Checksum))
+			}
+		}
+
+		for algorithm, digests := range byAlgorithm {
+			matches, err := lookupIOCs(ctx, cfg.iocURL, cfg.iocToken, algorithm, digests)
+
+			if err != nil {
+				return 0, err
+			}
+
+			for digest, name := range matches {
+				indicators[digest] = name
+			}
+		}
+	}
+
+	found := 0
+
+	for i, entry := range checksums {
+		if name, ok := indicators[normalizeDigest(entry.Checksum)]; ok && !entry.PresenceOnly {
+			checksums[i].IOC = name
+			found++
+
+			fmt.Printf("Known indicator of compromise: %s (%s)\n", entry.Path, name)
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func formatJUnit(report verifyReport) ([]byte, error) {
+	suite := junitTestSuite{Name: report.Root}
+
+	for _, path := range report.unchanged {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      path,
+			ClassName: "checksum",
+		})
+	}
+
+	for _, change := range report.Changes {
+		testCase := junitTestCase{
+			Name:      change.Path,
+			ClassName: "checksum",
+		}
+
+		problem := &junitProblem{
+			Message: "file " + string(change.Kind),
+			Type:    string(change.Kind),
+			Text:    describeChange(change),
+		}
+
+		if change.Kind == changeError {
+			testCase.Error = problem
+			suite.Errors++
+		} else {
+			testCase.Failure = problem
+			suite.Failures++
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	sort.SliceStable(suite.TestCases, func(i, j int) bool {
+		return suite.TestCases[i].Name < suite.TestCases[j].Name
+	})
+
+	suite.Tests = len(suite.TestCases)
+
+	suites := junitTestSuites{
+		Name:     "checksum-action",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	output, err := xml.MarshalIndent(suites, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), output...), nil
+}
@@ -0,0 +1,709 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+const (
+	awsSTSAudience          = "sts.amazonaws.com"
+	defaultGCPKMSURL        = "https://cloudkms.googleapis.com/"
+	gcpMetadataTokens       = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	defaultAzureAuthority   = "https://login.microsoftonline.com"
+	azureKeyVaultScope      = "https://vault.azure.net/.default"
+	azureKeyVaultAPIVersion = "7.4"
+	azureFederatedAudience  = "api://AzureADTokenExchange"
+)
+
+// kmsSigner is a crypto.Signer whose private key never leaves the KMS; only
+// the digest is sent for signing.
+type kmsSigner struct {
+	public crypto.PublicKey
+	sign   func(digest []byte) ([]byte, error)
+}
+
+func (s kmsSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s kmsSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.SHA256 {
+		return nil, fmt.Errorf("KMS signing supports SHA-256 digests only")
+	}
+
+	return s.sign(digest)
+}
+
+// kmsKeyName accepts both scheme:key and the scheme:///key URI form used by
+// cosign.
+func kmsKeyName(name string) string {
+	return strings.TrimLeft(name, "/")
+}
+
+func kmsKeyAlgorithm(public crypto.PublicKey) (string, error) {
+	switch key := public.(type) {
+	case *ecdsa.PublicKey:
+		if key.Curve.Params().BitSize != 256 {
+			return "", fmt.Errorf("unsupported ECDSA curve %s, use P-256", key.Curve.Params().Name)
+		}
+
+		return "ecdsa", nil
+	case *rsa.PublicKey:
+		return "rsa", nil
+	}
+
+	return "", fmt.Errorf("unsupported KMS key type %T", public)
+}
+
+type awsKMSCredentials struct{}
+
+func (awsKMSCredentials) Secret(ctx context.Context, name string) ([]byte, error) {
+	return nil, fmt.Errorf("awskms keys sign remotely and cannot be read as a secret")
+}
+
+// Signer uses the KMS key given by ID, ARN or alias. Credentials come from
+// the AWS_* environment, or from AWS_ROLE_ARN assumed with a web identity
+// token file or the job's GitHub OIDC token.
+func (awsKMSCredentials) Signer(ctx context.Context, name string) (crypto.Signer, error) {
+	name = kmsKeyName(name)
+	region := awsRegion(name)
+
+	if region == "" {
+		return nil, fmt.Errorf("set AWS_REGION or use a key ARN for %s", name)
+	}
+
+	credentials, err := loadAWSCredentials(ctx, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var key struct {
+		PublicKey string
+	}
+
+	if err := awsKMSCall(ctx, credentials, region, "GetPublicKey", map[string]string{"KeyId": name}, &key); err != nil {
+		return nil, fmt.Errorf("failed to get public key of %s: %w", name, err)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(key.PublicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := x509.ParsePKIXPublicKey(der)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key of %s: %w", name, err)
+	}
+
+	algorithm, err := kmsKeyAlgorithm(public)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signingAlgorithm := "ECDSA_SHA_256"
+
+	if algorithm == "rsa" {
+		signingAlgorithm = "RSASSA_PKCS1_V1_5_SHA_256"
+	}
+
+	return kmsSigner{
+		public: public,
+		sign: func(digest []byte) ([]byte, error) {
+			var signed struct {
+				Signature string
+			}
+
+			body := map[string]string{
+				"KeyId":            name,
+				"Message":          base64.StdEncoding.EncodeToString(digest),
+				"MessageType":      "DIGEST",
+				"SigningAlgorithm": signingAlgorithm,
+			}
+
+			if err := awsKMSCall(ctx, credentials, region, "Sign", body, &signed); err != nil {
+				return nil, fmt.Errorf("failed to sign with %s: %w", name, err)
+			}
+
+			return base64.StdEncoding.DecodeString(signed.Signature)
+		},
+	}, nil
+}
+
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+func awsRegion(keyID string) string {
+	if fields := strings.Split(keyID, ":"); len(fields) > 3 && fields[0] == "arn" {
+		return fields[3]
+	}
+
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+func awsEndpoint(service string, region string) string {
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL_" + strings.ToUpper(service)); endpoint != "" {
+		return endpoint
+	}
+
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		return endpoint
+	}
+
+	return "https://" + service + "." + region + ".amazonaws.com"
+}
+
+func loadAWSCredentials(ctx context.Context, region string) (awsCredentials, error) {
+	if accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyID != "" {
+		return awsCredentials{
+			accessKeyID:     accessKeyID,
+			secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+
+	if roleARN == "" {
+		return awsCredentials{}, fmt.Errorf("set AWS_ACCESS_KEY_ID, or AWS_ROLE_ARN to assume a role with GitHub OIDC")
+	}
+
+	var token string
+
+	if tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+
+		if err != nil {
+			return awsCredentials{}, err
+		}
+
+		token = strings.TrimSpace(string(data))
+	} else {
+		jwt, err := githubIDToken(ctx, awsSTSAudience)
+
+		if err != nil {
+			return awsCredentials{}, err
+		}
+
+		token = jwt
+	}
+
+	form := url.Values{}
+	form.Set("Action", "AssumeRoleWithWebIdentity")
+	form.Set("Version", "2011-06-15")
+	form.Set("RoleArn", roleARN)
+	form.Set("RoleSessionName", "checksum-action")
+	form.Set("WebIdentityToken", token)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, awsEndpoint("sts", region), strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return awsCredentials{}, err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return awsCredentials{}, err
+	}
+
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+
+	if err != nil {
+		return awsCredentials{}, err
+	}
+
+	if response.StatusCode >= 300 {
+		return awsCredentials{}, fmt.Errorf("failed to assume %s: STS responded with %d: %s", roleARN, response.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var assumed struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string `xml:"SecretAccessKey"`
+			SessionToken    string `xml:"SessionToken"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+
+	if err := xml.Unmarshal(data, &assumed); err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to decode STS response: %w", err)
+	}
+
+	return awsCredentials{
+		accessKeyID:     assumed.Credentials.AccessKeyID,
+		secretAccessKey: assumed.Credentials.SecretAccessKey,
+		sessionToken:    assumed.Credentials.SessionToken,
+	}, nil
+}
+
+// awsKMSCall sends a KMS JSON 1.1 request signed with Signature Version 4.
+func awsKMSCall(ctx context.Context, credentials awsCredentials, region string, action string, body any, result any) error {
+	payload, err := json.Marshal(body)
+
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, awsEndpoint("kms", region), bytes.NewReader(payload))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	request.Header.Set("X-Amz-Target", "TrentService."+action)
+
+	signAWSRequest(request, payload, credentials, region, "kms", time.Now().UTC())
+
+	return doCredentialRequest(request, result)
+}
+
+func signAWSRequest(request *http.Request, payload []byte, credentials awsCredentials, region string, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + region + "/" + service + "/aws4_request"
+	payloadHash := sha256.Sum256(payload)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+
+	if credentials.sessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.sessionToken)
+	}
+
+	names := []string{"content-type", "host", "x-amz-date"}
+
+	if credentials.sessionToken != "" {
+		names = append(names, "x-amz-security-token")
+	}
+
+	if request.Header.Get("X-Amz-Target") != "" {
+		names = append(names, "x-amz-target")
+	}
+
+	var headers strings.Builder
+
+	for _, name := range names {
+		value := request.Header.Get(name)
+
+		if name == "host" {
+			value = request.URL.Host
+		}
+
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	path := request.URL.EscapedPath()
+
+	if path == "" {
+		path = "/"
+	}
+
+	signedHeaders := strings.Join(names, ";")
+	canonical := strings.Join([]string{request.Method, path, request.URL.RawQuery, headers.String(), signedHeaders, hex.EncodeToString(payloadHash[:])}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + credentials.secretAccessKey)
+
+	for _, part := range []string{now.Format("20060102"), region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", credentials.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+type gcpKMSCredentials struct{}
+
+func (gcpKMSCredentials) Secret(ctx context.Context, name string) ([]byte, error) {
+	return nil, fmt.Errorf("gcpkms keys sign remotely and cannot be read as a secret")
+}
+
+// Signer uses the key version projects/P/locations/L/keyRings/R/cryptoKeys/K/
+// cryptoKeyVersions/V, authenticated with GOOGLE_OAUTH_ACCESS_TOKEN (as set by
+// google-github-actions/auth) or the metadata server.
+func (gcpKMSCredentials) Signer(ctx context.Context, name string) (crypto.Signer, error) {
+	name = kmsKeyName(name)
+	token, err := gcpAccessToken(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := os.Getenv("CLOUDSDK_API_ENDPOINT_OVERRIDES_CLOUDKMS")
+
+	if baseURL == "" {
+		baseURL = defaultGCPKMSURL
+	}
+
+	keyURL := strings.TrimSuffix(baseURL, "/") + "/v1/" + strings.TrimPrefix(name, "/")
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, keyURL+"/publicKey", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	var key struct {
+		PEM       string `json:"pem"`
+		Algorithm string `json:"algorithm"`
+	}
+
+	if err := doCredentialRequest(request, &key); err != nil {
+		return nil, fmt.Errorf("failed to get public key of %s: %w", name, err)
+	}
+
+	if !strings.HasSuffix(key.Algorithm, "SHA256") || strings.Contains(key.Algorithm, "PSS") {
+		return nil, fmt.Errorf("unsupported key algorithm %s, use EC_SIGN_P256_SHA256 or an RSA_SIGN_PKCS1_*_SHA256 key", key.Algorithm)
+	}
+
+	block, _ := pem.Decode([]byte(key.PEM))
+
+	if block == nil {
+		return nil, fmt.Errorf("public key of %s is not PEM encoded", name)
+	}
+
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key of %s: %w", name, err)
+	}
+
+	if _, err := kmsKeyAlgorithm(public); err != nil {
+		return nil, err
+	}
+
+	return kmsSigner{
+		public: public,
+		sign: func(digest []byte) ([]byte, error) {
+			body, err := json.Marshal(map[string]any{
+				"digest": map[string]string{"sha256": base64.StdEncoding.EncodeToString(digest)},
+			})
+
+			if err != nil {
+				return nil, err
+			}
+
+			request, err := http.NewRequestWithContext(ctx, http.MethodPost, keyURL+":asymmetricSign", bytes.NewReader(body))
+
+			if err != nil {
+				return nil, err
+			}
+
+			request.Header.Set("Authorization", "Bearer "+token)
+			request.Header.Set("Content-Type", "application/json")
+
+			var signed struct {
+				Signature string `json:"signature"`
+			}
+
+			if err := doCredentialRequest(request, &signed); err != nil {
+				return nil, fmt.Errorf("failed to sign with %s: %w", name, err)
+			}
+
+			return base64.StdEncoding.DecodeString(signed.Signature)
+		},
+	}, nil
+}
+
+func gcpAccessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokens, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	request.Header.Set("Metadata-Flavor", "Google")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := doCredentialRequest(request, &token); err != nil {
+		return "", fmt.Errorf("set GOOGLE_OAUTH_ACCESS_TOKEN or run on Google Cloud: %w", err)
+	}
+
+	return token.AccessToken, nil
+}
+
+type azureKMSCredentials struct{}
+
+func (azureKMSCredentials) Secret(ctx context.Context, name string) ([]byte, error) {
+	return nil, fmt.Errorf("azurekms keys sign remotely and cannot be read as a secret")
+}
+
+// Signer uses the Key Vault key VAULT.vault.azure.net/KEY[/VERSION]. A token
+// is requested for AZURE_CLIENT_ID in AZURE_TENANT_ID with AZURE_CLIENT_SECRET
+// or, without one, the job's GitHub OIDC token as a federated credential.
+func (azureKMSCredentials) Signer(ctx context.Context, name string) (crypto.Signer, error) {
+	name = kmsKeyName(name)
+	host, key, found := strings.Cut(name, "/")
+
+	if !found || host == "" || key == "" {
+		return nil, fmt.Errorf("invalid Azure key %q, expected VAULT.vault.azure.net/KEY[/VERSION]", name)
+	}
+
+	if !strings.Contains(host, ".") && !strings.Contains(host, ":") {
+		host += ".vault.azure.net"
+	}
+
+	scheme := "https"
+
+	if hostname, _, _ := strings.Cut(host, ":"); hostname == "localhost" || hostname == "127.0.0.1" {
+		scheme = "http"
+	}
+
+	keyURL := scheme + "://" + host + "/keys/" + key
+
+	token, err := azureAccessToken(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, keyURL+"?api-version="+azureKeyVaultAPIVersion, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	var bundle struct {
+		Key struct {
+			KID string `json:"kid"`
+			KTY string `json:"kty"`
+			CRV string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"key"`
+	}
+
+	if err := doCredentialRequest(request, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to get public key of %s: %w", name, err)
+	}
+
+	public, algorithm, err := azurePublicKey(bundle.Key.KTY, bundle.Key.CRV, bundle.Key.X, bundle.Key.Y, bundle.Key.N, bundle.Key.E)
+
+	if err != nil {
+		return nil, fmt.Errorf("unsupported key %s: %w", name, err)
+	}
+
+	// Sign with the exact version resolved above, so a rotation in between
+	// cannot produce a signature the returned public key does not verify.
+	signURL := keyURL
+
+	if kid, err := url.Parse(bundle.Key.KID); err == nil && kid.Path != "" {
+		signURL = scheme + "://" + host + kid.Path
+	}
+
+	return kmsSigner{
+		public: public,
+		sign: func(digest []byte) ([]byte, error) {
+			body, err := json.Marshal(map[string]string{
+				"alg":   algorithm,
+				"value": base64.RawURLEncoding.EncodeToString(digest),
+			})
+
+			if err != nil {
+				return nil, err
+			}
+
+			request, err := http.NewRequestWithContext(ctx, http.MethodPost, signURL+"/sign?api-version="+azureKeyVaultAPIVersion, bytes.NewReader(body))
+
+			if err != nil {
+				return nil, err
+			}
+
+			request.Header.Set("Authorization", "Bearer "+token)
+			request.Header.Set("Content-Type", "application/json")
+
+			var signed struct {
+				Value string `json:"value"`
+			}
+
+			if err := doCredentialRequest(request, &signed); err != nil {
+				return nil, fmt.Errorf("failed to sign with %s: %w", name, err)
+			}
+
+			signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(signed.Value, "="))
+
+			if err != nil {
+				return nil, err
+			}
+
+			if algorithm == "ES256" {
+				return ecdsaRawToASN1(signature)
+			}
+
+			return signature, nil
+		},
+	}, nil
+}
+
+func azurePublicKey(kty string, crv string, x string, y string, n string, e string) (crypto.PublicKey, string, error) {
+	decode := func(value string) (*big.Int, error) {
+		data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+
+		return new(big.Int).SetBytes(data), err
+	}
+
+	switch kty {
+	case "EC", "EC-HSM":
+		if crv != "P-256" {
+			return nil, "", fmt.Errorf("curve %s, use P-256", crv)
+		}
+
+		px, err := decode(x)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		py, err := decode(y)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: px, Y: py}, "ES256", nil
+	case "RSA", "RSA-HSM":
+		modulus, err := decode(n)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		exponent, err := decode(e)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, "RS256", nil
+	}
+
+	return nil, "", fmt.Errorf("key type %s", kty)
+}
+
+// ecdsaRawToASN1 converts the r||s signatures returned by Key Vault to the
+// ASN.1 form Go and OpenSSL verify.
+func ecdsaRawToASN1(signature []byte) ([]byte, error) {
+	if len(signature) != 64 {
+		return nil, fmt.Errorf("unexpected ES256 signature length %d", len(signature))
+	}
+
+	var builder cryptobyte.Builder
+
+	builder.AddASN1(asn1.SEQUENCE, func(sequence *cryptobyte.Builder) {
+		sequence.AddASN1BigInt(new(big.Int).SetBytes(signature[:32]))
+		sequence.AddASN1BigInt(new(big.Int).SetBytes(signature[32:]))
+	})
+
+	return builder.Bytes()
+}
+
+func azureAccessToken(ctx context.Context) (string, error) {
+	tenant := os.Getenv("AZURE_TENANT_ID")
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+
+	if tenant == "" || clientID == "" {
+		return "", fmt.Errorf("set AZURE_TENANT_ID and AZURE_CLIENT_ID to sign with Azure Key Vault")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientID)
+	form.Set("scope", azureKeyVaultScope)
+
+	if secret := os.Getenv("AZURE_CLIENT_SECRET"); secret != "" {
+		form.Set("client_secret", secret)
+	} else {
+		jwt, err := githubIDToken(ctx, azureFederatedAudience)
+
+		if err != nil {
+			return "", err
+		}
+
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", jwt)
+	}
+
+	authority := os.Getenv("AZURE_AUTHORITY_HOST")
+
+	if authority == "" {
+		authority = defaultAzureAuthority
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(authority, "/")+"/"+tenant+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return "", err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := doCredentialRequest(request, &token); err != nil {
+		return "", fmt.Errorf("failed to request Azure token: %w", err)
+	}
+
+	return token.AccessToken, nil
+}
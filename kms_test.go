@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAWSRequest(t *testing.T) {
+	credentials := awsCredentials{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	t.Run("documented example", func(t *testing.T) {
+		// The IAM ListUsers request from the AWS Signature Version 4 documentation.
+		request, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+		signAWSRequest(request, nil, credentials, "us-east-1", "iam", now)
+
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+
+		if got := request.Header.Get("Authorization"); got != want {
+			t.Errorf("Authorization = %q, want %q", got, want)
+		}
+
+		if got := request.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+			t.Errorf("X-Amz-Date = %q, want 20150830T123600Z", got)
+		}
+	})
+
+	t.Run("kms request with a session token", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodPost, "https://kms.eu-west-1.amazonaws.com", strings.NewReader("{}"))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+		request.Header.Set("X-Amz-Target", "TrentService.Sign")
+
+		withToken := credentials
+		withToken.sessionToken = "token"
+
+		signAWSRequest(request, []byte("{}"), withToken, "eu-west-1", "kms", now)
+
+		authorization := request.Header.Get("Authorization")
+
+		if !strings.Contains(authorization, "Credential=AKIDEXAMPLE/20150830/eu-west-1/kms/aws4_request") {
+			t.Errorf("Authorization = %q, want the eu-west-1 kms scope", authorization)
+		}
+
+		if !strings.Contains(authorization, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target,") {
+			t.Errorf("Authorization = %q, want the token and target headers signed", authorization)
+		}
+
+		if got := request.Header.Get("X-Amz-Security-Token"); got != "token" {
+			t.Errorf("X-Amz-Security-Token = %q, want token", got)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+)
+
+// knownGoodUnnamed names allowlist matches listed without a name.
+const knownGoodUnnamed = "known-good"
+
+// loadKnownGood merges the -known-good hash sets, such as the NSRL RDS or an
+// allowlist of vendor files.
+func loadKnownGood(paths []string) (map[string]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	knownGood := make(map[string]string)
+
+	for _, path := range paths {
+		digests, err := loadDigestList(path, knownGoodUnnamed)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", path, err)
+		}
+
+		for digest, name := range digests {
+			knownGood[digest] = name
+		}
+	}
+
+	return knownGood, nil
+}
+
+// knownGoodRule ignores the added and unlisted files -known-good-filter
+// leaves out. Modified files are still reported: known content replacing a
+// recorded file is a change all the same.
+func knownGoodRule() severityRule {
+	knownGood := true
+
+	return severityRule{
+		Kinds:     []changeKind{changeAdded, changeUnlisted},
+		KnownGood: &knownGood,
+		Severity:  severityIgnore,
+	}
+}
+
+// markKnownGood names the known-good file the new content of each change
+// matches.
+func markKnownGood(result *verifyResult, knownGood map[string]string) {
+	if len(knownGood) == 0 {
+		return
+	}
+
+	marked := make([]fileChange, len(result.changes))
+
+	for i, change := range result.changes {
+		if change.Actual != "" {
+			change.KnownGood = knownGood[normalizeDigest(change.Actual)]
+		}
+
+		marked[i] = change
+	}
+
+	result.changes = marked
+}
+
+// filterKnownGood names the known-good file each entry matches or, under
+// -known-good-filter, leaves the matching entries out. It returns the entries
+// kept and how many matched.
+func filterKnownGood(cfg config, checksums []FileChecksum) ([]FileChecksum, int) {
+	var kept []FileChecksum
+
+	matched := 0
+
+	for _, entry := range checksums {
+		name, ok := cfg.knownGood[normalizeDigest(entry.Checksum)]
+
+		if ok && !entry.PresenceOnly {
+			matched++
+
+			if cfg.knownGoodFilter {
+				continue
+			}
+
+			entry.KnownGood = name
+		}
+
+		kept = append(kept, entry)
+	}
+
+	return kept, matched
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type kubernetesObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Data       map[string]string  `yaml:"data"`
+	StringData map[string]string  `yaml:"stringData"`
+	BinaryData map[string]string  `yaml:"binaryData"`
+	Items      []kubernetesObject `yaml:"items"`
+}
+
+// kubernetesKeys returns the decoded value of every ConfigMap and Secret key
+// in a rendered manifest, keyed by kind/namespace/name/key.
+func kubernetesKeys(data []byte) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var object kubernetesObject
+
+		err := decoder.Decode(&object)
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := collectKubernetesKeys(object, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+func collectKubernetesKeys(object kubernetesObject, keys map[string][]byte) error {
+	for _, item := range object.Items {
+		if err := collectKubernetesKeys(item, keys); err != nil {
+			return err
+		}
+	}
+
+	namespace := object.Metadata.Namespace
+
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	prefix := path.Join(strings.ToLower(object.Kind), namespace, object.Metadata.Name)
+
+	switch object.Kind {
+	case "ConfigMap":
+		for key, value := range object.Data {
+			keys[path.Join(prefix, key)] = []byte(value)
+		}
+
+		for key, value := range object.BinaryData {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+
+			if err != nil {
+				return fmt.Errorf("failed to decode binaryData %s: %w", path.Join(prefix, key), err)
+			}
+
+			keys[path.Join(prefix, key)] = decoded
+		}
+	case "Secret":
+		for key, value := range object.Data {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+
+			if err != nil {
+				return fmt.Errorf("failed to decode data %s: %w", path.Join(prefix, key), err)
+			}
+
+			keys[path.Join(prefix, key)] = decoded
+		}
+
+		for key, value := range object.StringData {
+			keys[path.Join(prefix, key)] = []byte(value)
+		}
+	}
+
+	return nil
+}
+
+// volumeKeys reads the keys of a mounted ConfigMap or Secret volume. The
+// kubelet's "..data" and timestamped directories are skipped, the key symlinks
+// are followed.
+func volumeKeys(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]byte)
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys[path.Join(filepath.Base(dir), entry.Name())] = data
+	}
+
+	return keys, nil
+}
+
+func hashKubernetesKeys(ctx context.Context, sources []string, opts scanOptions, algorithms map[string]string) ([]FileChecksum, error) {
+	var checksums []FileChecksum
+
+	for _, source := range sources {
+		info, err := os.Stat(source)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var keys map[string][]byte
+
+		if info.IsDir() {
+			keys, err = volumeKeys(source)
+		} else {
+			var data []byte
+
+			data, err = os.ReadFile(source)
+
+			if err == nil {
+				keys, err = kubernetesKeys(data)
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+
+		for key, value := range keys {
+			algorithm, ok := algorithms[key]
+
+			if !ok || algorithm == "" {
+				algorithm = opts.hash.AlgorithmFor(key)
+			}
+
+			digest, size, err := opts.hash.Digest(ctx, bytes.NewReader(value), algorithm)
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to calculate checksum for %s: %w", key, err)
+			}
+
+			checksums = append(checksums, FileChecksum{
+				Path:      key,
+				Checksum:  opts.hash.Encode(algorithm, digest),
+				Algorithm: algorithm,
+				Size:      size,
+			})
+		}
+	}
+
+	sort.Slice(checksums, func(i, j int) bool {
+		return checksums[i].Path < checksums[j].Path
+	})
+
+	return checksums, nil
+}
+
+func runScanKubernetes(ctx context.Context, cfg config, checksumsFilePath string, opts scanOptions) bool {
+	if len(cfg.args) == 0 {
+		fmt.Println("Error scanning Kubernetes objects: scan-k8s expects rendered manifests or mounted volume directories")
+
+		return false
+	}
+
+	var expected []FileChecksum
+
+	algorithms := make(map[string]string)
+
+	if cfg.verify {
+		loaded, err := loadExpected(cfg, checksumsFilePath, opts.hash)
+
+		if err != nil {
+			fmt.Println("Error loading checksums:", err)
+
+			return false
+		}
+
+		expected = loaded
+
+		for _, entry := range expected {
+			algorithms[entry.Path] = entry.Algorithm
+		}
+	}
+
+	checksums, err := hashKubernetesKeys(ctx, cfg.args, opts, algorithms)
+
+	if err != nil {
+		fmt.Println("Error calculating checksums:", err)
+
+		return false
+	}
+
+	if cfg.verify {
+		return reportVerification(ctx, cfg, "kubernetes", compareChecksums(expected, checksums), len(expected))
+	}
+
+	if err := saveChecksums(cfg, checksums, checksumsFilePath); err != nil {
+		fmt.Println("Error saving checksums:", err)
+
+		return false
+	}
+
+	saveRunSummary(cfg, generationSummary(cfg, checksums, nil))
+
+	fmt.Printf("Saved checksums of %d keys\n", len(checksums))
+
+	return true
+}
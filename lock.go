@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockPollInterval is how often a run waiting for the lock tries it again.
+const lockPollInterval = 100 * time.Millisecond
+
+var errLocked = errors.New("another run holds the lock")
+
+type fileLock struct {
+	file *os.File
+}
+
+func lockPath(outputFile string) string {
+	return outputFile + ".lock"
+}
+
+// acquireLock takes the lock at path. With wait it tries again until the lock
+// is free or ctx is done, so -timeout also bounds the wait; a blocking lock
+// could not be given up.
+func acquireLock(ctx context.Context, path string, wait bool) (*fileLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		err := lockFile(file, false)
+
+		if err == nil {
+			return &fileLock{file: file}, nil
+		}
+
+		if !wait || !errors.Is(err, errLocked) {
+			file.Close()
+
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+
+			return nil, fmt.Errorf("%w, gave up waiting on %s", errLocked, interruptReason(ctx))
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+func (l *fileLock) release() error {
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+
+		return err
+	}
+
+	return l.file.Close()
+}
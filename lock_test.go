@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireLockWaitsUntilTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checksums.json.lock")
+
+	held, err := acquireLock(context.Background(), path, false)
+
+	if err != nil {
+		t.Fatalf("acquireLock() error = %v", err)
+	}
+
+	if _, err := acquireLock(context.Background(), path, false); !errors.Is(err, errLocked) {
+		t.Fatalf("acquireLock() without waiting error = %v, want errLocked", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockPollInterval)
+	defer cancel()
+
+	started := time.Now()
+
+	if _, err := acquireLock(ctx, path, true); !errors.Is(err, errLocked) {
+		t.Fatalf("acquireLock() waiting past the timeout error = %v, want errLocked", err)
+	}
+
+	if waited := time.Since(started); waited > time.Second {
+		t.Errorf("acquireLock() waited %v, want it to give up at the timeout", waited)
+	}
+
+	go func() {
+		time.Sleep(2 * lockPollInterval)
+		held.release()
+	}()
+
+	lock, err := acquireLock(context.Background(), path, true)
+
+	if err != nil {
+		t.Fatalf("acquireLock() waiting for the release error = %v", err)
+	}
+
+	lock.release()
+}
@@ -1,150 +1,1419 @@
 package main
 
 import (
-	"crypto/sha1"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
 )
 
-type FileChecksum struct {
-	Path     string `json:"path"`
-	Checksum string `json:"checksum"`
-}
+type FileChecksum = checksum.Entry
+
+type scanOptions struct {
+	ignorePatterns   []string
+	excludedFiles    []string
+	excludedGlobs    []string
+	presenceOnly     []string
+	tags             []tagRule
+	pathKey          []byte
+	hash             checksum.Options
+	checkpoint       *checkpoint
+	fileHook         []string
+	metadata         metadataFilter
+	hardLinks        *hardLinks
+	device           *deviceFilter
+	quickCheck       bool
+	scope            string
+	sampled          map[string]bool
+	verifyOrder      string
+	failFast         bool
+	emptyDirs        bool
+	recordType       bool
+	forensic         bool
+	contentType      bool
+	detectEncoding   bool
+	similarity       bool
+	entropy          bool
+	entropyThreshold float64
+	scanSecrets      bool
+	severityRules    []severityRule
+	allowEmpty       []string
+	knownGood        map[string]string
+	coverageCheck    bool
+	manifestTime     time.Time
+	walkers          int
+	readPath         string
+	canonicalize     string
+	sidecars         []string
+	spool            *entrySpool
+}
+
+func main() {
+	cfg := parseFlags()
+
+	stopProfiling, err := startProfiling(cfg)
+
+	if err != nil {
+		fmt.Println("Error starting profiling:", err)
+		os.Exit(2)
+	}
+
+	defer stopProfiling()
+
+	// exit flushes the profiles, which deferred calls would skip.
+	exit := func(code int) {
+		stopProfiling()
+		os.Exit(code)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	ignorePatterns := make([]string, 0)
+
+	if cfg.ignorePaths != "" {
+		ignorePatterns = strings.Split(cfg.ignorePaths, ",")
+	}
+
+	var key []byte
+
+	if cfg.keyFile != "" {
+		data, err := readSecret(ctx, cfg.keyFile)
+
+		if err != nil {
+			fmt.Println("Error reading key file:", err)
+			exit(2)
+		}
+
+		key = data
+	}
+
+	var pathKey []byte
+
+	if cfg.hashPaths && cfg.splitOutput {
+		fmt.Println("Error configuring path hashing: -hash-paths cannot be combined with -split-output")
+		exit(2)
+	}
+
+	if cfg.hashPaths {
+		if cfg.pathKeyFile == "" {
+			fmt.Println("Error configuring path hashing: -hash-paths requires -path-key-file")
+			exit(2)
+		}
+
+		data, err := readSecret(ctx, cfg.pathKeyFile)
+
+		if err != nil {
+			fmt.Println("Error reading path key file:", err)
+			exit(2)
+		}
+
+		pathKey = data
+	}
+
+	overrides, err := checksum.ParseAlgorithmOverrides(cfg.algorithmRules)
+
+	if err != nil {
+		fmt.Println("Error parsing algorithm overrides:", err)
+		exit(2)
+	}
+
+	tagRules, err := parseTagRules(cfg.tagRules)
+
+	if err != nil {
+		fmt.Println("Error parsing tag rules:", err)
+		exit(2)
+	}
+
+	cfg.severityRules, err = parseTagPolicies(cfg.tagPolicyRules)
+
+	if err != nil {
+		fmt.Println("Error parsing tag policies:", err)
+		exit(2)
+	}
+
+	if cfg.policyFile != "" {
+		rules, err := loadSeverityPolicy(cfg.policyFile)
+
+		if err != nil {
+			fmt.Println("Error loading policy file:", err)
+			exit(2)
+		}
+
+		cfg.severityRules = append(cfg.severityRules, rules...)
+	}
+
+	cfg.knownGood, err = loadKnownGood(cfg.knownGoodFiles)
+
+	if err != nil {
+		fmt.Println("Error loading known-good hash sets:", err)
+		exit(2)
+	}
+
+	if cfg.knownGoodFilter {
+		if cfg.knownGood == nil {
+			fmt.Println("Error loading known-good hash sets: -known-good-filter requires -known-good")
+			exit(2)
+		}
+
+		cfg.severityRules = append([]severityRule{knownGoodRule()}, cfg.severityRules...)
+	}
+
+	if cfg.failOnEmpty {
+		cfg.severityRules = append([]severityRule{failOnEmptyRule()}, cfg.severityRules...)
+	}
+
+	if fipsBuild && !cfg.fips {
+		fmt.Println("Error configuring FIPS mode: this binary was built with the fips tag and cannot disable -fips")
+		exit(2)
+	}
+
+	if cfg.fips {
+		if !isFlagSet("algo") {
+			cfg.algorithm = "sha256"
+		}
+
+		cfg.header.Compliance = "fips"
+	}
+
+	if cfg.format == "sri" {
+		if !isFlagSet("algo") {
+			cfg.algorithm = "sha384"
+		}
+
+		if isFlagSet("encoding") && cfg.encoding != "base64" {
+			fmt.Printf("Error configuring output format: sri digests are base64 encoded, got -encoding %s\n", cfg.encoding)
+			exit(2)
+		}
+
+		cfg.encoding = "base64"
+	}
+
+	hashOpts := checksum.Options{
+		Algorithm:   cfg.algorithm,
+		Key:         key,
+		Overrides:   overrides,
+		DigestBytes: cfg.digestBytes,
+		Encoding:    cfg.encoding,
+		CIDChunker:  cfg.cidChunker,
+		FIPS:        cfg.fips,
+	}
+
+	if err := hashOpts.Validate(); err != nil {
+		fmt.Println("Error configuring hashing:", err)
+		exit(2)
+	}
+
+	if err := validateStorageProfile(cfg.storageProfile); err != nil {
+		fmt.Println("Error configuring hashing:", err)
+		exit(2)
+	}
+
+	if err := validateReadPath(cfg.readPath); err != nil {
+		fmt.Println("Error configuring hashing:", err)
+		exit(2)
+	}
+
+	if err := validateCanonicalize(cfg.canonicalize); err != nil {
+		fmt.Println("Error configuring hashing:", err)
+		exit(2)
+	}
+
+	if cfg.canonicalize != "" && (cfg.format != "json" || cfg.sidecar) {
+		fmt.Println("Error validating flags: -canonicalize digests do not match the bytes on disk, so they are only written to json manifests and cannot be combined with -sidecar")
+		exit(2)
+	}
+
+	if err := validateFormat(cfg.format, hashOpts); err != nil {
+		fmt.Println("Error configuring output format:", err)
+		exit(2)
+	}
+
+	if cfg.maxMemory != "" && (cfg.format != "json" || cfg.splitOutput || cfg.maxEntries > 0 || cfg.hardLinks || len(cfg.sinks) > 0 || cfg.onComplete != "" || cfg.verify || cfg.baselineBranch != "" || cfg.command != "") {
+		fmt.Println("Error configuring memory limit: -max-memory streams a single json manifest and cannot be combined with verification, splitting, hard links, sinks or -on-complete")
+		exit(2)
+	}
+
+	if cfg.explain && cfg.format != "json" {
+		fmt.Println("Error configuring output format: -explain embeds the explanation in the manifest header and requires -format json")
+		exit(2)
+	}
+
+	if cfg.digestPrefix && cfg.format != "json" {
+		fmt.Println("Error configuring output format: -digest-prefix requires -format json")
+		exit(2)
+	}
+
+	if (cfg.emptyDirs || cfg.recordType || cfg.forensic || cfg.tombstones || cfg.contentType || cfg.detectEncoding || cfg.similarity || cfg.entropy || cfg.scanSecrets || cfg.iocBlocklist != "" || cfg.iocURL != "" || len(cfg.enrichers) > 0 || (len(cfg.knownGoodFiles) > 0 && !cfg.knownGoodFilter)) && cfg.format != "json" {
+		fmt.Println("Error configuring output format: -empty-dirs, -record-type, -forensic, -tombstones, -content-type, -detect-encoding, -similarity, -entropy, -scan-secrets, -ioc-blocklist, -ioc-url, -enrich and -known-good without -known-good-filter require -format json, the only format recording them")
+		exit(2)
+	}
+
+	cfg.header.DigestPrefix = cfg.digestPrefix
+
+	if cfg.format == "gosrc" {
+		if cfg.splitOutput || cfg.maxEntries > 0 || cfg.verify || cfg.baselineBranch != "" || (cfg.command != "" && cfg.command != "convert") {
+			fmt.Println("Error configuring output format: gosrc manifests cannot be read back or paginated, use json for verification")
+			exit(2)
+		}
+
+		if err := validateGoPackage(cfg.goPackage); err != nil {
+			fmt.Println("Error configuring output format:", err)
+			exit(2)
+		}
+	}
+
+	if cfg.sidecar {
+		if cfg.hashPaths || cfg.maxMemory != "" {
+			fmt.Println("Error configuring sidecar files: -sidecar needs every entry and its literal path, and cannot be combined with -hash-paths or -max-memory")
+			exit(2)
+		}
+
+		if err := validateSidecarOptions(cfg.sidecarFormat, hashOpts); err != nil {
+			fmt.Println("Error configuring sidecar files:", err)
+			exit(2)
+		}
+	}
+
+	if cfg.rekor {
+		if cfg.rekorKey == "" || cfg.maxMemory != "" {
+			fmt.Println("Error configuring Rekor: -rekor requires -rekor-key and cannot be combined with -max-memory")
+			exit(2)
+		}
+
+		signer, err := resolveSigner(ctx, cfg.rekorKey)
+
+		if err != nil {
+			fmt.Println("Error configuring Rekor:", err)
+			exit(2)
+		}
+
+		cfg.rekorSigner = signer
+	}
+
+	if err := validateSignatureOptions(cfg); err != nil {
+		fmt.Println("Error configuring manifest signing:", err)
+		exit(2)
+	}
+
+	if cfg.requireSignature {
+		if cfg.publicKey == "" || cfg.splitOutput || cfg.maxEntries > 0 {
+			fmt.Println("Error configuring signature checks: -require-signature requires -pubkey and a single manifest file, without -split-output or -max-entries")
+			exit(2)
+		}
+
+		verifier, err := resolveManifestVerifier(ctx, cfg.publicKey, cfg.signatureFormat)
+
+		if err != nil {
+			fmt.Println("Error configuring signature checks:", err)
+			exit(2)
+		}
+
+		cfg.manifestVerifier = verifier
+	}
+
+	if cfg.signKMS != "" || cfg.signKey != "" {
+		signer, err := resolveManifestSigner(ctx, cfg)
+
+		if err != nil {
+			fmt.Println("Error configuring manifest signing:", err)
+			exit(2)
+		}
+
+		cfg.manifestSigner = signer
+	}
+
+	for _, name := range cfg.sinks {
+		if _, err := resolveSink(cfg, name); err != nil {
+			fmt.Println("Error configuring sinks:", err)
+			exit(2)
+		}
+	}
+
+	if err := validateNotifyFormat(cfg.notifyFormat); err != nil {
+		fmt.Println("Error configuring notifications:", err)
+		exit(2)
+	}
+
+	if cfg.syslog != "" {
+		if _, _, err := parseSyslogAddress(cfg.syslog); err != nil {
+			fmt.Println("Error configuring notifications:", err)
+			exit(2)
+		}
+
+		if _, err := parseSyslogFacility(cfg.syslogFacility); err != nil {
+			fmt.Println("Error configuring notifications:", err)
+			exit(2)
+		}
+	}
+
+	if cfg.githubToken == "" {
+		cfg.githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+
+	if err := cfg.email().validate(); err != nil {
+		fmt.Println("Error configuring email:", err)
+		exit(2)
+	}
+
+	metadata, err := parseMetadataFilter(cfg.ownedBy, cfg.skipWorldWritable)
+
+	if err != nil {
+		fmt.Println("Error configuring file filters:", err)
+		exit(2)
+	}
+
+	projectDir, err := filepath.Abs(cfg.rootDir)
+
+	if err != nil {
+		fmt.Println("Error generating project dir:", err)
+		exit(2)
+	}
+
+	switch cfg.command {
+	case "", "verify-file", "verify-restore", "compare-remote", "bench", "test-ignore", "fim":
+		if err := validateRootDir(projectDir); err != nil {
+			fmt.Println("Error validating flags:", err)
+			exit(2)
+		}
+	}
+
+	if err := validateIgnorePatterns(ignorePatterns); err != nil {
+		fmt.Println("Error validating flags:", err)
+		exit(2)
+	}
+
+	if err := validatePresencePatterns(cfg.presenceOnly); err != nil {
+		fmt.Println("Error validating flags:", err)
+		exit(2)
+	}
+
+	if err := validateAllowEmptyPatterns(cfg.allowEmpty); err != nil {
+		fmt.Println("Error validating flags:", err)
+		exit(2)
+	}
+
+	scope, err := parseScope(cfg.scope)
+
+	if err != nil {
+		fmt.Println("Error validating flags:", err)
+		exit(2)
+	}
+
+	if scope != "" && (!cfg.verify || cfg.hashPaths) {
+		fmt.Println("Error validating flags: -scope requires -verify and cannot be combined with -hash-paths")
+		exit(2)
+	}
+
+	if err := validateVerifyOrder(cfg.verifyOrder); err != nil {
+		fmt.Println("Error validating flags:", err)
+		exit(2)
+	}
+
+	cfg.expect, err = parseExpectations(cfg)
+
+	if err != nil {
+		fmt.Println("Error validating flags:", err)
+		exit(2)
+	}
+
+	if cfg.failFast && (!cfg.verify || cfg.regoPolicy != "") {
+		fmt.Println("Error validating flags: -fail-fast requires -verify and cannot be combined with -rego-policy")
+		exit(2)
+	}
+
+	if (cfg.iocBlocklist != "" || cfg.iocURL != "") && (cfg.verify || cfg.maxMemory != "") {
+		fmt.Println("Error validating flags: -ioc-blocklist and -ioc-url sweep generated manifests and cannot be combined with -verify or -max-memory")
+		exit(2)
+	}
+
+	if cfg.failOnIOC && cfg.iocBlocklist == "" && cfg.iocURL == "" {
+		fmt.Println("Error validating flags: -fail-on-ioc requires -ioc-blocklist or -ioc-url")
+		exit(2)
+	}
+
+	if len(cfg.enrichers) > 0 && (cfg.verify || cfg.maxMemory != "") {
+		fmt.Println("Error validating flags: -enrich records findings in generated manifests and cannot be combined with -verify or -max-memory")
+		exit(2)
+	}
+
+	if cfg.tombstones && (cfg.verify || cfg.maxMemory != "" || cfg.baselineBranch != "") {
+		fmt.Println("Error validating flags: -tombstones reads the previous manifest at the output file and cannot be combined with -verify, -max-memory or -baseline-branch")
+		exit(2)
+	}
+
+	if len(cfg.knownGoodFiles) > 0 && cfg.maxMemory != "" {
+		fmt.Println("Error validating flags: -known-good cannot be combined with -max-memory")
+		exit(2)
+	}
+
+	cfg.sampling, err = parseSample(cfg.sample)
+
+	if err != nil {
+		fmt.Println("Error validating flags:", err)
+		exit(2)
+	}
+
+	if cfg.sampling.enabled() && !cfg.verify && cfg.command != "verify-restore" {
+		fmt.Println("Error validating flags: -sample requires -verify or the verify-restore command")
+		exit(2)
+	}
+
+	cfg.par2Redundancy, err = parsePAR2Redundancy(cfg.par2)
+
+	if err != nil {
+		fmt.Println("Error validating flags:", err)
+		exit(2)
+	}
+
+	if cfg.par2Redundancy > 0 && (cfg.hashPaths || cfg.maxMemory != "") {
+		fmt.Println("Error validating flags: -par2 needs every entry and its literal path, and cannot be combined with -hash-paths or -max-memory")
+		exit(2)
+	}
+
+	cfg.scrubAge, err = parseScrubAge(cfg.scrubOlderThan)
+
+	if err != nil {
+		fmt.Println("Error validating flags:", err)
+		exit(2)
+	}
+
+	if cfg.scrubAge > 0 && (!cfg.verify || cfg.command != "") {
+		fmt.Println("Error validating flags: -scrub-older-than requires -verify")
+		exit(2)
+	}
+
+	if cfg.walkers <= 0 {
+		cfg.walkers = profileWalkers(resolveStorageProfile(cfg.storageProfile, projectDir))
+	}
+
+	checksumsFilePath, err := cfg.outputPath(projectDir)
+
+	if err != nil {
+		fmt.Println("Error resolving output file:", err)
+		exit(2)
+	}
+
+	if cfg.splitOutput {
+		checksumsFilePath = splitOutputDir(checksumsFilePath)
+	}
+
+	switch cfg.command {
+	case "", "scan-container", "scan-k8s":
+		cfg.summaryFile = summaryPath(checksumsFilePath)
+		cfg.scrubStateFile = scrubStatePath(checksumsFilePath)
+
+		if cfg.createOutputDir && !cfg.verify {
+			if err := os.MkdirAll(filepath.Dir(checksumsFilePath), 0755); err != nil {
+				fmt.Println("Error creating output directory:", err)
+				exit(1)
+			}
+		}
+
+		if err := validateOutputDir(checksumsFilePath); err != nil {
+			fmt.Println("Error validating flags:", err)
+			exit(2)
+		}
+	}
+
+	if cfg.par2Dir == "" {
+		cfg.par2Dir = recoveryDir(checksumsFilePath)
+	} else if cfg.par2Dir, err = filepath.Abs(cfg.par2Dir); err != nil {
+		fmt.Println("Error resolving recovery directory:", err)
+		exit(2)
+	}
+
+	if cfg.fimDatabase == "" {
+		cfg.fimDatabase = fimDatabasePath(checksumsFilePath)
+	} else if cfg.fimDatabase, err = filepath.Abs(cfg.fimDatabase); err != nil {
+		fmt.Println("Error resolving fim database:", err)
+		exit(2)
+	}
+
+	excludedFiles := []string{cfg.par2Dir, checksumsFilePath, checkpointPath(checksumsFilePath), lockPath(checksumsFilePath), summaryPath(checksumsFilePath), scrubStatePath(checksumsFilePath), signaturePath(checksumsFilePath, "cosign"), signaturePath(checksumsFilePath, "minisign")}
+	excludedFiles = append(excludedFiles, fimDatabaseFiles(cfg.fimDatabase)...)
+
+	if cfg.reportFile != "" {
+		reportFilePath, err := filepath.Abs(cfg.reportFile)
+
+		if err != nil {
+			fmt.Println("Error resolving report file:", err)
+			exit(2)
+		}
+
+		excludedFiles = append(excludedFiles, reportFilePath)
+	}
+
+	if cfg.auditLog != "" {
+		auditLogPath, err := filepath.Abs(cfg.auditLog)
+
+		if err != nil {
+			fmt.Println("Error resolving audit log:", err)
+			exit(2)
+		}
+
+		excludedFiles = append(excludedFiles, auditLogPath)
+	}
+
+	if cfg.quarantineDir != "" {
+		quarantinePath, err := filepath.Abs(cfg.quarantineDir)
+
+		if err != nil {
+			fmt.Println("Error resolving quarantine directory:", err)
+			exit(2)
+		}
+
+		excludedFiles = append(excludedFiles, quarantinePath)
+	}
+
+	if cfg.maxEntries > 0 {
+		excludedFiles = append(excludedFiles, writtenPages(checksumsFilePath)...)
+	}
+
+	var excludedGlobs []string
+
+	for _, excluded := range excludedFiles {
+		excludedGlobs = append(excludedGlobs, atomicTempPattern(excluded))
+	}
+
+	opts := scanOptions{
+		ignorePatterns:   ignorePatterns,
+		excludedFiles:    excludedFiles,
+		excludedGlobs:    excludedGlobs,
+		presenceOnly:     cfg.presenceOnly,
+		tags:             tagRules,
+		pathKey:          pathKey,
+		hash:             hashOpts,
+		fileHook:         strings.Fields(cfg.onFile),
+		metadata:         metadata,
+		quickCheck:       cfg.quickCheck,
+		scope:            scope,
+		verifyOrder:      cfg.verifyOrder,
+		failFast:         cfg.failFast,
+		emptyDirs:        cfg.emptyDirs,
+		recordType:       cfg.recordType,
+		forensic:         cfg.forensic,
+		contentType:      cfg.contentType,
+		detectEncoding:   cfg.detectEncoding,
+		similarity:       cfg.similarity,
+		entropy:          cfg.entropy,
+		entropyThreshold: cfg.entropyThreshold,
+		scanSecrets:      cfg.scanSecrets,
+		severityRules:    cfg.severityRules,
+		allowEmpty:       cfg.allowEmpty,
+		knownGood:        cfg.knownGood,
+		coverageCheck:    cfg.coverageCheck,
+		walkers:          cfg.walkers,
+		readPath:         cfg.readPath,
+		canonicalize:     cfg.canonicalize,
+	}
+
+	if cfg.sidecar {
+		opts.sidecars = sidecarExtensions(cfg.sidecarExtension, hashOpts)
+	}
+
+	if cfg.oneFileSystem {
+		opts.device, err = newDeviceFilter(projectDir)
+
+		if err != nil {
+			fmt.Println("Error configuring file filters:", err)
+			exit(2)
+		}
+	}
+
+	switch cfg.command {
+	case "verify-file":
+		if !runVerifyFile(ctx, cfg, projectDir, checksumsFilePath, opts) {
+			exit(1)
+		}
+
+		return
+	case "verify-restore":
+		if !runVerifyRestore(ctx, cfg, projectDir, checksumsFilePath, opts) {
+			exit(1)
+		}
+
+		return
+	case "query":
+		if !runQuery(cfg, checksumsFilePath, opts) {
+			exit(1)
+		}
+
+		return
+	case "compare-remote":
+		if !runCompareRemote(ctx, cfg, projectDir, opts) {
+			exit(1)
+		}
+
+		return
+	case "test-ignore":
+		if !runTestIgnore(cfg, projectDir, opts) {
+			exit(1)
+		}
+
+		return
+	case "release-checksums":
+		if !runReleaseChecksums(ctx, cfg, opts) {
+			exit(1)
+		}
+
+		return
+	case "bench":
+		if !runBench(ctx, cfg, projectDir, opts) {
+			exit(1)
+		}
+
+		return
+	case "convert":
+		if !runConvert(cfg, checksumsFilePath, opts) {
+			exit(1)
+		}
+
+		return
+	case "validate-manifest":
+		if !runValidateManifest(cfg, checksumsFilePath) {
+			exit(1)
+		}
+
+		return
+	case "install-service":
+		if !runInstallService(cfg) {
+			exit(1)
+		}
+
+		return
+	case "verify-audit-log":
+		if !runVerifyAuditLog(cfg) {
+			exit(1)
+		}
+
+		return
+	}
+
+	// Approving drift runs beside the fim daemon holding the lock, and SQLite
+	// keeps their writes apart.
+	if cfg.command == "fim" && cfg.baselineUpdate {
+		if !runFIMApproval(ctx, cfg, projectDir, opts) {
+			exit(1)
+		}
+
+		return
+	}
+
+	lock, err := acquireLock(ctx, lockPath(checksumsFilePath), cfg.waitLock)
+
+	if errors.Is(err, errLocked) || (err != nil && !cfg.verify) {
+		fmt.Println("Error acquiring lock:", err)
+		exit(1)
+	}
+
+	if lock != nil {
+		defer lock.release()
+	}
+
+	switch cfg.command {
+	case "scan-container":
+		if !runScanContainer(ctx, cfg, checksumsFilePath, opts) {
+			exit(1)
+		}
+
+		return
+	case "scan-k8s":
+		if !runScanKubernetes(ctx, cfg, checksumsFilePath, opts) {
+			exit(1)
+		}
+
+		return
+	case "fim":
+		var passed bool
+
+		if isWindowsService() {
+			passed = runWindowsService(ctx, cfg.serviceName, func(ctx context.Context) bool {
+				return runFIM(ctx, cfg, projectDir, opts)
+			})
+		} else {
+			passed = runFIM(ctx, cfg, projectDir, opts)
+		}
+
+		if !passed {
+			exit(1)
+		}
 
-func main() {
-	rootDir := flag.String("dir", ".", "Root directory to calculate checksums")
-	outputFile := flag.String("output", "checksums.json", "Output file to save checksums")
-	ignorePaths := flag.String("ignore", "", "Comma-separated list of paths to ignore (relative to root)")
+		return
+	}
 
-	flag.Parse()
+	if cfg.baselineBranch != "" {
+		if !runBaseline(ctx, cfg, projectDir, opts) {
+			exit(1)
+		}
 
-	ignorePatterns := make([]string, 0)
+		return
+	}
+
+	if cfg.verify {
+		expected, err := loadExpected(cfg, checksumsFilePath, opts.hash)
+
+		if err != nil {
+			// A missing, untrusted or non-compliant manifest must fail the
+			// job rather than skip verification.
+			fmt.Println("Error loading checksums:", err)
+			exit(1)
+		}
+
+		expected = scopeEntries(expected, opts.scope)
+
+		if err := cfg.expect.check(len(expected), totalBytes(expected)); err != nil {
+			fmt.Println("Error checking expectations:", err)
+			exit(1)
+		}
+
+		if info, err := os.Stat(checksumsFilePath); err == nil {
+			opts.manifestTime = info.ModTime()
+		}
+
+		if !runVerify(ctx, cfg, projectDir, expected, opts) {
+			exit(1)
+		}
+
+		return
+	}
+
+	if cfg.hardLinks {
+		opts.hardLinks = newHardLinks()
+	}
+
+	if cfg.resume {
+		opts.checkpoint, err = openCheckpoint(checkpointPath(checksumsFilePath), checkpointOptionsFor(opts))
+
+		if err != nil {
+			fmt.Println("Error opening checkpoint:", err)
+			exit(1)
+		}
+
+		if opts.checkpoint.discarded {
+			fmt.Println("Discarding checkpoint recorded with other hashing options")
+		}
 
-	if *ignorePaths != "" {
-		ignorePatterns = strings.Split(*ignorePaths, ",")
+		if resumed := len(opts.checkpoint.done); resumed > 0 {
+			fmt.Printf("Resuming from checkpoint with %d entries\n", resumed)
+		}
+	}
+
+	if cfg.explain {
+		cfg.header.Explain, err = explainRun(cfg, projectDir, opts)
+
+		if err != nil {
+			fmt.Println("Error explaining run:", err)
+
+			return
+		}
+	}
+
+	if cfg.maxMemory != "" {
+		limit, err := parseSize(cfg.maxMemory)
+
+		if err != nil {
+			fmt.Println("Error configuring memory limit:", err)
+			exit(2)
+		}
+
+		opts.spool = newEntrySpool(limit)
+		defer opts.spool.close()
 	}
 
-	projectDir, err := filepath.Abs(*rootDir)
+	checksums, err := calculateChecksums(ctx, projectDir, opts)
 
 	if err != nil {
-		fmt.Println("Error generating project dir:", err)
+		if opts.checkpoint != nil {
+			opts.checkpoint.close()
+		}
+
+		if ctx.Err() != nil {
+			hashed := len(checksums)
+
+			if opts.spool != nil {
+				hashed += opts.spool.files
+			}
+
+			fmt.Printf("Interrupted (%s) after hashing %d files, no output written\n", interruptReason(ctx), hashed)
+
+			if opts.checkpoint != nil {
+				fmt.Println("Progress saved, rerun with -resume to continue")
+			}
+
+			summary := generationSummary(cfg, checksums, []string{err.Error()})
+			opts.spool.addTo(&summary)
+			saveRunSummary(cfg, summary)
+			opts.spool.close()
+			stop()
+			exit(1)
+		}
+
+		fmt.Println("Error calculating checksums:", err)
+
+		summary := generationSummary(cfg, checksums, []string{err.Error()})
+		opts.spool.addTo(&summary)
+		saveRunSummary(cfg, summary)
 
 		return
 	}
 
-	checksums, err := calculateChecksums(projectDir, ignorePatterns)
+	files, bytes := len(checksums), totalBytes(checksums)
+
+	if opts.spool != nil {
+		files += opts.spool.files
+		bytes += opts.spool.bytes
+	}
+
+	if err := cfg.expect.check(files, bytes); err != nil {
+		fmt.Printf("Error checking expectations: %v, no output written\n", err)
+
+		summary := generationSummary(cfg, checksums, []string{err.Error()})
+		opts.spool.addTo(&summary)
+		saveRunSummary(cfg, summary)
+		opts.spool.close()
+		exit(1)
+	}
+
+	empty, err := zeroByteFiles(opts.spool, checksums)
 
 	if err != nil {
-		fmt.Println("Error calculating checksums:", err)
+		fmt.Println("Error listing zero-byte files:", err)
 
 		return
 	}
 
-	checksumsFilePath := filepath.Join(projectDir, *outputFile)
+	if len(empty) > 0 {
+		fmt.Printf("%d zero-byte files: %s\n", len(empty), listEmpty(empty))
+	}
+
+	if unexpected := unexpectedEmpty(cfg.allowEmpty, empty); cfg.failOnEmpty && len(unexpected) > 0 {
+		err := fmt.Errorf("%d zero-byte files are not allowed by -allow-empty: %s", len(unexpected), listEmpty(unexpected))
+
+		fmt.Printf("Error checking empty files: %v, no output written\n", err)
+
+		summary := generationSummary(cfg, checksums, []string{err.Error()})
+		opts.spool.addTo(&summary)
+		saveRunSummary(cfg, summary)
+		opts.spool.close()
+		exit(1)
+	}
+
+	if cfg.knownGood != nil {
+		var matched int
+
+		checksums, matched = filterKnownGood(cfg, checksums)
+
+		if cfg.knownGoodFilter {
+			fmt.Printf("Left out %d files matching known-good hash sets\n", matched)
+		} else {
+			fmt.Printf("%d files match known-good hash sets\n", matched)
+		}
+	}
+
+	if cfg.iocBlocklist != "" || cfg.iocURL != "" {
+		matches, err := sweepIOCs(ctx, cfg, checksums)
+
+		if err != nil {
+			fmt.Println("Error sweeping for indicators of compromise:", err)
+			exit(1)
+		}
+
+		fmt.Printf("%d files match known indicators of compromise\n", matches)
+
+		if cfg.failOnIOC && matches > 0 {
+			err := fmt.Errorf("%d files match known indicators of compromise", matches)
+
+			fmt.Printf("Error checking indicators of compromise: %v, no output written\n", err)
+
+			saveRunSummary(cfg, generationSummary(cfg, checksums, []string{err.Error()}))
+			exit(1)
+		}
+	}
+
+	if len(cfg.enrichers) > 0 {
+		enriched, err := enrichEntries(ctx, cfg, checksums)
+
+		if err != nil {
+			fmt.Println("Error enriching checksums:", err)
+			exit(1)
+		}
+
+		fmt.Printf("Enriched %d files\n", enriched)
+	}
+
+	entries := checksums
+
+	if cfg.tombstones {
+		var deleted int
+
+		entries, deleted, err = carryTombstones(cfg, projectDir, checksums, checksumsFilePath, opts)
+
+		if err != nil {
+			fmt.Println("Error carrying tombstones:", err)
+			exit(1)
+		}
+
+		fmt.Printf("Recorded %d deleted files as tombstones\n", deleted)
+	}
 
-	err = saveToFile(checksums, checksumsFilePath)
+	if opts.spool != nil {
+		err = opts.spool.save(checksums, checksumsFilePath, cfg.header)
+	} else {
+		err = saveChecksums(cfg, entries, checksumsFilePath)
+	}
 
 	if err != nil {
 		fmt.Println("Error saving checksums:", err)
+
+		return
+	}
+
+	if cfg.manifestSigner != nil {
+		if err := signManifest(cfg.manifestSigner, checksumsFilePath, cfg.signatureFormat); err != nil {
+			fmt.Println("Error signing manifest:", err)
+			opts.spool.close()
+			exit(1)
+		}
+
+		fmt.Println("Signed manifest into", signaturePath(checksumsFilePath, cfg.signatureFormat))
+	}
+
+	if cfg.sidecar {
+		written, err := writeSidecars(cfg, projectDir, checksums)
+
+		if err != nil {
+			fmt.Println("Error writing sidecar files:", err)
+			exit(1)
+		}
+
+		fmt.Printf("Wrote %d sidecar files\n", written)
+	}
+
+	if cfg.par2Redundancy > 0 {
+		written, err := writeRecoveryFiles(ctx, cfg, projectDir, checksums)
+
+		if err != nil {
+			fmt.Println("Error writing recovery data:", err)
+			exit(1)
+		}
+
+		fmt.Printf("Wrote recovery data for %d files into %s\n", written, cfg.par2Dir)
+	}
+
+	if opts.entropy {
+		printHighEntropy(opts, checksums)
+	}
+
+	if opts.scanSecrets {
+		printSecrets(checksums)
+	}
+
+	var runErrors []string
+
+	if err := sendToSinks(ctx, cfg, checksums); err != nil {
+		fmt.Println("Error sending checksums:", err)
+		runErrors = append(runErrors, err.Error())
+	}
+
+	if err := runCompleteHook(ctx, cfg, checksums); err != nil {
+		fmt.Println("Error running completion hook:", err)
+		runErrors = append(runErrors, err.Error())
+	}
+
+	if cfg.rekor {
+		if err := publishToRekor(ctx, cfg, aggregateDigest(checksums)); err != nil {
+			fmt.Println("Error recording in Rekor:", err)
+			runErrors = append(runErrors, err.Error())
+		}
+	}
+
+	summary := generationSummary(cfg, checksums, runErrors)
+	summary.EmptyFiles = len(empty)
+	opts.spool.addTo(&summary)
+
+	defer func() {
+		saveRunSummary(cfg, summary)
+	}()
+
+	if opts.checkpoint != nil {
+		if err := opts.checkpoint.remove(); err != nil {
+			fmt.Println("Error removing checkpoint:", err)
+		}
+	}
+
+	if cfg.treeDigest != "" {
+		digest, err := calculateTreeDigest(ctx, projectDir, opts, cfg.treeDigest, cfg.dirhashPrefix)
+
+		if err != nil {
+			fmt.Println("Error calculating tree digest:", err)
+			summary.Errors = append(summary.Errors, err.Error())
+
+			return
+		}
+
+		summary.TreeDigest = digest
+
+		fmt.Printf("Tree digest (%s): %s\n", cfg.treeDigest, digest)
+
+		if err := setActionOutput("tree-digest", digest); err != nil {
+			fmt.Println("Error setting action output:", err)
+		}
 	}
 }
 
-func generateSHA1Checksum(filePath string) (string, error) {
-	data, err := os.ReadFile(filePath)
+func saveChecksums(cfg config, checksums []FileChecksum, checksumsFilePath string) error {
+	switch {
+	case cfg.format == "gosrc":
+		return saveGoSource(checksums, checksumsFilePath, cfg.goPackage)
+	case cfg.splitOutput:
+		return saveSplit(checksums, checksumsFilePath, cfg.format, cfg.maxEntries, cfg.header)
+	}
 
-	if err != nil {
-		return "", err
+	return saveManifest(checksums, checksumsFilePath, cfg.format, cfg.maxEntries, cfg.header)
+}
+
+// pruneRule decides whether the walk leaves out the directory at the
+// slash-separated relativePath with everything below it. Like skipRule, it
+// returns the responsible rule formatted like git check-ignore -v as
+// source:pattern, or an empty string when the directory is walked.
+func (o scanOptions) pruneRule(relativePath string, d fs.DirEntry) string {
+	for _, pattern := range o.ignorePatterns {
+		if strings.HasPrefix(relativePath, pattern) {
+			return "-ignore:" + pattern
+		}
+	}
+
+	if o.device != nil && !o.device.contains(d) {
+		return "-one-file-system:" + relativePath
+	}
+
+	return ""
+}
+
+// skipRule decides whether the walk leaves out the file, or the empty
+// directory with -empty-dirs, at the slash-separated relativePath. walkTree
+// and test-ignore both ask it, so test-ignore explains exactly what the walk
+// does. It returns the responsible rule, or an empty string when the path is
+// recorded.
+func (o scanOptions) skipRule(rootDir string, relativePath string, d fs.DirEntry) string {
+	for _, pattern := range o.ignorePatterns {
+		if strings.HasPrefix(relativePath, pattern) {
+			return "-ignore:" + pattern
+		}
+	}
+
+	path := filepath.Join(rootDir, filepath.FromSlash(relativePath))
+
+	for _, excluded := range o.excludedFiles {
+		if isExcluded(path, []string{excluded}) {
+			return "default-exclude:" + excluded
+		}
+	}
+
+	for _, pattern := range o.excludedGlobs {
+		if matchesAny(path, []string{pattern}) {
+			return "default-exclude:" + pattern
+		}
+	}
+
+	for _, extension := range o.sidecars {
+		if strings.HasSuffix(relativePath, "."+extension) {
+			return "-sidecar:*." + extension
+		}
+	}
+
+	if !o.metadata.matches(d) {
+		if info, err := d.Info(); err == nil && o.metadata.skipWorldWritable && info.Mode().Perm()&0o002 != 0 {
+			return "-skip-world-writable"
+		}
+
+		return "-only-owned-by"
+	}
+
+	return ""
+}
+
+func walkFiles(ctx context.Context, rootDir string, opts scanOptions, fn func(path string, relativePath string) error) error {
+	return walkTree(ctx, rootDir, opts, fn, nil)
+}
+
+// walkTree walks like walkFiles and, with -empty-dirs, also calls emptyDir
+// with every empty directory.
+func walkTree(ctx context.Context, rootDir string, opts scanOptions, fn func(path string, relativePath string) error, emptyDir func(path string, relativePath string) error) error {
+	walkOpts := checksum.WalkOptions{
+		Skip: func(relativePath string, d fs.DirEntry) bool {
+			return opts.skipRule(rootDir, relativePath, d) != ""
+		},
+		SkipDir: func(relativePath string, d fs.DirEntry) bool {
+			return opts.pruneRule(relativePath, d) != ""
+		},
+		Root:        opts.scope,
+		Concurrency: opts.walkers,
+	}
+
+	if opts.emptyDirs && emptyDir != nil {
+		walkOpts.EmptyDir = func(relativePath string) error {
+			return emptyDir(filepath.Join(rootDir, filepath.FromSlash(relativePath)), filepath.FromSlash(relativePath))
+		}
 	}
 
-	hash := sha1.Sum(data)
+	err := checksum.Walk(ctx, os.DirFS(rootDir), walkOpts, func(relativePath string) error {
+		return fn(filepath.Join(rootDir, filepath.FromSlash(relativePath)), filepath.FromSlash(relativePath))
+	})
+
+	if err != nil {
+		return fmt.Errorf("error walking the directory: %w", err)
+	}
 
-	return hex.EncodeToString(hash[:]), nil
+	return nil
 }
 
-func calculateChecksums(rootDir string, ignorePatterns []string) ([]FileChecksum, error) {
+func calculateChecksums(ctx context.Context, rootDir string, opts scanOptions) ([]FileChecksum, error) {
 	var checksums []FileChecksum
 
-	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+	err := walkTree(ctx, rootDir, opts, func(path string, relativePath string) error {
+		if opts.spool != nil {
+			tail, err := opts.spool.track(checksums)
+
+			if err != nil {
+				return err
+			}
+
+			checksums = tail
+		}
+
+		fileType, err := opts.fileType(path)
+
 		if err != nil {
 			return err
 		}
 
-		ignored, err := isIgnored(path, ignorePatterns, rootDir)
+		times, err := opts.fileTimes(path)
 
 		if err != nil {
 			return err
 		}
 
-		if ignored {
-			if d.IsDir() {
-				return filepath.SkipDir
+		if opts.isPresenceOnly(relativePath) || isSpecialType(fileType) {
+			entry := FileChecksum{
+				Path:         opts.manifestPath(relativePath),
+				PresenceOnly: true,
+				Tags:         opts.tagsFor(relativePath),
+				Type:         fileType,
+				Times:        times,
+			}
+
+			if err := opts.runFileHook(ctx, entry); err != nil {
+				return err
 			}
 
+			checksums = append(checksums, entry)
+
 			return nil
 		}
 
-		if d.IsDir() {
-			return nil
+		var group *linkGroup
+
+		if opts.hardLinks != nil {
+			linked, err := opts.hardLinks.lookup(path)
+
+			if err != nil {
+				return err
+			}
+
+			group = linked
+
+			if group != nil && group.index >= 0 && checksums[group.index].Algorithm == opts.hash.AlgorithmFor(relativePath) {
+				entry := checksums[group.index]
+				entry.Path = opts.manifestPath(relativePath)
+				entry.LinkGroup = opts.hardLinks.join(group, checksums)
+				entry.Tags = opts.tagsFor(relativePath)
+				entry.Type = fileType
+				entry.Times = times
+
+				if err := opts.runFileHook(ctx, entry); err != nil {
+					return err
+				}
+
+				checksums = append(checksums, entry)
+
+				return nil
+			}
+		}
+
+		var info fs.FileInfo
+
+		if opts.checkpoint != nil || opts.quickCheck {
+			stat, err := os.Stat(path)
+
+			if err != nil {
+				return err
+			}
+
+			info = stat
+		}
+
+		if opts.checkpoint != nil {
+			if entry, ok := opts.checkpoint.lookup(opts.manifestPath(relativePath), info); ok {
+				if opts.quickCheck {
+					entry.ModTime = info.ModTime().UnixNano()
+				}
+
+				entry.Tags = opts.tagsFor(relativePath)
+				entry.Type = fileType
+				entry.Times = times
+
+				group.add(len(checksums))
+				checksums = append(checksums, entry)
+
+				return nil
+			}
 		}
 
-		checksum, err := generateSHA1Checksum(path)
+		algorithm := opts.hash.AlgorithmFor(relativePath)
+		inspectors := opts.newInspectors()
+
+		digest, size, err := opts.digestInspected(ctx, path, algorithm, inspectors)
 
 		if err != nil {
 			return fmt.Errorf("failed to calculate checksum for %s: %w", path, err)
 		}
 
-		relativePath, err := filepath.Rel(rootDir, path)
+		contentType, err := opts.detectContentType(path)
+
+		if err != nil {
+			return fmt.Errorf("failed to detect content type of %s: %w", path, err)
+		}
+
+		entry := FileChecksum{
+			Path:        opts.manifestPath(relativePath),
+			Checksum:    opts.hash.Encode(algorithm, digest),
+			Algorithm:   algorithm,
+			Size:        size,
+			Tags:        opts.tagsFor(relativePath),
+			Type:        fileType,
+			ContentType: contentType,
+			Times:       times,
+		}
+
+		if opts.quickCheck {
+			entry.ModTime = info.ModTime().UnixNano()
+		}
+
+		for _, inspector := range inspectors {
+			inspector.record(&entry)
+		}
+
+		if err := opts.runFileHook(ctx, entry); err != nil {
+			return err
+		}
+
+		if opts.checkpoint != nil {
+			if err := opts.checkpoint.record(entry, info); err != nil {
+				return fmt.Errorf("failed to write checkpoint: %w", err)
+			}
+		}
+
+		group.add(len(checksums))
+		checksums = append(checksums, entry)
+		return nil
+	}, func(path string, relativePath string) error {
+		times, err := opts.fileTimes(path)
 
 		if err != nil {
 			return err
 		}
 
-		checksums = append(checksums, FileChecksum{
-			Path:     relativePath,
-			Checksum: checksum,
-		})
+		entry := FileChecksum{
+			Path:         opts.manifestPath(relativePath),
+			PresenceOnly: true,
+			Tags:         opts.tagsFor(relativePath),
+			Type:         checksum.TypeDir,
+			Times:        times,
+		}
+
+		if err := opts.runFileHook(ctx, entry); err != nil {
+			return err
+		}
+
+		checksums = append(checksums, entry)
+
 		return nil
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("error walking the directory: %w", err)
+		return checksums, err
 	}
 
 	return checksums, nil
 }
 
-func isIgnored(path string, ignorePatterns []string, rootDir string) (bool, error) {
-	relativePath, err := filepath.Rel(rootDir, path)
+func (o scanOptions) manifestPath(relativePath string) string {
+	relativePath = filepath.ToSlash(relativePath)
 
-	if err != nil {
-		return false, err
+	if o.pathKey == nil {
+		return relativePath
 	}
 
-	for _, pattern := range ignorePatterns {
-		if strings.HasPrefix(relativePath, pattern) {
-			return true, nil
+	mac := hmac.New(sha256.New, o.pathKey)
+	mac.Write([]byte(relativePath))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (o scanOptions) isPresenceOnly(relativePath string) bool {
+	for _, pattern := range o.presenceOnly {
+		if checksum.MatchGlob(pattern, filepath.ToSlash(relativePath)) {
+			return true
 		}
 	}
 
-	return false, nil
+	return false
 }
 
-func saveToFile(checksums []FileChecksum, outputFile string) error {
-	outputData, err := json.MarshalIndent(checksums, "", "  ")
+func interruptReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "timeout"
+	}
+
+	return "signal"
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to marshal checksums to JSON: %w", err)
+func matchesAny(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
+		}
 	}
 
-	if err := os.WriteFile(outputFile, outputData, 0644); err != nil {
-		return fmt.Errorf("failed to write checksums to file: %w", err)
+	return false
+}
+
+func isExcluded(path string, excludedFiles []string) bool {
+	for _, excluded := range excludedFiles {
+		if path == excluded || strings.HasPrefix(path, excluded+string(filepath.Separator)) {
+			return true
+		}
 	}
 
-	return nil
+	return false
 }
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Minisign and signify keys and signatures are base64 lines under an untrusted
+// comment, each starting with the algorithm and the 8 byte ID of the key.
+const (
+	ed25519Algorithm        = "Ed"
+	minisignHashedAlgorithm = "ED"
+	untrustedComment        = "untrusted comment: "
+	trustedComment          = "trusted comment: "
+)
+
+type ed25519Key struct {
+	keyID   []byte
+	public  ed25519.PublicKey
+	private ed25519.PrivateKey
+}
+
+type minisignKey ed25519Key
+
+type signifyKey ed25519Key
+
+// signatureLines returns the non-empty lines of a key or signature file.
+func signatureLines(data []byte) []string {
+	var lines []string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// decodeKeyFile decodes the key line of a minisign or signify key file. The
+// untrusted comment may be left out, as when the key comes from a variable.
+func decodeKeyFile(data []byte) ([]byte, error) {
+	for _, line := range signatureLines(data) {
+		if !strings.HasPrefix(line, untrustedComment) {
+			return base64.StdEncoding.DecodeString(strings.TrimSpace(line))
+		}
+	}
+
+	return nil, fmt.Errorf("no key found")
+}
+
+// formatKeyID prints a key ID as minisign does, a little-endian number.
+func formatKeyID(keyID []byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(keyID))
+}
+
+// parseEd25519PublicKey reads a minisign or signify public key, which share
+// one layout.
+func parseEd25519PublicKey(data []byte) (ed25519Key, error) {
+	raw, err := decodeKeyFile(data)
+
+	if err != nil {
+		return ed25519Key{}, err
+	}
+
+	if len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != ed25519Algorithm {
+		return ed25519Key{}, fmt.Errorf("not a minisign or signify public key")
+	}
+
+	return ed25519Key{keyID: raw[2:10], public: ed25519.PublicKey(raw[10:])}, nil
+}
+
+// parseMinisignSecretKey reads a minisign secret key, decrypting it with
+// password unless it was created with minisign -G -W.
+func parseMinisignSecretKey(data []byte, password string) (minisignKey, error) {
+	raw, err := decodeKeyFile(data)
+
+	if err != nil {
+		return minisignKey{}, err
+	}
+
+	if len(raw) != 158 || string(raw[:2]) != ed25519Algorithm || string(raw[4:6]) != "B2" {
+		return minisignKey{}, fmt.Errorf("not a minisign secret key")
+	}
+
+	salt := raw[6:38]
+	opsLimit := binary.LittleEndian.Uint64(raw[38:46])
+	memLimit := binary.LittleEndian.Uint64(raw[46:54])
+	secret := bytes.Clone(raw[54:])
+
+	switch string(raw[2:4]) {
+	case "Sc":
+		if password == "" {
+			return minisignKey{}, fmt.Errorf("minisign secret key is encrypted, set -sign-key-password")
+		}
+
+		n, r, p := minisignScryptParams(opsLimit, memLimit)
+
+		stream, err := scrypt.Key([]byte(password), salt, n, r, p, len(secret))
+
+		if err != nil {
+			return minisignKey{}, fmt.Errorf("failed to derive minisign key: %w", err)
+		}
+
+		subtle.XORBytes(secret, secret, stream)
+	case "\x00\x00":
+	default:
+		return minisignKey{}, fmt.Errorf("unsupported minisign key derivation %q", raw[2:4])
+	}
+
+	keyID, private, checksum := secret[:8], secret[8:72], secret[72:]
+
+	hash, _ := blake2b.New256(nil)
+	hash.Write([]byte(ed25519Algorithm))
+	hash.Write(keyID)
+	hash.Write(private)
+
+	if subtle.ConstantTimeCompare(hash.Sum(nil), checksum) != 1 {
+		return minisignKey{}, fmt.Errorf("wrong password for minisign secret key")
+	}
+
+	key := ed25519.PrivateKey(private)
+
+	return minisignKey{keyID: keyID, public: key.Public().(ed25519.PublicKey), private: key}, nil
+}
+
+// minisignScryptParams maps the stored limits to scrypt parameters the way
+// libsodium's crypto_pwhash_scryptsalsa208sha256 does.
+func minisignScryptParams(opsLimit uint64, memLimit uint64) (int, int, int) {
+	opsLimit = max(opsLimit, 32768)
+	r := uint64(8)
+	maxN := memLimit / (r * 128)
+
+	if opsLimit < memLimit/32 {
+		maxN = opsLimit / (r * 4)
+	}
+
+	logN := 1
+
+	for ; logN < 63; logN++ {
+		if uint64(1)<<logN > maxN/2 {
+			break
+		}
+	}
+
+	p := uint64(1)
+
+	if opsLimit >= memLimit/32 {
+		p = min((opsLimit/4)/(uint64(1)<<logN), 0x3fffffff) / r
+	}
+
+	return 1 << logN, int(r), int(p)
+}
+
+// sign writes a prehashed signature, the default since minisign 0.8, whose
+// trusted comment is signed along with it.
+func (k minisignKey) sign(data []byte) ([]byte, error) {
+	digest := blake2b.Sum512(data)
+
+	signature := append([]byte(minisignHashedAlgorithm), k.keyID...)
+	signature = append(signature, ed25519.Sign(k.private, digest[:])...)
+
+	comment := fmt.Sprintf("timestamp:%d\thashed", time.Now().Unix())
+	global := ed25519.Sign(k.private, append(bytes.Clone(signature[10:]), comment...))
+
+	encoded := fmt.Sprintf("%ssignature from minisign secret key %s\n%s\n%s%s\n%s\n",
+		untrustedComment, formatKeyID(k.keyID), base64.StdEncoding.EncodeToString(signature),
+		trustedComment, comment, base64.StdEncoding.EncodeToString(global))
+
+	return []byte(encoded), nil
+}
+
+// verify checks a minisign signature and its trusted comment, as minisign -V.
+func (k minisignKey) verify(data []byte, signatureData []byte) error {
+	lines := signatureLines(signatureData)
+
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], untrustedComment) || !strings.HasPrefix(lines[2], trustedComment) {
+		return fmt.Errorf("not a minisign signature")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(lines[1])
+
+	if err != nil || len(signature) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("not a minisign signature")
+	}
+
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return fmt.Errorf("not a minisign signature")
+	}
+
+	if !bytes.Equal(signature[2:10], k.keyID) {
+		return fmt.Errorf("signature was made with key %s, not %s", formatKeyID(signature[2:10]), formatKeyID(k.keyID))
+	}
+
+	message := data
+
+	switch string(signature[:2]) {
+	case minisignHashedAlgorithm:
+		digest := blake2b.Sum512(data)
+		message = digest[:]
+	case ed25519Algorithm:
+	default:
+		return fmt.Errorf("unsupported minisign signature algorithm %q", signature[:2])
+	}
+
+	if !ed25519.Verify(k.public, message, signature[10:]) {
+		return fmt.Errorf("signature does not match the public key")
+	}
+
+	comment := strings.TrimPrefix(lines[2], trustedComment)
+
+	if !ed25519.Verify(k.public, append(bytes.Clone(signature[10:]), comment...), global) {
+		return fmt.Errorf("trusted comment signature does not match the public key")
+	}
+
+	return nil
+}
+
+// parseSignifySecretKey reads a signify secret key. Only keys created with
+// signify -G -n are accepted, because signify cannot be given a passphrase
+// without a terminal either.
+func parseSignifySecretKey(data []byte) (signifyKey, error) {
+	raw, err := decodeKeyFile(data)
+
+	if err != nil {
+		return signifyKey{}, err
+	}
+
+	if len(raw) != 104 || string(raw[:2]) != ed25519Algorithm || string(raw[2:4]) != "BK" {
+		return signifyKey{}, fmt.Errorf("not a signify secret key")
+	}
+
+	if rounds := binary.BigEndian.Uint32(raw[4:8]); rounds != 0 {
+		return signifyKey{}, fmt.Errorf("signify secret key is protected with a passphrase, create it with signify -G -n")
+	}
+
+	checksum, keyID, private := raw[24:32], raw[32:40], raw[40:]
+	digest := sha512.Sum512(private)
+
+	if subtle.ConstantTimeCompare(digest[:8], checksum) != 1 {
+		return signifyKey{}, fmt.Errorf("signify secret key checksum does not match")
+	}
+
+	key := ed25519.PrivateKey(private)
+
+	return signifyKey{keyID: keyID, public: key.Public().(ed25519.PublicKey), private: key}, nil
+}
+
+func (k signifyKey) sign(data []byte) ([]byte, error) {
+	signature := append([]byte(ed25519Algorithm), k.keyID...)
+	signature = append(signature, ed25519.Sign(k.private, data)...)
+
+	return []byte(untrustedComment + "signature from signify secret key\n" + base64.StdEncoding.EncodeToString(signature) + "\n"), nil
+}
+
+// verify checks a detached signify signature, as signify -V.
+func (k signifyKey) verify(data []byte, signatureData []byte) error {
+	lines := signatureLines(signatureData)
+
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], untrustedComment) {
+		return fmt.Errorf("not a signify signature")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(lines[1])
+
+	if err != nil || len(signature) != 2+8+ed25519.SignatureSize || string(signature[:2]) != ed25519Algorithm {
+		return fmt.Errorf("not a signify signature")
+	}
+
+	if !bytes.Equal(signature[2:10], k.keyID) {
+		return fmt.Errorf("signature was made with a different key")
+	}
+
+	if !ed25519.Verify(k.public, data, signature[10:]) {
+		return fmt.Errorf("signature does not match the public key")
+	}
+
+	return nil
+}
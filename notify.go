@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultNotifyFormat = "generic"
+	notifyMaxChanges    = 10
+)
+
+func validateNotifyFormat(format string) error {
+	switch format {
+	case "generic", "slack", "teams":
+		return nil
+	}
+
+	return fmt.Errorf("unsupported notification format: %s", format)
+}
+
+func summarizeReport(report verifyReport) string {
+	var builder strings.Builder
+
+	summary := report.Summary
+
+	if len(report.Changes) == 0 {
+		fmt.Fprintf(&builder, "Checksum verification of %s passed: %d files unchanged", report.Root, summary.Unchanged)
+	} else {
+		fmt.Fprintf(
+			&builder,
+			"Checksum verification of %s found %d changes: %d modified, %d removed, %d added, %d errors",
+			report.Root, len(report.Changes), summary.Modified, summary.Removed, summary.Added, summary.Errors,
+		)
+	}
+
+	for i, change := range report.Changes {
+		if i == notifyMaxChanges {
+			fmt.Fprintf(&builder, "\n... and %d more", len(report.Changes)-notifyMaxChanges)
+
+			break
+		}
+
+		fmt.Fprintf(&builder, "\n- %s: %s", change.Kind, change.Path)
+	}
+
+	if runURL := actionRunURL(); runURL != "" {
+		fmt.Fprintf(&builder, "\n%s", runURL)
+	}
+
+	return builder.String()
+}
+
+func actionRunURL() string {
+	server := os.Getenv("GITHUB_SERVER_URL")
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	runID := os.Getenv("GITHUB_RUN_ID")
+
+	if server == "" || repository == "" || runID == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/%s/actions/runs/%s", server, repository, runID)
+}
+
+func notificationPayload(report verifyReport, format string) ([]byte, error) {
+	text := summarizeReport(report)
+
+	switch format {
+	case "slack":
+		return json.Marshal(map[string]string{"text": text})
+	case "teams":
+		return json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  "Checksum verification results",
+			"text":     strings.ReplaceAll(text, "\n", "\n\n"),
+		})
+	}
+
+	return json.Marshal(struct {
+		Text    string        `json:"text"`
+		Root    string        `json:"root"`
+		Summary verifySummary `json:"summary"`
+		Changes []fileChange  `json:"changes"`
+	}{
+		Text:    text,
+		Root:    report.Root,
+		Summary: report.Summary,
+		Changes: report.Changes,
+	})
+}
+
+// withoutURL drops the URL net/http puts in request errors, for webhook and
+// API URLs that carry their token and must not end up in logs.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+
+	return err
+}
+
+func sendWebhook(url string, report verifyReport, format string) error {
+	payload, err := notificationPayload(report, format)
+
+	if err != nil {
+		return fmt.Errorf("failed to build notification payload: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	response, err := client.Post(url, "application/json", bytes.NewReader(payload))
+
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", withoutURL(err))
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook responded with %s", response.Status)
+	}
+
+	return nil
+}
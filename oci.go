@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociEmptyMediaType    = "application/vnd.oci.empty.v1+json"
+	ociArtifactType      = "application/vnd.checksum-action.manifest.v1"
+	dockerHubRegistry    = "registry-1.docker.io"
+)
+
+var ociManifestAccept = strings.Join([]string{
+	ociManifestMediaType,
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}, ", ")
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+type ociReference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+type ociDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Subject       *ociDescriptor    `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+type registryError struct {
+	method  string
+	path    string
+	status  int
+	message string
+}
+
+func (e *registryError) Error() string {
+	return fmt.Sprintf("%s %s responded with %d: %s", e.method, e.path, e.status, e.message)
+}
+
+// parseOCIReference splits registry/repository[:tag][@digest], applying the
+// same Docker Hub defaults as docker pull.
+func parseOCIReference(ref string) (ociReference, error) {
+	var parsed ociReference
+
+	name, digest, _ := strings.Cut(ref, "@")
+	parsed.digest = digest
+
+	if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		parsed.tag = name[colon+1:]
+		name = name[:colon]
+	}
+
+	first, rest, found := strings.Cut(name, "/")
+
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		parsed.registry = first
+		parsed.repository = rest
+	} else {
+		parsed.registry = dockerHubRegistry
+		parsed.repository = name
+
+		if !found {
+			parsed.repository = "library/" + name
+		}
+	}
+
+	if parsed.repository == "" || parsed.repository != strings.ToLower(parsed.repository) {
+		return ociReference{}, fmt.Errorf("invalid OCI reference %q, expected registry/repository[:tag]", ref)
+	}
+
+	return parsed, nil
+}
+
+func (r ociReference) String() string {
+	name := r.registry + "/" + r.repository
+
+	if r.tag != "" {
+		name += ":" + r.tag
+	}
+
+	if r.digest != "" {
+		name += "@" + r.digest
+	}
+
+	return name
+}
+
+type registryClient struct {
+	baseURL    string
+	repository string
+	username   string
+	password   string
+	auth       string
+	http       *http.Client
+}
+
+func newRegistryClient(ref ociReference, username string, password string) *registryClient {
+	scheme := "https"
+	host, _, _ := strings.Cut(ref.registry, ":")
+
+	if host == "localhost" || host == "127.0.0.1" {
+		scheme = "http"
+	}
+
+	return &registryClient{
+		baseURL:    scheme + "://" + ref.registry,
+		repository: ref.repository,
+		username:   username,
+		password:   password,
+		http:       &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// do sends a request, answering a single 401 challenge by fetching a token
+// for push and pull access to the repository.
+func (c *registryClient) do(ctx context.Context, method string, target string, header http.Header, body []byte) (*http.Response, error) {
+	if !strings.Contains(target, "://") {
+		target = c.baseURL + target
+	}
+
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for name, values := range header {
+			request.Header[name] = values
+		}
+
+		if c.auth != "" {
+			request.Header.Set("Authorization", c.auth)
+		}
+
+		response, err := c.http.Do(request)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := response.Header.Get("WWW-Authenticate")
+			response.Body.Close()
+
+			if err := c.authenticate(ctx, challenge); err != nil {
+				return nil, fmt.Errorf("failed to authenticate to registry: %w", err)
+			}
+
+			continue
+		}
+
+		if response.StatusCode >= 300 {
+			defer response.Body.Close()
+
+			message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+			return nil, &registryError{
+				method:  method,
+				path:    request.URL.Path,
+				status:  response.StatusCode,
+				message: strings.TrimSpace(string(message)),
+			}
+		}
+
+		return response, nil
+	}
+}
+
+func (c *registryClient) authenticate(ctx context.Context, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+
+	if strings.EqualFold(scheme, "Basic") {
+		if c.username == "" {
+			return fmt.Errorf("registry requires credentials, set -oci-username and -oci-password")
+		}
+
+		c.auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password))
+
+		return nil
+	}
+
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("unsupported challenge %q", challenge)
+	}
+
+	values := make(map[string]string)
+
+	for _, match := range challengeParam.FindAllStringSubmatch(params, -1) {
+		values[match[1]] = match[2]
+	}
+
+	if values["realm"] == "" {
+		return fmt.Errorf("challenge %q has no realm", challenge)
+	}
+
+	query := url.Values{}
+	query.Set("scope", "repository:"+c.repository+":pull,push")
+
+	if values["service"] != "" {
+		query.Set("service", values["service"])
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, values["realm"]+"?"+query.Encode(), nil)
+
+	if err != nil {
+		return err
+	}
+
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	response, err := c.http.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("token endpoint responded with %d", response.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to decode token: %w", err)
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	c.auth = "Bearer " + token.Token
+
+	return nil
+}
+
+func ociDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// pushBlob uploads data in a single monolithic PUT unless the registry
+// already has it.
+func (c *registryClient) pushBlob(ctx context.Context, data []byte) (string, error) {
+	digest := ociDigest(data)
+
+	response, err := c.do(ctx, http.MethodHead, "/v2/"+c.repository+"/blobs/"+digest, nil, nil)
+
+	if err == nil {
+		response.Body.Close()
+
+		return digest, nil
+	}
+
+	response, err = c.do(ctx, http.MethodPost, "/v2/"+c.repository+"/blobs/uploads/", nil, nil)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to start upload: %w", err)
+	}
+
+	response.Body.Close()
+
+	location, err := response.Request.URL.Parse(response.Header.Get("Location"))
+
+	if err != nil {
+		return "", fmt.Errorf("invalid upload location: %w", err)
+	}
+
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+
+	response, err = c.do(ctx, http.MethodPut, location.String(), header, data)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to upload blob: %w", err)
+	}
+
+	response.Body.Close()
+
+	return digest, nil
+}
+
+// resolve returns the descriptor of the manifest ref points to, used as the
+// subject of the pushed artifact.
+func (c *registryClient) resolve(ctx context.Context, ref ociReference) (*ociDescriptor, error) {
+	reference := ref.digest
+
+	if reference == "" {
+		reference = ref.tag
+	}
+
+	if reference == "" {
+		reference = "latest"
+	}
+
+	response, err := c.do(ctx, http.MethodHead, "/v2/"+c.repository+"/manifests/"+reference, http.Header{"Accept": {ociManifestAccept}}, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body.Close()
+
+	size, err := strconv.ParseInt(response.Header.Get("Content-Length"), 10, 64)
+
+	if err != nil {
+		return nil, fmt.Errorf("registry did not report the size of %s", ref)
+	}
+
+	digest := response.Header.Get("Docker-Content-Digest")
+
+	if digest == "" {
+		digest = ref.digest
+	}
+
+	if digest == "" {
+		return nil, fmt.Errorf("registry did not report the digest of %s", ref)
+	}
+
+	mediaType, _, _ := strings.Cut(response.Header.Get("Content-Type"), ";")
+
+	return &ociDescriptor{MediaType: mediaType, Digest: digest, Size: size}, nil
+}
+
+func ociLayerMediaType(format string) string {
+	switch format {
+	case "json", "sri":
+		return "application/json"
+	case "gosrc":
+		return "text/x-go"
+	}
+
+	return "text/plain"
+}
+
+// pushOCIArtifact stores a rendered manifest as an ORAS style artifact: an
+// empty config, the manifest as the only layer titled after the output file,
+// and optionally a subject so registries list it among the image's referrers.
+func pushOCIArtifact(ctx context.Context, cfg config, target string, data []byte) (string, error) {
+	ref, err := parseOCIReference(target)
+
+	if err != nil {
+		return "", err
+	}
+
+	client := newRegistryClient(ref, cfg.ociUsername, cfg.ociPassword)
+
+	var subject *ociDescriptor
+
+	if cfg.ociSubject != "" {
+		subjectRef, err := parseOCIReference(cfg.ociSubject)
+
+		if err != nil {
+			return "", err
+		}
+
+		if subjectRef.registry != ref.registry || subjectRef.repository != ref.repository {
+			return "", fmt.Errorf("subject %s must be in the pushed repository %s/%s", cfg.ociSubject, ref.registry, ref.repository)
+		}
+
+		subject, err = client.resolve(ctx, subjectRef)
+
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve subject %s: %w", cfg.ociSubject, err)
+		}
+	}
+
+	empty := []byte("{}")
+
+	configDigest, err := client.pushBlob(ctx, empty)
+
+	if err != nil {
+		return "", err
+	}
+
+	layerDigest, err := client.pushBlob(ctx, data)
+
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := json.Marshal(ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		ArtifactType:  ociArtifactType,
+		Config:        ociDescriptor{MediaType: ociEmptyMediaType, Digest: configDigest, Size: int64(len(empty))},
+		Layers: []ociDescriptor{{
+			MediaType:   ociLayerMediaType(cfg.format),
+			Digest:      layerDigest,
+			Size:        int64(len(data)),
+			Annotations: map[string]string{"org.opencontainers.image.title": filepath.Base(cfg.outputFile)},
+		}},
+		Subject:     subject,
+		Annotations: map[string]string{"org.opencontainers.image.created": cfg.startedAt.UTC().Format(time.RFC3339)},
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	digest := ociDigest(manifest)
+	reference := ref.tag
+
+	if reference == "" {
+		reference = digest
+	}
+
+	response, err := client.do(ctx, http.MethodPut, "/v2/"+ref.repository+"/manifests/"+reference, http.Header{"Content-Type": {ociManifestMediaType}}, manifest)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to push manifest: %w", err)
+	}
+
+	response.Body.Close()
+
+	return digest, nil
+}
+
+func ociSink(cfg config, target string) checksum.Sink {
+	return checksum.SinkFunc(func(ctx context.Context, entries []checksum.Entry, data []byte) error {
+		digest, err := pushOCIArtifact(ctx, cfg, target, data)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Pushed manifest to %s@%s\n", target, digest)
+
+		return nil
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	verifyOrderManifest = "manifest"
+	// verifyOrderRecent checks the most recently modified files first, the ones
+	// most likely to have changed.
+	verifyOrderRecent = "recent"
+	// verifyOrderPriority checks tagged files first, then by recency.
+	verifyOrderPriority = "priority"
+)
+
+func validateVerifyOrder(order string) error {
+	switch order {
+	case verifyOrderManifest, verifyOrderRecent, verifyOrderPriority:
+		return nil
+	default:
+		return fmt.Errorf("unsupported verify order: %s", order)
+	}
+}
+
+// orderEntries returns expected in the order -verify-order checks it in.
+// Missing files fail without being read, so they lead their group.
+func (o scanOptions) orderEntries(expected []FileChecksum, present map[string]string) []FileChecksum {
+	if o.verifyOrder == "" || o.verifyOrder == verifyOrderManifest {
+		return expected
+	}
+
+	ordered := append([]FileChecksum{}, expected...)
+	modified := make(map[string]int64, len(ordered))
+	tagged := make(map[string]bool, len(ordered))
+
+	for _, entry := range ordered {
+		key := filepath.ToSlash(entry.Path)
+		modified[key] = math.MaxInt64
+
+		if path, ok := present[key]; ok {
+			if info, err := os.Stat(path); err == nil {
+				modified[key] = info.ModTime().UnixNano()
+			}
+		}
+
+		tagged[key] = o.verifyOrder == verifyOrderPriority && len(o.entryTags(entry)) > 0
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		left, right := filepath.ToSlash(ordered[i].Path), filepath.ToSlash(ordered[j].Path)
+
+		if tagged[left] != tagged[right] {
+			return tagged[left]
+		}
+
+		return modified[left] > modified[right]
+	})
+
+	return ordered
+}
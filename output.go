@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+const defaultFormat = "json"
+
+func validateFormat(format string, hashOpts checksum.Options) error {
+	switch format {
+	case "json", "gosrc":
+		return nil
+	case "sri":
+		if hashOpts.DigestBytes > 0 {
+			return fmt.Errorf("sri format does not support truncated digests")
+		}
+
+		for _, algorithm := range hashOpts.Algorithms() {
+			if !isSRIAlgorithm(algorithm) {
+				return fmt.Errorf("sri format requires sha256, sha384 or sha512, got %s", algorithm)
+			}
+		}
+
+		return nil
+	case "sums":
+		return validateSumsOptions(hashOpts)
+	}
+
+	if _, ok := checksum.LookupFormatter(format); ok {
+		return nil
+	}
+
+	return fmt.Errorf("unsupported output format: %s", format)
+}
+
+func isSRIAlgorithm(algorithm string) bool {
+	return algorithm == "sha256" || algorithm == "sha384" || algorithm == "sha512"
+}
+
+func formatChecksums(checksums []FileChecksum, format string, header checksum.Header) ([]byte, error) {
+	switch format {
+	case "sri":
+		integrity := make(map[string]string, len(checksums))
+
+		for _, checksum := range checksums {
+			if checksum.PresenceOnly {
+				continue
+			}
+
+			integrity[checksum.Path] = checksum.Algorithm + "-" + checksum.Checksum
+		}
+
+		return json.MarshalIndent(integrity, "", "  ")
+	case "sums":
+		return formatSums(checksums)
+	case "json":
+	default:
+		if formatter, ok := checksum.LookupFormatter(format); ok {
+			return formatter.Format(checksums)
+		}
+	}
+
+	return checksum.MarshalManifestWithHeader(checksums, header)
+}
+
+func saveToFile(checksums []FileChecksum, outputFile string, format string, header checksum.Header) error {
+	outputData, err := formatChecksums(checksums, format, header)
+
+	if err != nil {
+		return fmt.Errorf("failed to format checksums: %w", err)
+	}
+
+	if err := writeFileAtomic(outputFile, outputData, 0644); err != nil {
+		return fmt.Errorf("failed to write checksums to file: %w", err)
+	}
+
+	return nil
+}
+
+func loadFromFile(inputFile string) ([]FileChecksum, error) {
+	inputData, err := os.ReadFile(inputFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums file: %w", err)
+	}
+
+	return parseManifestFile(inputFile, inputData)
+}
+
+func parseManifestFile(inputFile string, inputData []byte) ([]FileChecksum, error) {
+	if isManifestIndex(inputData) {
+		return loadIndex(inputFile, inputData)
+	}
+
+	return checksum.ParseFile(inputData, filepath.Base(inputFile))
+}
+
+func setActionOutput(name string, value string) error {
+	outputFile := os.Getenv("GITHUB_OUTPUT")
+
+	if outputFile == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return fmt.Errorf("failed to open action output file: %w", err)
+	}
+
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "%s=%s\n", name, value); err != nil {
+		return fmt.Errorf("failed to write action output: %w", err)
+	}
+
+	return nil
+}
+
+func atomicTempPattern(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+
+	if err != nil {
+		return err
+	}
+
+	tempPath := file.Name()
+
+	defer os.Remove(tempPath)
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tempPath, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, path)
+}
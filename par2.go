@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const defaultPAR2Command = "par2"
+
+func recoveryDir(outputFile string) string {
+	return outputFile + ".recovery"
+}
+
+// parsePAR2Redundancy reads a -par2 value, the size of the recovery data as a
+// percentage of each file like 10%.
+func parsePAR2Redundancy(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	number, ok := strings.CutSuffix(value, "%")
+	redundancy, err := strconv.Atoi(number)
+
+	if !ok || err != nil || redundancy <= 0 || redundancy > 100 {
+		return 0, fmt.Errorf("invalid -par2 %q, expected a redundancy percentage like 10%%", value)
+	}
+
+	return redundancy, nil
+}
+
+// recoveryFile returns where the PAR2 index of the file at manifest path
+// relativePath is kept, its recovery volumes sitting next to it.
+func recoveryFile(dir string, relativePath string) string {
+	return filepath.Join(dir, filepath.FromSlash(relativePath)) + ".par2"
+}
+
+// writeRecoveryFiles creates PAR2 recovery data for every hashed file with
+// -par2-command, one recovery set per file so a damaged file is repaired on
+// its own. Bit rot leaves modification times alone, so a set newer than its
+// file still describes the intact content and is kept rather than rebuilt
+// from bytes that may have rotted since.
+func writeRecoveryFiles(ctx context.Context, cfg config, rootDir string, checksums []FileChecksum) (int, error) {
+	written := 0
+
+	for _, entry := range checksums {
+		if ctx.Err() != nil {
+			return written, ctx.Err()
+		}
+
+		if entry.PresenceOnly || entry.Size == 0 {
+			continue
+		}
+
+		source := filepath.Join(rootDir, filepath.FromSlash(entry.Path))
+		target := recoveryFile(cfg.par2Dir, entry.Path)
+
+		info, err := os.Lstat(source)
+
+		if err != nil {
+			return written, err
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		if existing, err := os.Stat(target); err == nil && !existing.ModTime().Before(info.ModTime()) {
+			continue
+		}
+
+		if err := removeRecoverySet(target); err != nil {
+			return written, fmt.Errorf("failed to replace recovery data for %s: %w", entry.Path, err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+
+		args := []string{"create", "-qq", "-n1", "-r" + strconv.Itoa(cfg.par2Redundancy), "-B", rootDir, target, source}
+
+		if err := runPAR2(ctx, cfg.par2Command, args); err != nil {
+			return written, fmt.Errorf("failed to create recovery data for %s: %w", entry.Path, err)
+		}
+
+		written++
+	}
+
+	return written, nil
+}
+
+// removeRecoverySet deletes the index file target and its recovery volumes,
+// named like file.vol00+10.par2, which par2 refuses to overwrite.
+func removeRecoverySet(target string) error {
+	entries, err := os.ReadDir(filepath.Dir(target))
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.TrimSuffix(filepath.Base(target), ".par2")
+
+	for _, file := range entries {
+		name := file.Name()
+
+		if name == filepath.Base(target) || (strings.HasPrefix(name, prefix+".vol") && strings.HasSuffix(name, ".par2")) {
+			if err := os.Remove(filepath.Join(filepath.Dir(target), name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func runPAR2(ctx context.Context, par2Command string, args []string) error {
+	command := strings.Fields(par2Command)
+
+	if len(command) == 0 {
+		command = []string{defaultPAR2Command}
+	}
+
+	var output bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, command[0], append(command[1:], args...)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(output.String()); message != "" {
+			return fmt.Errorf("%s %s failed: %w: %s", command[0], args[0], err, message)
+		}
+
+		return fmt.Errorf("%s %s failed: %w", command[0], args[0], err)
+	}
+
+	return nil
+}
+
+// printRepairHints tells how to repair the modified files that have recovery
+// data, since bit rot is what it protects against.
+func printRepairHints(cfg config, rootDir string, changes []fileChange) {
+	for _, change := range changes {
+		if change.Kind != changeModified {
+			continue
+		}
+
+		target := recoveryFile(cfg.par2Dir, change.Path)
+
+		if _, err := os.Stat(target); err == nil {
+			fmt.Printf("Recovery data for %s, repair with: %s repair -B %s %s\n", change.Path, cfg.par2Command, rootDir, target)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import "strings"
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+
+		if item != "" {
+			*l = append(*l, item)
+		}
+	}
+
+	return nil
+}
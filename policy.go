@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	severityFail   = "fail"
+	severityWarn   = "warn"
+	severityIgnore = "ignore"
+)
+
+// severityPolicy is the file given to -policy-file:
+//
+//	rules:
+//	  - kinds: [added, modified]
+//	    paths: ["dist/**"]
+//	    severity: warn
+//	  - kinds: [unlisted]
+//	    severity: ignore
+//	  - types: [symlink]
+//	    severity: warn
+//	  - kinds: [added]
+//	    knownGood: true
+//	    severity: warn
+//	  - empty: true
+//	    severity: fail
+//
+// The first rule matching a change decides its severity; changes no rule
+// matches fail verification.
+type severityPolicy struct {
+	Rules []severityRule `yaml:"rules"`
+}
+
+// severityRule matches the changes of one of Kinds, to a path matching one of
+// Paths, tagged with one of Tags and of one of the recorded entry Types.
+// Empty lists match every change. KnownGood, when set, matches the changes
+// whose new content is, or is not, in the -known-good hash sets, and Empty the
+// changes that left a file empty outside the -allow-empty patterns.
+type severityRule struct {
+	Kinds     []changeKind `yaml:"kinds"`
+	Paths     []string     `yaml:"paths"`
+	Tags      []string     `yaml:"tags"`
+	Types     []string     `yaml:"types"`
+	KnownGood *bool        `yaml:"knownGood"`
+	Empty     *bool        `yaml:"empty"`
+	Severity  string       `yaml:"severity"`
+}
+
+func loadSeverityPolicy(path string) ([]severityRule, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var policy severityPolicy
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for i, rule := range policy.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d in %s: %w", i+1, path, err)
+		}
+	}
+
+	return policy.Rules, nil
+}
+
+func (r severityRule) validate() error {
+	switch r.Severity {
+	case severityFail, severityWarn, severityIgnore:
+	default:
+		return fmt.Errorf("unsupported severity: %q", r.Severity)
+	}
+
+	for _, kind := range r.Kinds {
+		switch kind {
+		case changeAdded, changeRemoved, changeModified, changeError, changeFiltered, changeUnlisted, changeRenamed:
+		default:
+			return fmt.Errorf("unsupported change kind: %s", kind)
+		}
+	}
+
+	for _, fileType := range r.Types {
+		switch fileType {
+		case checksum.TypeRegular, checksum.TypeSymlink, checksum.TypeDir, checksum.TypeSocket, checksum.TypeFIFO, checksum.TypeDevice:
+		default:
+			return fmt.Errorf("unsupported entry type: %s", fileType)
+		}
+	}
+
+	for _, pattern := range r.Paths {
+		if err := checksum.ValidateGlob(pattern); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r severityRule) matches(change fileChange) bool {
+	if len(r.Kinds) > 0 && !containsKind(r.Kinds, change.Kind) {
+		return false
+	}
+
+	if len(r.Paths) > 0 && !matchesAnyGlob(r.Paths, filepath.ToSlash(change.Path)) {
+		return false
+	}
+
+	if len(r.Tags) > 0 && !containsAnyString(change.Tags, r.Tags) {
+		return false
+	}
+
+	if len(r.Types) > 0 && !containsString(r.Types, change.Type) {
+		return false
+	}
+
+	if r.KnownGood != nil && (change.KnownGood != "") != *r.KnownGood {
+		return false
+	}
+
+	if r.Empty != nil && change.Empty != *r.Empty {
+		return false
+	}
+
+	return true
+}
+
+// severityOf returns the severity of the first rule matching change.
+func severityOf(change fileChange, rules []severityRule) string {
+	for _, rule := range rules {
+		if rule.matches(change) {
+			return rule.Severity
+		}
+	}
+
+	return severityFail
+}
+
+// applySeverities drops the changes the rules ignore, counting them, and marks
+// those that only warn.
+func applySeverities(result *verifyResult, rules []severityRule) {
+	if len(rules) == 0 {
+		return
+	}
+
+	var kept []fileChange
+
+	for _, change := range result.changes {
+		switch severityOf(change, rules) {
+		case severityIgnore:
+			result.ignored++
+
+			continue
+		case severityWarn:
+			change.Warning = true
+		}
+
+		kept = append(kept, change)
+	}
+
+	result.changes = kept
+}
+
+func containsKind(kinds []changeKind, kind changeKind) bool {
+	for _, candidate := range kinds {
+		if candidate == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsAnyString(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsString(values, candidate) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesAnyGlob(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if checksum.MatchGlob(pattern, path) {
+			return true
+		}
+	}
+
+	return false
+}
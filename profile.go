@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiling serves pprof endpoints and starts a CPU profile as
+// configured. The returned function stops the CPU profile and writes the heap
+// profile, and must run before the process exits.
+func startProfiling(cfg config) (func(), error) {
+	if cfg.pprofAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(cfg.pprofAddr, nil); err != nil {
+				fmt.Println("Error serving pprof:", err)
+			}
+		}()
+	}
+
+	var cpuFile *os.File
+
+	if cfg.cpuProfile != "" {
+		file, err := os.Create(cfg.cpuProfile)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+
+		cpuFile = file
+	}
+
+	return func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			cpuFile.Close()
+		}
+
+		if cfg.memProfile != "" {
+			if err := writeHeapProfile(cfg.memProfile); err != nil {
+				fmt.Println("Error writing memory profile:", err)
+			}
+		}
+	}, nil
+}
+
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	runtime.GC()
+
+	return pprof.WriteHeapProfile(file)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const quarantineChangesFile = "changes.json"
+
+// quarantineChanges copies the added and modified files of a failed
+// verification into dir under their paths in the tree, and writes every change
+// with its expected digest to dir/changes.json, so they can be examined after
+// later steps overwrite the tree. Changes the severity policies ignore are
+// left out.
+func quarantineChanges(dir string, projectDir string, changes []fileChange, rules []severityRule) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	kept := []fileChange{}
+	copied := 0
+
+	for _, change := range changes {
+		if severityOf(change, rules) == severityIgnore {
+			continue
+		}
+
+		kept = append(kept, change)
+
+		switch change.Kind {
+		case changeAdded, changeModified, changeUnlisted:
+		default:
+			continue
+		}
+
+		if err := quarantineFile(filepath.Join(projectDir, change.Path), filepath.Join(dir, change.Path)); err != nil {
+			return copied, fmt.Errorf("failed to quarantine %s: %w", change.Path, err)
+		}
+
+		copied++
+	}
+
+	data, err := json.MarshalIndent(kept, "", "  ")
+
+	if err != nil {
+		return copied, fmt.Errorf("failed to marshal quarantined changes: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, quarantineChangesFile), append(data, '\n'), 0644); err != nil {
+		return copied, fmt.Errorf("failed to write quarantined changes: %w", err)
+	}
+
+	return copied, nil
+}
+
+// quarantineFile copies source to target keeping its mode and modification
+// time.
+func quarantineFile(source string, target string) error {
+	file, err := openScanned(source)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	output, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(output, file); err != nil {
+		output.Close()
+
+		return err
+	}
+
+	if err := output.Close(); err != nil {
+		return err
+	}
+
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
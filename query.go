@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOperator
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind  tokenKind
+	value string
+}
+
+type predicate func(entry FileChecksum) bool
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// parseQuery compiles a -where expression such as
+// `path glob "assets/**" and size > 1MB` into a predicate over manifest entries.
+func parseQuery(expression string) (predicate, error) {
+	tokens, err := tokenizeQuery(expression)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return func(FileChecksum) bool { return true }, nil
+	}
+
+	parser := &queryParser{tokens: tokens}
+
+	match, err := parser.parseOr()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected %q", parser.tokens[parser.pos].value)
+	}
+
+	return match, nil
+}
+
+func tokenizeQuery(expression string) ([]queryToken, error) {
+	var tokens []queryToken
+
+	for i := 0; i < len(expression); {
+		c := expression[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen, value: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose, value: ")"})
+			i++
+		case c == '"':
+			end := i + 1
+
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+
+				end++
+			}
+
+			if end >= len(expression) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+
+			value, err := strconv.Unquote(expression[i : end+1])
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+
+			tokens = append(tokens, queryToken{kind: tokenString, value: value})
+			i = end + 1
+		case strings.IndexByte("=!<>", c) >= 0:
+			end := i + 1
+
+			if end < len(expression) && expression[end] == '=' {
+				end++
+			}
+
+			tokens = append(tokens, queryToken{kind: tokenOperator, value: expression[i:end]})
+			i = end
+		default:
+			end := i
+
+			for end < len(expression) && strings.IndexByte(" \t\n()\"=!<>", expression[end]) < 0 {
+				end++
+			}
+
+			tokens = append(tokens, queryToken{kind: tokenWord, value: expression[i:end]})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+func (p *queryParser) next() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+
+	t := p.tokens[p.pos]
+	p.pos++
+
+	return t, true
+}
+
+func (p *queryParser) peekKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenWord && strings.EqualFold(p.tokens[p.pos].value, keyword)
+}
+
+func (p *queryParser) parseOr() (predicate, error) {
+	left, err := p.parseAnd()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("or") {
+		p.pos++
+
+		right, err := p.parseAnd()
+
+		if err != nil {
+			return nil, err
+		}
+
+		a, b := left, right
+		left = func(entry FileChecksum) bool { return a(entry) || b(entry) }
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (predicate, error) {
+	left, err := p.parseUnary()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("and") {
+		p.pos++
+
+		right, err := p.parseUnary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		a, b := left, right
+		left = func(entry FileChecksum) bool { return a(entry) && b(entry) }
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (predicate, error) {
+	if p.peekKeyword("not") {
+		p.pos++
+
+		inner, err := p.parseUnary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return func(entry FileChecksum) bool { return !inner(entry) }, nil
+	}
+
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOpen {
+		p.pos++
+
+		inner, err := p.parseOr()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if t, ok := p.next(); !ok || t.kind != tokenClose {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (predicate, error) {
+	field, ok := p.next()
+
+	if !ok || field.kind != tokenWord {
+		return nil, fmt.Errorf("expected a field name")
+	}
+
+	operator, ok := p.next()
+
+	if !ok || (operator.kind != tokenOperator && operator.kind != tokenWord) {
+		return nil, fmt.Errorf("expected an operator after %q", field.value)
+	}
+
+	value, ok := p.next()
+
+	if !ok || (value.kind != tokenWord && value.kind != tokenString) {
+		return nil, fmt.Errorf("expected a value after %q", operator.value)
+	}
+
+	op := strings.ToLower(operator.value)
+
+	switch strings.ToLower(field.value) {
+	case "path":
+		return compareString(op, value.value, func(entry FileChecksum) string { return entry.Path })
+	case "checksum":
+		return compareString(op, value.value, func(entry FileChecksum) string { return entry.Checksum })
+	case "algorithm":
+		return compareString(op, value.value, func(entry FileChecksum) string { return entry.Algorithm })
+	case "type":
+		return compareString(op, value.value, func(entry FileChecksum) string { return entry.Type })
+	case "contenttype":
+		return compareString(op, value.value, func(entry FileChecksum) string { return entry.ContentType })
+	case "encoding":
+		return compareString(op, value.value, func(entry FileChecksum) string { return entry.Encoding })
+	case "ioc":
+		return compareString(op, value.value, func(entry FileChecksum) string { return entry.IOC })
+	case "knowngood":
+		return compareString(op, value.value, func(entry FileChecksum) string { return entry.KnownGood })
+	case "deleted":
+		return compareString(op, value.value, func(entry FileChecksum) string { return entry.Deleted })
+	case "size":
+		size, err := parseSize(value.value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return compareSize(op, size)
+	case "secrets":
+		expected, err := strconv.ParseBool(value.value)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", value.value)
+		}
+
+		switch op {
+		case "=", "==":
+			return func(entry FileChecksum) bool { return (len(entry.Secrets) > 0) == expected }, nil
+		case "!=":
+			return func(entry FileChecksum) bool { return (len(entry.Secrets) > 0) != expected }, nil
+		}
+
+		return nil, fmt.Errorf("unsupported operator %q for secrets", operator.value)
+	case "presenceonly":
+		expected, err := strconv.ParseBool(value.value)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", value.value)
+		}
+
+		switch op {
+		case "=", "==":
+			return func(entry FileChecksum) bool { return entry.PresenceOnly == expected }, nil
+		case "!=":
+			return func(entry FileChecksum) bool { return entry.PresenceOnly != expected }, nil
+		}
+
+		return nil, fmt.Errorf("unsupported operator %q for presenceOnly", operator.value)
+	}
+
+	return nil, fmt.Errorf("unknown field %q", field.value)
+}
+
+func compareString(op string, value string, get func(entry FileChecksum) string) (predicate, error) {
+	switch op {
+	case "=", "==":
+		return func(entry FileChecksum) bool { return get(entry) == value }, nil
+	case "!=":
+		return func(entry FileChecksum) bool { return get(entry) != value }, nil
+	case "glob":
+		return func(entry FileChecksum) bool { return checksum.MatchGlob(value, get(entry)) }, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operator %q for strings", op)
+}
+
+func compareSize(op string, size int64) (predicate, error) {
+	switch op {
+	case "=", "==":
+		return func(entry FileChecksum) bool { return entry.Size == size }, nil
+	case "!=":
+		return func(entry FileChecksum) bool { return entry.Size != size }, nil
+	case "<":
+		return func(entry FileChecksum) bool { return entry.Size < size }, nil
+	case "<=":
+		return func(entry FileChecksum) bool { return entry.Size <= size }, nil
+	case ">":
+		return func(entry FileChecksum) bool { return entry.Size > size }, nil
+	case ">=":
+		return func(entry FileChecksum) bool { return entry.Size >= size }, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operator %q for size", op)
+}
+
+func parseSize(value string) (int64, error) {
+	number := strings.TrimRightFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	multiplier, ok := sizeUnits[strings.ToLower(value[len(number):])]
+
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in %q", value)
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	return int64(n * multiplier), nil
+}
+
+func runQuery(cfg config, checksumsFilePath string, opts scanOptions) bool {
+	match, err := parseQuery(cfg.where)
+
+	if err != nil {
+		fmt.Println("Error parsing query:", err)
+
+		return false
+	}
+
+	manifest := checksumsFilePath
+
+	if cfg.manifest != "" {
+		manifest = cfg.manifest
+	}
+
+	checksums, err := loadManifest(cfg, manifest, opts.hash)
+
+	if err != nil {
+		fmt.Println("Error loading checksums:", err)
+
+		return false
+	}
+
+	matches := make([]FileChecksum, 0)
+
+	for _, checksum := range checksums {
+		if match(checksum) {
+			matches = append(matches, checksum)
+		}
+	}
+
+	data, err := json.MarshalIndent(matches, "", "  ")
+
+	if err != nil {
+		fmt.Println("Error formatting query results:", err)
+
+		return false
+	}
+
+	fmt.Println(string(data))
+
+	return true
+}
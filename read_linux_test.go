@@ -0,0 +1,143 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+	"golang.org/x/sys/unix"
+)
+
+func TestOpenScanned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a")
+
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	accessed := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+
+	if err := os.Chtimes(path, accessed, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := openScanned(path)
+
+	if err != nil {
+		t.Fatalf("openScanned() error = %v", err)
+	}
+
+	defer file.Close()
+
+	flags, err := unix.FcntlInt(file.Fd(), unix.F_GETFL, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if flags&unix.O_ACCMODE != unix.O_RDONLY {
+		t.Errorf("openScanned() access mode = %#o, want read-only", flags&unix.O_ACCMODE)
+	}
+
+	if flags&unix.O_NOATIME == 0 {
+		t.Errorf("openScanned() flags = %#o, want O_NOATIME on a file the caller owns", flags)
+	}
+
+	if _, err := file.Write([]byte("x")); err == nil {
+		t.Error("Write() on a scanned file succeeded, want it refused")
+	}
+
+	content, err := io.ReadAll(file)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "abc" {
+		t.Errorf("content = %q, want abc", content)
+	}
+
+	var stat unix.Stat_t
+
+	if err := unix.Stat(path, &stat); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := time.Unix(stat.Atim.Unix()); !got.Equal(accessed) {
+		t.Errorf("access time = %v, want %v", got, accessed)
+	}
+}
+
+func TestScanLeavesFilesUntouched(t *testing.T) {
+	rootDir := t.TempDir()
+	files := map[string][]byte{
+		"a":   []byte("abc"),
+		"d/b": bytes.Repeat([]byte("0123456789"), 100000),
+	}
+
+	// An access time older than the modification time is updated by a plain
+	// read even on relatime mounts, so only O_NOATIME keeps it.
+	accessed := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	for name, data := range files {
+		path := filepath.Join(rootDir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, accessed, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checksums, err := calculateChecksums(context.Background(), rootDir, scanOptions{hash: checksum.Options{Algorithm: "sha256", Encoding: "hex"}})
+
+	if err != nil {
+		t.Fatalf("calculateChecksums() error = %v", err)
+	}
+
+	if len(checksums) != len(files) {
+		t.Fatalf("calculateChecksums() returned %d entries, want %d", len(checksums), len(files))
+	}
+
+	for name, data := range files {
+		path := filepath.Join(rootDir, filepath.FromSlash(name))
+
+		var stat unix.Stat_t
+
+		if err := unix.Stat(path, &stat); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := time.Unix(stat.Atim.Unix()); !got.Equal(accessed) {
+			t.Errorf("%s access time = %v, want %v", name, got, accessed)
+		}
+
+		if got := time.Unix(stat.Mtim.Unix()); !got.Equal(modified) {
+			t.Errorf("%s modification time = %v, want %v", name, got, modified)
+		}
+
+		content, err := os.ReadFile(path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(content, data) {
+			t.Errorf("%s content changed", name)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+func openScanned(path string) (*os.File, error) {
+	return os.Open(path)
+}
+
+func adviseSequential(file *os.File) {}
+
+func adviseDone(file *os.File) {}
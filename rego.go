@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	defaultOPACommand = "opa"
+	regoQuery         = "data.checksum"
+)
+
+// regoDecision is what a Rego policy in package checksum decides about a
+// verification report, given to it as input:
+//
+//	package checksum
+//
+//	deny contains msg if {
+//	    some change in input.changes
+//	    change.kind == "removed"
+//	    msg := sprintf("%s was removed", [change.path])
+//	}
+//
+// Verification fails when deny holds any message; warn messages are only
+// printed.
+type regoDecision struct {
+	Deny []string `json:"deny"`
+	Warn []string `json:"warn,omitempty"`
+}
+
+type opaEvalOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+// evaluateRegoPolicy runs opa eval with the report on stdin, so the policy
+// language and its built-ins are exactly those of the installed opa.
+func evaluateRegoPolicy(ctx context.Context, opaCommand string, policy string, report verifyReport) (regoDecision, error) {
+	input, err := json.Marshal(report)
+
+	if err != nil {
+		return regoDecision{}, fmt.Errorf("failed to marshal policy input: %w", err)
+	}
+
+	command := strings.Fields(opaCommand)
+
+	if len(command) == 0 {
+		command = []string{defaultOPACommand}
+	}
+
+	args := append(command[1:], "eval", "--format", "json", "--stdin-input", "--data", policy, regoQuery)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if output := strings.TrimSpace(stderr.String() + stdout.String()); output != "" {
+			return regoDecision{}, fmt.Errorf("%s eval failed: %w: %s", command[0], err, output)
+		}
+
+		return regoDecision{}, fmt.Errorf("%s eval failed: %w", command[0], err)
+	}
+
+	var output opaEvalOutput
+
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return regoDecision{}, fmt.Errorf("failed to parse %s output: %w", command[0], err)
+	}
+
+	if len(output.Result) == 0 || len(output.Result[0].Expressions) == 0 {
+		return regoDecision{}, fmt.Errorf("policy %s does not define package checksum", policy)
+	}
+
+	var decision regoDecision
+
+	if err := json.Unmarshal(output.Result[0].Expressions[0].Value, &decision); err != nil {
+		return regoDecision{}, fmt.Errorf("policy deny and warn must be sets of strings: %w", err)
+	}
+
+	if decision.Deny == nil {
+		decision.Deny = []string{}
+	}
+
+	return decision, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultRekorURL = "https://rekor.sigstore.dev"
+
+type rekorEntry struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Spec       rekorSpec `json:"spec"`
+}
+
+type rekorSpec struct {
+	Signature struct {
+		Content   string `json:"content"`
+		PublicKey struct {
+			Content string `json:"content"`
+		} `json:"publicKey"`
+	} `json:"signature"`
+	Data struct {
+		Hash struct {
+			Algorithm string `json:"algorithm"`
+			Value     string `json:"value"`
+		} `json:"hash"`
+	} `json:"data"`
+}
+
+type rekorLogEntry struct {
+	LogIndex       int64 `json:"logIndex"`
+	IntegratedTime int64 `json:"integratedTime"`
+}
+
+// newRekorEntry signs the aggregate digest and wraps it in a hashedrekord
+// entry. The logged artifact is the listing aggregateDigest hashes, so anyone
+// holding the manifest can recompute it.
+func newRekorEntry(key crypto.Signer, digest string) (rekorEntry, error) {
+	value, ok := strings.CutPrefix(digest, "sha256:")
+
+	if !ok {
+		return rekorEntry{}, fmt.Errorf("unsupported aggregate digest %q", digest)
+	}
+
+	sum, err := hex.DecodeString(value)
+
+	if err != nil {
+		return rekorEntry{}, err
+	}
+
+	signature, err := key.Sign(rand.Reader, sum, crypto.SHA256)
+
+	if err != nil {
+		return rekorEntry{}, fmt.Errorf("failed to sign digest: %w", err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+
+	if err != nil {
+		return rekorEntry{}, err
+	}
+
+	entry := rekorEntry{APIVersion: "0.0.1", Kind: "hashedrekord"}
+	entry.Spec.Signature.Content = base64.StdEncoding.EncodeToString(signature)
+	entry.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = value
+
+	return entry, nil
+}
+
+// recordInRekor uploads the entry and returns its log index. An entry already
+// in the log is looked up instead, so reruns on an unchanged tree succeed.
+func recordInRekor(ctx context.Context, cfg config, digest string) (int64, error) {
+	entry, err := newRekorEntry(cfg.rekorSigner, digest)
+
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(entry)
+
+	if err != nil {
+		return 0, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	baseURL := strings.TrimSuffix(cfg.rekorURL, "/")
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/api/v1/log/entries", bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusConflict && response.Header.Get("Location") != "" {
+		location, err := response.Request.URL.Parse(response.Header.Get("Location"))
+
+		if err != nil {
+			return 0, err
+		}
+
+		request, err = http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+
+		if err != nil {
+			return 0, err
+		}
+
+		request.Header.Set("Accept", "application/json")
+
+		response, err = client.Do(request)
+
+		if err != nil {
+			return 0, err
+		}
+
+		defer response.Body.Close()
+	}
+
+	if response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+		return 0, fmt.Errorf("rekor responded with %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var entries map[string]rekorLogEntry
+
+	if err := json.NewDecoder(response.Body).Decode(&entries); err != nil {
+		return 0, fmt.Errorf("failed to decode rekor response: %w", err)
+	}
+
+	for _, logged := range entries {
+		return logged.LogIndex, nil
+	}
+
+	return 0, fmt.Errorf("rekor returned no entry")
+}
+
+func publishToRekor(ctx context.Context, cfg config, digest string) error {
+	index, err := recordInRekor(ctx, cfg, digest)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Recorded aggregate digest %s in %s at log index %d\n", digest, cfg.rekorURL, index)
+
+	return setActionOutput("rekor-log-index", strconv.FormatInt(index, 10))
+}
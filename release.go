@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultReleaseChecksumsName = "checksums.txt"
+
+type githubRelease struct {
+	ID        int64                `json:"id"`
+	TagName   string               `json:"tag_name"`
+	UploadURL string               `json:"upload_url"`
+	Assets    []githubReleaseAsset `json:"assets"`
+}
+
+type githubReleaseAsset struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+func (c *githubClient) releaseByTag(tag string) (*githubRelease, error) {
+	var release githubRelease
+
+	path := fmt.Sprintf("/repos/%s/releases/tags/%s", c.repository, url.PathEscape(tag))
+
+	if err := c.do(http.MethodGet, path, nil, &release); err != nil {
+		return nil, err
+	}
+
+	return &release, nil
+}
+
+// hashReleaseAsset streams the asset from GitHub into the hasher. Assets are
+// served through a redirect to storage, which net/http follows without
+// forwarding the token.
+func (c *githubClient) hashReleaseAsset(ctx context.Context, asset githubReleaseAsset, opts scanOptions) (FileChecksum, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+
+	if err != nil {
+		return FileChecksum{}, err
+	}
+
+	request.Header.Set("Accept", "application/octet-stream")
+
+	response, err := c.send(http.DefaultClient, request)
+
+	if err != nil {
+		return FileChecksum{}, err
+	}
+
+	defer response.Body.Close()
+
+	algorithm := opts.hash.AlgorithmFor(asset.Name)
+
+	digest, size, err := opts.hash.Digest(ctx, response.Body, algorithm)
+
+	if err != nil {
+		return FileChecksum{}, err
+	}
+
+	return FileChecksum{
+		Path:      asset.Name,
+		Checksum:  opts.hash.Encode(algorithm, digest),
+		Algorithm: algorithm,
+		Size:      size,
+	}, nil
+}
+
+func (c *githubClient) uploadReleaseAsset(ctx context.Context, release *githubRelease, name string, data []byte) error {
+	uploadURL, _, _ := strings.Cut(release.UploadURL, "{")
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL+"?name="+url.QueryEscape(name), bytes.NewReader(data))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	response, err := c.send(http.DefaultClient, request)
+
+	if err != nil {
+		return err
+	}
+
+	return response.Body.Close()
+}
+
+// publishReleaseChecksums hashes every asset of the release and uploads the
+// result as a goreleaser style checksums file, replacing an earlier one.
+func publishReleaseChecksums(ctx context.Context, client *githubClient, tag string, name string, opts scanOptions) (int, error) {
+	release, err := client.releaseByTag(tag)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up release %s: %w", tag, err)
+	}
+
+	var (
+		checksums []FileChecksum
+		previous  *githubReleaseAsset
+	)
+
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			previous = &asset
+
+			continue
+		}
+
+		entry, err := client.hashReleaseAsset(ctx, asset, opts)
+
+		if err != nil {
+			return 0, fmt.Errorf("failed to hash asset %s: %w", asset.Name, err)
+		}
+
+		checksums = append(checksums, entry)
+	}
+
+	data, err := formatSums(checksums)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if previous != nil {
+		path := fmt.Sprintf("/repos/%s/releases/assets/%d", client.repository, previous.ID)
+
+		if err := client.do(http.MethodDelete, path, nil, nil); err != nil {
+			return 0, fmt.Errorf("failed to delete previous %s: %w", name, err)
+		}
+	}
+
+	if err := client.uploadReleaseAsset(ctx, release, name, data); err != nil {
+		return 0, fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+
+	return len(checksums), nil
+}
+
+func runReleaseChecksums(ctx context.Context, cfg config, opts scanOptions) bool {
+	if len(cfg.args) != 1 {
+		fmt.Println("Error publishing release checksums: release-checksums expects a release tag")
+
+		return false
+	}
+
+	if err := validateSumsOptions(opts.hash); err != nil {
+		fmt.Println("Error configuring output format:", err)
+
+		return false
+	}
+
+	client, err := newGithubClient(cfg.githubToken)
+
+	if err != nil {
+		fmt.Println("Error configuring GitHub client:", err)
+
+		return false
+	}
+
+	count, err := publishReleaseChecksums(ctx, client, cfg.args[0], cfg.releaseChecksums, opts)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println("Publishing interrupted:", interruptReason(ctx))
+
+			return false
+		}
+
+		fmt.Println("Error publishing release checksums:", err)
+
+		return false
+	}
+
+	fmt.Printf("Uploaded %s with checksums of %d assets to release %s\n", cfg.releaseChecksums, count, cfg.args[0])
+
+	return true
+}
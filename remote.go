@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const defaultSSHCommand = "ssh"
+
+func parseRemoteTarget(target string) (string, string, error) {
+	host, dir, found := strings.Cut(target, ":")
+
+	if !found || host == "" || dir == "" {
+		return "", "", fmt.Errorf("invalid remote %q, expected [user@]host:/path", target)
+	}
+
+	return host, dir, nil
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// hashRemote streams the remote directory as a tar archive over SSH and hashes
+// it locally, so nothing has to be installed on the remote host.
+func hashRemote(ctx context.Context, sshCommand string, target string, opts scanOptions, algorithms map[string]string) ([]FileChecksum, error) {
+	host, dir, err := parseRemoteTarget(target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	command := strings.Fields(sshCommand)
+
+	if len(command) == 0 {
+		command = []string{defaultSSHCommand}
+	}
+
+	args := append(command[1:], host, "tar -C "+shellQuote(dir)+" -cf - .")
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command[0], err)
+	}
+
+	checksums, err := hashArchive(ctx, stdout, opts, algorithms)
+
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+
+		return nil, err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("remote command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return checksums, nil
+}
+
+func runCompareRemote(ctx context.Context, cfg config, projectDir string, opts scanOptions) bool {
+	if len(cfg.args) != 1 {
+		fmt.Println("Error comparing remote: compare-remote expects [user@]host:/path")
+
+		return false
+	}
+
+	local, err := calculateChecksums(ctx, projectDir, opts)
+
+	if err != nil {
+		fmt.Println("Error calculating checksums:", err)
+
+		return false
+	}
+
+	algorithms := make(map[string]string, len(local))
+
+	for _, entry := range local {
+		algorithms[entry.Path] = entry.Algorithm
+	}
+
+	remote, err := hashRemote(ctx, cfg.sshCommand, cfg.args[0], opts, algorithms)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println("Comparison interrupted:", interruptReason(ctx))
+
+			return false
+		}
+
+		fmt.Println("Error hashing remote:", err)
+
+		return false
+	}
+
+	return reportVerification(ctx, cfg, cfg.args[0], compareChecksums(local, remote), len(local))
+}
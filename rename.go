@@ -0,0 +1,47 @@
+package main
+
+// renames holds the removed changes by digest, so an added file with the same
+// content can claim one and be reported once as renamed instead of as a
+// removal and an addition.
+type renames map[string][]int
+
+func renameKey(algorithm string, digest string) string {
+	return algorithm + ":" + digest
+}
+
+// add records that the change at index removed a file with digest.
+func (r renames) add(algorithm string, digest string, index int) {
+	if digest == "" {
+		return
+	}
+
+	key := renameKey(algorithm, digest)
+	r[key] = append(r[key], index)
+}
+
+// claim returns the index of a removed change with digest not claimed yet.
+func (r renames) claim(algorithm string, digest string) (int, bool) {
+	key := renameKey(algorithm, digest)
+	indexes := r[key]
+
+	if len(indexes) == 0 {
+		return 0, false
+	}
+
+	r[key] = indexes[1:]
+
+	return indexes[0], true
+}
+
+// renamedChange combines the removal and the addition of the same content
+// into a rename from the removed path to the added one.
+func renamedChange(removed fileChange, added fileChange) fileChange {
+	added.Kind = changeRenamed
+	added.From = removed.Path
+	added.Expected = removed.Expected
+	added.ExpectedSize = removed.ExpectedSize
+	added.SizeDelta = added.ActualSize - removed.ExpectedSize
+	added.Tags = mergeTags(removed.Tags, added.Tags)
+
+	return added
+}
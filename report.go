@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+const defaultReportFormat = "json"
+
+type verifySummary struct {
+	Expected  int `json:"expected"`
+	Unchanged int `json:"unchanged"`
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Modified  int `json:"modified"`
+	Errors    int `json:"errors"`
+	Filtered  int `json:"filtered"`
+	Unlisted  int `json:"unlisted"`
+	Renamed   int `json:"renamed"`
+	// Warnings counts the changes above that do not fail verification.
+	Warnings int `json:"warnings,omitempty"`
+	// Ignored counts the changes left out of the report by the severity
+	// policies.
+	Ignored int `json:"ignored,omitempty"`
+	// Unsampled counts the unchanged entries outside the -sample, which were
+	// only checked for presence.
+	Unsampled int `json:"unsampled,omitempty"`
+	// Stopped is set when -fail-fast ended verification early, so the counts
+	// above leave out the files after the first failure.
+	Stopped bool `json:"stopped,omitempty"`
+	// HighEntropy counts the changes whose new content looks packed or
+	// encrypted.
+	HighEntropy int `json:"highEntropy,omitempty"`
+	// KnownGood counts the changes whose new content is in the -known-good
+	// hash sets.
+	KnownGood int `json:"knownGood,omitempty"`
+	// Empty counts the changed and new files found empty outside the
+	// -allow-empty patterns.
+	Empty int `json:"empty,omitempty"`
+}
+
+type verifyReport struct {
+	Root    string        `json:"root,omitempty"`
+	Summary verifySummary `json:"summary"`
+	Stats   verifyStats   `json:"stats"`
+	Changes []fileChange  `json:"changes"`
+	// Policy is the decision of the -rego-policy, which replaces the severity
+	// policies in deciding whether verification passes.
+	Policy *regoDecision `json:"policy,omitempty"`
+
+	unchanged []string
+}
+
+func newVerifyReport(root string, result verifyResult, expected int) verifyReport {
+	changes := result.changes
+
+	summary := verifySummary{
+		Expected:  expected,
+		Unchanged: len(result.unchanged),
+		Ignored:   result.ignored,
+		Unsampled: result.unsampled,
+		Stopped:   result.stopped,
+	}
+
+	for _, change := range changes {
+		if change.Warning {
+			summary.Warnings++
+		}
+
+		if change.HighEntropy {
+			summary.HighEntropy++
+		}
+
+		if change.KnownGood != "" {
+			summary.KnownGood++
+		}
+
+		if change.Empty {
+			summary.Empty++
+		}
+
+		switch change.Kind {
+		case changeAdded:
+			summary.Added++
+		case changeRemoved:
+			summary.Removed++
+		case changeModified:
+			summary.Modified++
+		case changeError:
+			summary.Errors++
+		case changeFiltered:
+			summary.Filtered++
+		case changeUnlisted:
+			summary.Unlisted++
+		case changeRenamed:
+			summary.Renamed++
+		}
+	}
+
+	if changes == nil {
+		changes = []fileChange{}
+	}
+
+	return verifyReport{
+		Root:    filepath.Clean(root),
+		Summary: summary,
+		Stats:   newVerifyStats(result),
+		Changes: changes,
+
+		unchanged: result.unchanged,
+	}
+}
+
+func printReport(report verifyReport) {
+	if len(report.Changes) > 0 {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		tagged := false
+
+		for _, change := range report.Changes {
+			tagged = tagged || len(change.Tags) > 0
+		}
+
+		if tagged {
+			fmt.Fprintln(writer, "KIND\tPATH\tSIZE DELTA\tTAGS")
+		} else {
+			fmt.Fprintln(writer, "KIND\tPATH\tSIZE DELTA")
+		}
+
+		for _, change := range report.Changes {
+			kind := string(change.Kind)
+
+			if change.Warning {
+				kind += " (warning)"
+			}
+
+			if change.Similarity != nil {
+				kind += fmt.Sprintf(" (%.0f%% similar)", *change.Similarity*100)
+			}
+
+			path := change.Path
+
+			if change.Kind == changeRenamed {
+				path = change.From + " -> " + change.Path
+			}
+
+			if !tagged {
+				fmt.Fprintf(writer, "%s\t%s\t%+d\n", kind, path, change.SizeDelta)
+
+				continue
+			}
+
+			fmt.Fprintf(writer, "%s\t%s\t%+d\t%s\n", kind, path, change.SizeDelta, strings.Join(change.Tags, ","))
+		}
+
+		writer.Flush()
+	}
+
+	summary := report.Summary
+
+	fmt.Printf(
+		"Verified %d files: %d unchanged, %d modified, %d removed, %d added, %d errors\n",
+		summary.Expected, summary.Unchanged, summary.Modified, summary.Removed, summary.Added, summary.Errors,
+	)
+
+	if summary.Renamed > 0 {
+		fmt.Printf("%d files were renamed or moved with their content unchanged\n", summary.Renamed)
+	}
+
+	if summary.Warnings > 0 {
+		fmt.Printf("%d changes only warn under the severity policies\n", summary.Warnings)
+	}
+
+	if summary.HighEntropy > 0 {
+		var paths []string
+
+		for _, change := range report.Changes {
+			if change.HighEntropy {
+				paths = append(paths, change.Path)
+			}
+		}
+
+		fmt.Printf("%d changed files look packed or encrypted: %s\n", summary.HighEntropy, strings.Join(paths, ", "))
+	}
+
+	if summary.KnownGood > 0 {
+		fmt.Printf("%d changed files match known-good hash sets\n", summary.KnownGood)
+	}
+
+	if summary.Empty > 0 {
+		var truncated, added []string
+
+		for _, change := range report.Changes {
+			switch {
+			case !change.Empty:
+			case change.Kind == changeAdded || change.Kind == changeUnlisted:
+				added = append(added, change.Path)
+			default:
+				truncated = append(truncated, change.Path)
+			}
+		}
+
+		if len(truncated) > 0 {
+			fmt.Printf("%d changed files are now empty, possibly truncated: %s\n", len(truncated), listEmpty(truncated))
+		}
+
+		if len(added) > 0 {
+			fmt.Printf("%d new files are empty: %s\n", len(added), listEmpty(added))
+		}
+	}
+
+	for _, change := range report.Changes {
+		for _, hit := range change.Secrets {
+			fmt.Printf("Possible secret in %s:%d (%s)\n", change.Path, hit.Line, hit.Rule)
+		}
+	}
+
+	if summary.Stopped {
+		fmt.Println("Stopped at the first failing change (-fail-fast), the remaining files were not checked")
+	}
+
+	if summary.Unsampled > 0 {
+		fmt.Printf("%d entries outside the sample were only checked for presence\n", summary.Unsampled)
+	}
+
+	if summary.Ignored > 0 {
+		fmt.Printf("%d changes are ignored by the severity policies\n", summary.Ignored)
+	}
+
+	if report.Policy != nil {
+		for _, message := range report.Policy.Deny {
+			fmt.Println("Policy denied:", message)
+		}
+
+		for _, message := range report.Policy.Warn {
+			fmt.Println("Policy warning:", message)
+		}
+	}
+
+	if summary.Filtered > 0 || summary.Unlisted > 0 {
+		fmt.Printf(
+			"Coverage: %d manifest entries are filtered out by the current rules, %d files older than the manifest are not listed in it\n",
+			summary.Filtered, summary.Unlisted,
+		)
+	}
+
+	printStats(report.Stats)
+}
+
+func formatReport(report verifyReport, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(report, "", "  ")
+	case "sarif":
+		return formatSARIF(report)
+	case "junit":
+		return formatJUnit(report)
+	case "cef":
+		return formatCEF(report)
+	case "leef":
+		return formatLEEF(report)
+	}
+
+	return nil, fmt.Errorf("unsupported report format: %s", format)
+}
+
+func saveReport(report verifyReport, reportFile string, format string) error {
+	reportData, err := formatReport(report, format)
+
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(reportFile, reportData, 0644); err != nil {
+		return fmt.Errorf("failed to write report to file: %w", err)
+	}
+
+	return nil
+}
+
+func describeChange(change fileChange) string {
+	switch change.Kind {
+	case changeModified:
+		if change.Similarity != nil {
+			return fmt.Sprintf("%s was modified: expected %s, got %s (%+d bytes, %.0f%% similar)", change.Path, change.Expected, change.Actual, change.SizeDelta, *change.Similarity*100)
+		}
+
+		return fmt.Sprintf("%s was modified: expected %s, got %s (%+d bytes)", change.Path, change.Expected, change.Actual, change.SizeDelta)
+	case changeRemoved:
+		return fmt.Sprintf("%s was removed: expected %s", change.Path, change.Expected)
+	case changeAdded:
+		return fmt.Sprintf("%s was added: got %s", change.Path, change.Actual)
+	case changeRenamed:
+		return fmt.Sprintf("%s was renamed to %s: got %s", change.From, change.Path, change.Actual)
+	case changeFiltered:
+		return fmt.Sprintf("%s is in the manifest but filtered out by the current ignore rules", change.Path)
+	case changeUnlisted:
+		return fmt.Sprintf("%s predates the manifest but is not listed in it, check the ignore rules used to generate it", change.Path)
+	}
+
+	return fmt.Sprintf("%s could not be verified: %s", change.Path, change.Error)
+}
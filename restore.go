@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+type restoreEntry struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Error string `json:"error,omitempty"`
+}
+
+// restoreReport describes how much of a backup manifest a restored tree holds.
+// Every entry is checked for presence; only the sampled ones are hashed.
+type restoreReport struct {
+	Root          string         `json:"root"`
+	Files         int            `json:"files"`
+	Bytes         int64          `json:"bytes"`
+	Present       int            `json:"present"`
+	Sampled       int            `json:"sampled"`
+	Verified      int            `json:"verified"`
+	VerifiedBytes int64          `json:"verifiedBytes"`
+	Missing       []restoreEntry `json:"missing"`
+	Corrupted     []restoreEntry `json:"corrupted"`
+	Errors        []restoreEntry `json:"errors"`
+}
+
+func (r restoreReport) passed() bool {
+	return len(r.Missing) == 0 && len(r.Corrupted) == 0 && len(r.Errors) == 0
+}
+
+func runVerifyRestore(ctx context.Context, cfg config, projectDir string, checksumsFilePath string, opts scanOptions) bool {
+	if opts.pathKey != nil {
+		fmt.Println("Error verifying restore: manifests with hashed paths cannot be mapped to restored files")
+
+		return false
+	}
+
+	if cfg.reportFile != "" && cfg.reportFormat != "json" {
+		fmt.Println("Error verifying restore: verify-restore only writes json reports")
+
+		return false
+	}
+
+	manifest := checksumsFilePath
+
+	if cfg.manifest != "" {
+		manifest = cfg.manifest
+	}
+
+	expected, err := loadExpected(cfg, manifest, opts.hash)
+
+	if err != nil {
+		fmt.Println("Error loading checksums:", err)
+
+		return false
+	}
+
+	report, err := verifyRestore(ctx, projectDir, expected, opts, chooseSample(cfg, expected))
+
+	if err != nil {
+		fmt.Println("Error verifying restore:", err)
+
+		return false
+	}
+
+	printRestoreReport(report)
+
+	if cfg.reportFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+
+		if err == nil {
+			err = os.WriteFile(cfg.reportFile, append(data, '\n'), 0644)
+		}
+
+		if err != nil {
+			fmt.Println("Error saving report:", err)
+		}
+	}
+
+	return report.passed()
+}
+
+func verifyRestore(ctx context.Context, rootDir string, expected []FileChecksum, opts scanOptions, sampled map[string]bool) (restoreReport, error) {
+	report := restoreReport{
+		Root:      rootDir,
+		Files:     len(expected),
+		Missing:   []restoreEntry{},
+		Corrupted: []restoreEntry{},
+		Errors:    []restoreEntry{},
+	}
+
+	for _, entry := range expected {
+		if ctx.Err() != nil {
+			return restoreReport{}, ctx.Err()
+		}
+
+		report.Bytes += entry.Size
+
+		path := filepath.Join(rootDir, filepath.FromSlash(entry.Path))
+
+		info, err := os.Stat(path)
+
+		if err != nil {
+			report.Missing = append(report.Missing, restoreEntry{Path: entry.Path, Size: entry.Size})
+
+			continue
+		}
+
+		// Empty directories and special files are recorded by type, and only
+		// need to be there as what they were.
+		if restored, recorded := checksum.FileType(info.Mode()), restoredType(entry); restored != recorded {
+			report.Errors = append(report.Errors, restoreEntry{Path: entry.Path, Size: entry.Size, Error: fmt.Sprintf("restored as %s, recorded as %s", restored, recorded)})
+
+			continue
+		}
+
+		report.Present++
+
+		if sampled != nil && !sampled[filepath.ToSlash(entry.Path)] {
+			continue
+		}
+
+		report.Sampled++
+
+		if entry.PresenceOnly {
+			report.Verified++
+			report.VerifiedBytes += entry.Size
+
+			continue
+		}
+
+		digest, size, err := opts.digest(ctx, path, entry.Algorithm)
+
+		if ctx.Err() != nil {
+			return restoreReport{}, ctx.Err()
+		}
+
+		if err != nil {
+			report.Errors = append(report.Errors, restoreEntry{Path: entry.Path, Size: entry.Size, Error: err.Error()})
+
+			continue
+		}
+
+		if opts.hash.Encode(entry.Algorithm, digest) != entry.Checksum {
+			report.Corrupted = append(report.Corrupted, restoreEntry{Path: entry.Path, Size: size})
+
+			continue
+		}
+
+		report.Verified++
+		report.VerifiedBytes += size
+	}
+
+	// The largest missing files are the ones most worth restoring again first.
+	sort.SliceStable(report.Missing, func(i, j int) bool {
+		return report.Missing[i].Size > report.Missing[j].Size
+	})
+
+	return report, nil
+}
+
+// restoredType is the type a restored entry must have: the one -empty-dirs
+// or -record-type recorded, and a regular file otherwise. Symlinks are
+// followed like verification follows them.
+func restoredType(entry FileChecksum) string {
+	if entry.Type == "" || entry.Type == checksum.TypeSymlink {
+		return checksum.TypeRegular
+	}
+
+	return entry.Type
+}
+
+func printRestoreReport(report restoreReport) {
+	if len(report.Missing) > 0 || len(report.Corrupted) > 0 || len(report.Errors) > 0 {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(writer, "STATUS\tPATH\tSIZE")
+
+		for _, entry := range report.Missing {
+			fmt.Fprintf(writer, "missing\t%s\t%d\n", entry.Path, entry.Size)
+		}
+
+		for _, entry := range report.Corrupted {
+			fmt.Fprintf(writer, "corrupted\t%s\t%d\n", entry.Path, entry.Size)
+		}
+
+		for _, entry := range report.Errors {
+			fmt.Fprintf(writer, "error\t%s\t%d (%s)\n", entry.Path, entry.Size, entry.Error)
+		}
+
+		writer.Flush()
+	}
+
+	fmt.Printf(
+		"Restored %d of %d files (%.1f%%): %d missing, %d corrupted, %d errors\n",
+		report.Present, report.Files, percentage(int64(report.Present), int64(report.Files)),
+		len(report.Missing), len(report.Corrupted), len(report.Errors),
+	)
+
+	fmt.Printf(
+		"Verified %d files (%.1f%%) and %d bytes (%.1f%%) of the backup\n",
+		report.Verified, percentage(int64(report.Verified), int64(report.Files)),
+		report.VerifiedBytes, percentage(report.VerifiedBytes, report.Bytes),
+	)
+
+	if report.Sampled < report.Present {
+		fmt.Printf("Hashed a sample of %d of %d restored files\n", report.Sampled, report.Present)
+	}
+}
+
+func percentage(part int64, total int64) float64 {
+	if total == 0 {
+		return 100
+	}
+
+	return float64(part) * 100 / float64(total)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sampleSpec is a -sample value, either a percentage of the manifest entries
+// like 5% or a number of entries like 1000.
+type sampleSpec struct {
+	percent float64
+	count   int
+}
+
+func parseSample(value string) (sampleSpec, error) {
+	if value == "" {
+		return sampleSpec{}, nil
+	}
+
+	if number, ok := strings.CutSuffix(value, "%"); ok {
+		percent, err := strconv.ParseFloat(number, 64)
+
+		if err != nil || percent <= 0 || percent > 100 {
+			return sampleSpec{}, fmt.Errorf("invalid sample %q, expected a percentage above 0%% and at most 100%%", value)
+		}
+
+		return sampleSpec{percent: percent}, nil
+	}
+
+	count, err := strconv.Atoi(value)
+
+	if err != nil || count <= 0 {
+		return sampleSpec{}, fmt.Errorf("invalid sample %q, expected a percentage like 5%% or a number of entries", value)
+	}
+
+	return sampleSpec{count: count}, nil
+}
+
+func (s sampleSpec) enabled() bool {
+	return s.percent > 0 || s.count > 0
+}
+
+func (s sampleSpec) size(entries int) int {
+	if s.count > 0 {
+		return min(s.count, entries)
+	}
+
+	return min(int(math.Ceil(float64(entries)*s.percent/100)), entries)
+}
+
+// chooseSample picks the manifest paths whose digests are checked, nil when
+// every entry is. The seed is printed, so giving it to -sample-seed checks
+// the same entries again as long as the manifest does not change.
+func chooseSample(cfg config, entries []FileChecksum) map[string]bool {
+	if !cfg.sampling.enabled() {
+		return nil
+	}
+
+	seed := cfg.sampleSeed
+
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	random := rand.New(rand.NewPCG(seed, seed))
+	sampled := make(map[string]bool)
+
+	for _, index := range random.Perm(len(entries))[:cfg.sampling.size(len(entries))] {
+		sampled[filepath.ToSlash(entries[index].Path)] = true
+	}
+
+	fmt.Printf("Sampling %d of %d entries with -sample-seed %d\n", len(sampled), len(entries), seed)
+
+	return sampled
+}
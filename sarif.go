@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"path"
+	"path/filepath"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+var sarifRules = []sarifRule{
+	{ID: "checksum/modified", Name: "FileModified", ShortDescription: sarifMessage{Text: "File content does not match the recorded checksum"}},
+	{ID: "checksum/removed", Name: "FileRemoved", ShortDescription: sarifMessage{Text: "File recorded in the manifest is missing"}},
+	{ID: "checksum/added", Name: "FileAdded", ShortDescription: sarifMessage{Text: "File is not recorded in the manifest"}},
+	{ID: "checksum/error", Name: "FileError", ShortDescription: sarifMessage{Text: "File could not be verified"}},
+	{ID: "checksum/filtered", Name: "FileFiltered", ShortDescription: sarifMessage{Text: "File recorded in the manifest is filtered out by the current rules"}},
+	{ID: "checksum/renamed", Name: "FileRenamed", ShortDescription: sarifMessage{Text: "File recorded in the manifest moved to another path"}},
+	{ID: "checksum/unlisted", Name: "FileUnlisted", ShortDescription: sarifMessage{Text: "File predating the manifest is not recorded in it"}},
+}
+
+func formatSARIF(report verifyReport) ([]byte, error) {
+	results := make([]sarifResult, 0, len(report.Changes))
+
+	for _, change := range report.Changes {
+		location := sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{
+				URI: path.Join(filepath.ToSlash(report.Root), filepath.ToSlash(change.Path)),
+			},
+		}
+
+		if change.Kind != changeRemoved {
+			location.Region = &sarifRegion{StartLine: 1}
+		}
+
+		results = append(results, sarifResult{
+			RuleID:    "checksum/" + string(change.Kind),
+			Level:     sarifLevel(change),
+			Message:   sarifMessage{Text: describeChange(change)},
+			Locations: []sarifLocation{{PhysicalLocation: location}},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "checksum-action",
+						InformationURI: "https://github.com/edvinaskrucas/checksum-action",
+						Rules:          sarifRules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+
+	return json.MarshalIndent(log, "", "  ")
+}
+
+func sarifLevel(change fileChange) string {
+	if change.Warning {
+		return "warning"
+	}
+
+	switch change.Kind {
+	case changeAdded, changeFiltered, changeUnlisted, changeRenamed:
+		return "warning"
+	}
+
+	return "error"
+}
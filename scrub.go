@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scrubState records when each manifest entry was last hashed and found
+// unchanged, so -scrub-older-than only hashes the entries not verified
+// recently.
+type scrubState struct {
+	Verified map[string]time.Time `json:"verified"`
+}
+
+func scrubStatePath(outputFile string) string {
+	return outputFile + ".scrub.json"
+}
+
+// parseScrubAge reads a -scrub-older-than value, a duration that may also be
+// given in days, like 30d.
+func parseScrubAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if number, ok := strings.CutSuffix(value, "d"); ok {
+		days, err := strconv.Atoi(number)
+
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid -scrub-older-than %q, expected a number of days like 30d or a duration like 12h", value)
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(value)
+
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid -scrub-older-than %q, expected a number of days like 30d or a duration like 12h", value)
+	}
+
+	return age, nil
+}
+
+func loadScrubState(path string) (scrubState, error) {
+	state := scrubState{Verified: make(map[string]time.Time)}
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+
+	if err != nil {
+		return scrubState{}, fmt.Errorf("failed to read scrub state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return scrubState{}, fmt.Errorf("failed to parse scrub state: %w", err)
+	}
+
+	if state.Verified == nil {
+		state.Verified = make(map[string]time.Time)
+	}
+
+	return state, nil
+}
+
+// chooseScrub picks the entries not verified within -scrub-older-than, the
+// longest unverified first. A -sample caps how many are hashed per run, so
+// scrubbing a whole archive is spread over many runs.
+func chooseScrub(cfg config, expected []FileChecksum, state scrubState) map[string]bool {
+	cutoff := time.Now().Add(-cfg.scrubAge)
+
+	var due []string
+
+	for _, entry := range expected {
+		key := filepath.ToSlash(entry.Path)
+
+		if !entry.PresenceOnly && state.Verified[key].Before(cutoff) {
+			due = append(due, key)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return state.Verified[due[i]].Before(state.Verified[due[j]])
+	})
+
+	if cfg.sampling.enabled() {
+		due = due[:min(len(due), cfg.sampling.size(len(expected)))]
+	}
+
+	scrubbed := make(map[string]bool, len(due))
+
+	for _, key := range due {
+		scrubbed[key] = true
+	}
+
+	fmt.Printf("Scrubbing %d of %d entries not verified within %s\n", len(scrubbed), len(expected), cfg.scrubOlderThan)
+
+	return scrubbed
+}
+
+// recordScrub marks the entries hashed and found unchanged as verified now
+// and forgets the entries no longer in the manifest.
+func recordScrub(path string, state scrubState, expected []FileChecksum, verified []string) error {
+	now := time.Now().UTC()
+	next := scrubState{Verified: make(map[string]time.Time, len(expected))}
+
+	for _, entry := range expected {
+		key := filepath.ToSlash(entry.Path)
+
+		if at, ok := state.Verified[key]; ok {
+			next.Verified[key] = at
+		}
+	}
+
+	for _, key := range verified {
+		next.Verified[key] = now
+	}
+
+	data, err := json.MarshalIndent(next, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
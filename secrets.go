@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// secretPatterns are the credential formats -scan-secrets looks for. They are
+// specific enough to rarely match anything else; no pattern matches more than
+// secretOverlap bytes.
+var secretPatterns = []struct {
+	rule    string
+	pattern *regexp.Regexp
+}{
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED |PGP )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abprs]-[0-9A-Za-z-]{10,72}`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+}
+
+// secretOverlap is how much of the previous write is searched again, so
+// secrets split across two writes are still found.
+const secretOverlap = 128
+
+// maxSecretHits bounds the hits recorded for a single file.
+const maxSecretHits = 20
+
+// secretInspector finds credentials in a file while it is hashed.
+type secretInspector struct {
+	carry []byte
+	// carryOffset and carryLine locate the start of carry in the file.
+	carryOffset int64
+	carryLine   int
+	seen        map[int64]bool
+	hits        []checksum.SecretHit
+}
+
+func newSecretInspector() *secretInspector {
+	return &secretInspector{carryLine: 1, seen: make(map[int64]bool)}
+}
+
+func (i *secretInspector) Write(p []byte) (int, error) {
+	window := append(i.carry, p...)
+
+	for _, secret := range secretPatterns {
+		for _, match := range secret.pattern.FindAllIndex(window, -1) {
+			// Matches ending in the carry were searched with the previous write.
+			if match[1] <= len(i.carry) || len(i.hits) >= maxSecretHits || i.seen[i.carryOffset+int64(match[0])] {
+				continue
+			}
+
+			i.seen[i.carryOffset+int64(match[0])] = true
+			i.hits = append(i.hits, checksum.SecretHit{
+				Rule: secret.rule,
+				Line: i.carryLine + bytes.Count(window[:match[0]], []byte("\n")),
+			})
+		}
+	}
+
+	keep := min(len(window), secretOverlap)
+	consumed := window[:len(window)-keep]
+
+	i.carryLine += bytes.Count(consumed, []byte("\n"))
+	i.carryOffset += int64(len(consumed))
+	i.carry = append([]byte{}, window[len(consumed):]...)
+
+	return len(p), nil
+}
+
+func (i *secretInspector) record(entry *FileChecksum) {
+	entry.Secrets = i.hits
+}
+
+// printSecrets lists the possible credentials found in checksums.
+func printSecrets(checksums []FileChecksum) {
+	hits := 0
+
+	for _, entry := range checksums {
+		for _, hit := range entry.Secrets {
+			fmt.Printf("Possible secret in %s:%d (%s)\n", entry.Path, hit.Line, hit.Rule)
+			hits++
+		}
+	}
+
+	if hits > 0 {
+		fmt.Printf("Found %d possible secrets, the manifest records where\n", hits)
+	}
+}
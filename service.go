@@ -0,0 +1,224 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultServiceName     = "checksum"
+	defaultServiceDir      = "/etc/systemd/system"
+	defaultServiceSchedule = "daily"
+	// serviceModeScan runs the configured scan or verification on a timer.
+	serviceModeScan = "scan"
+	// serviceModeFIM runs the fim command as a long-running service.
+	serviceModeFIM = "fim"
+)
+
+// serviceFlags configure the install-service command itself and are left out
+// of the units it writes.
+var serviceFlags = []string{"service-name", "service-dir", "service-schedule", "service-mode"}
+
+func validateServiceMode(mode string) error {
+	switch mode {
+	case serviceModeScan, serviceModeFIM:
+		return nil
+	default:
+		return fmt.Errorf("unsupported service mode: %s", mode)
+	}
+}
+
+// runInstallService writes systemd units running the executable with the
+// flags of this run, given or taken from the environment. Secret flags go to
+// an environment file only root can read, since unit files are world
+// readable. On Windows it registers a fim service instead.
+func runInstallService(cfg config) bool {
+	if err := validateServiceMode(cfg.serviceMode); err != nil {
+		fmt.Println("Error validating flags:", err)
+
+		return false
+	}
+
+	executable, err := os.Executable()
+
+	if err != nil {
+		fmt.Println("Error locating executable:", err)
+
+		return false
+	}
+
+	workingDir, err := os.Getwd()
+
+	if err != nil {
+		fmt.Println("Error locating working directory:", err)
+
+		return false
+	}
+
+	rootDir, err := filepath.Abs(cfg.rootDir)
+
+	if err != nil {
+		fmt.Println("Error generating project dir:", err)
+
+		return false
+	}
+
+	command := []string{executable}
+
+	if cfg.serviceMode == serviceModeFIM {
+		command = append(command, "fim")
+	}
+
+	command = append(command, "-dir="+rootDir)
+
+	var secrets []string
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "dir" || containsString(serviceFlags, f.Name) {
+			return
+		}
+
+		if redactedValue(f.Name, f.Value.String()) != f.Value.String() {
+			secrets = append(secrets, fmt.Sprintf("%s=%s", envName(f.Name), quoteEnvironmentValue(f.Value.String())))
+
+			return
+		}
+
+		command = append(command, fmt.Sprintf("-%s=%s", f.Name, f.Value.String()))
+	})
+
+	if runtime.GOOS == "windows" {
+		return installService(cfg, command, secrets)
+	}
+
+	unitPath := filepath.Join(cfg.serviceDir, cfg.serviceName+".service")
+	envPath := filepath.Join(cfg.serviceDir, cfg.serviceName+".env")
+
+	var unit strings.Builder
+
+	fmt.Fprintf(&unit, "[Unit]\nDescription=Checksum %s of %s\n\n[Service]\n", cfg.serviceMode, rootDir)
+
+	if cfg.serviceMode == serviceModeFIM {
+		unit.WriteString("Type=simple\nRestart=on-failure\nRestartSec=10\n")
+	} else {
+		unit.WriteString("Type=oneshot\n")
+	}
+
+	fmt.Fprintf(&unit, "WorkingDirectory=%s\n", workingDir)
+
+	if len(secrets) > 0 {
+		fmt.Fprintf(&unit, "EnvironmentFile=%s\n", envPath)
+	}
+
+	fmt.Fprintf(&unit, "ExecStart=%s\n", quoteExecStart(command))
+
+	if cfg.serviceMode == serviceModeFIM {
+		unit.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+	}
+
+	files := map[string]string{unitPath: unit.String()}
+
+	if cfg.serviceMode == serviceModeScan {
+		timerPath := filepath.Join(cfg.serviceDir, cfg.serviceName+".timer")
+		files[timerPath] = fmt.Sprintf(
+			"[Unit]\nDescription=Scheduled checksum scan of %s\n\n[Timer]\nOnCalendar=%s\nPersistent=true\n\n[Install]\nWantedBy=timers.target\n",
+			rootDir, cfg.serviceSchedule,
+		)
+	}
+
+	paths := make([]string, 0, len(files))
+
+	for path := range files {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte(files[path]), 0644); err != nil {
+			fmt.Println("Error writing unit:", err)
+
+			return false
+		}
+
+		fmt.Println("Wrote", path)
+	}
+
+	if len(secrets) > 0 {
+		if err := os.WriteFile(envPath, []byte(strings.Join(secrets, "\n")+"\n"), 0600); err != nil {
+			fmt.Println("Error writing environment file:", err)
+
+			return false
+		}
+
+		fmt.Println("Wrote", envPath)
+	}
+
+	unitName := cfg.serviceName + ".service"
+
+	if cfg.serviceMode == serviceModeScan {
+		unitName = cfg.serviceName + ".timer"
+	}
+
+	fmt.Printf("Enable with: systemctl daemon-reload && systemctl enable --now %s\n", unitName)
+
+	return true
+}
+
+// installService registers command as a Windows service. Services start in
+// the system directory, so relative paths in the flags other than -dir and
+// the output file resolve there.
+func installService(cfg config, command []string, secrets []string) bool {
+	if cfg.serviceMode != serviceModeFIM {
+		fmt.Println("Error validating flags: Windows services run the fim command and require -service-mode fim")
+
+		return false
+	}
+
+	if len(secrets) > 0 {
+		fmt.Println("Error validating flags: secret flags would be stored in the service command line, set them as CHECKSUM_* system environment variables instead")
+
+		return false
+	}
+
+	args := append(command[1:], "-service-name="+cfg.serviceName)
+
+	if err := installWindowsService(cfg.serviceName, command[0], args); err != nil {
+		fmt.Println("Error installing service:", err)
+
+		return false
+	}
+
+	fmt.Println("Registered Windows service", cfg.serviceName)
+	fmt.Printf("Start with: sc start %s\n", cfg.serviceName)
+
+	return true
+}
+
+// quoteExecStart renders command as an ExecStart line, quoting the words
+// systemd would otherwise split or expand.
+func quoteExecStart(command []string) string {
+	words := make([]string, len(command))
+
+	for i, word := range command {
+		word = strings.ReplaceAll(word, "%", "%%")
+		word = strings.ReplaceAll(word, "$", "$$")
+
+		if strings.ContainsAny(word, " \t\"'\\;") || word == "" {
+			word = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(word) + `"`
+		}
+
+		words[i] = word
+	}
+
+	return strings.Join(words, " ")
+}
+
+func quoteEnvironmentValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+func installWindowsService(name string, executable string, args []string) error {
+	return errors.New("Windows services can only be installed on Windows")
+}
+
+func isWindowsService() bool {
+	return false
+}
+
+func runWindowsService(ctx context.Context, name string, run func(ctx context.Context) bool) bool {
+	return run(ctx)
+}
@@ -0,0 +1,167 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// fimEventID is the event ID of the event log entries written by the fim
+// service.
+const fimEventID = 1
+
+// installWindowsService registers a service starting the executable with args
+// at boot, and the event log source of its output.
+func installWindowsService(name string, executable string, args []string) error {
+	manager, err := mgr.Connect()
+
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+
+	defer manager.Disconnect()
+
+	if service, err := manager.OpenService(name); err == nil {
+		service.Close()
+
+		return fmt.Errorf("service %s already exists", name)
+	}
+
+	service, err := manager.CreateService(name, executable, mgr.Config{
+		DisplayName: "Checksum fim " + name,
+		Description: "Monitors file integrity against a checksum baseline",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+
+	defer service.Close()
+
+	if err := eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		service.Delete()
+
+		return fmt.Errorf("failed to register event log source: %w", err)
+	}
+
+	return nil
+}
+
+func isWindowsService() bool {
+	service, err := svc.IsWindowsService()
+
+	return err == nil && service
+}
+
+// runWindowsService runs under the service manager until stopped, writing the
+// output of run to the event log as it would print it.
+func runWindowsService(ctx context.Context, name string, run func(ctx context.Context) bool) bool {
+	log, err := eventlog.Open(name)
+
+	if err != nil {
+		return false
+	}
+
+	defer log.Close()
+
+	reader, writer, err := os.Pipe()
+
+	if err != nil {
+		log.Error(fimEventID, fmt.Sprintf("Error redirecting output: %v", err))
+
+		return false
+	}
+
+	stdout := os.Stdout
+	os.Stdout = writer
+
+	forwarded := make(chan struct{})
+
+	go func() {
+		forwardToEventLog(reader, log)
+		close(forwarded)
+	}()
+
+	handler := &windowsService{ctx: ctx, run: run}
+	err = svc.Run(name, handler)
+
+	os.Stdout = stdout
+	writer.Close()
+	<-forwarded
+
+	if err != nil {
+		log.Error(fimEventID, fmt.Sprintf("Error running service: %v", err))
+
+		return false
+	}
+
+	return handler.passed
+}
+
+// forwardToEventLog writes every printed line as an event, errors as error
+// events.
+func forwardToEventLog(reader io.Reader, log *eventlog.Log) {
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "Error") {
+			log.Error(fimEventID, line)
+		} else {
+			log.Info(fimEventID, line)
+		}
+	}
+}
+
+type windowsService struct {
+	ctx    context.Context
+	run    func(ctx context.Context) bool
+	passed bool
+}
+
+func (s *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	done := make(chan bool, 1)
+
+	go func() {
+		done <- s.run(ctx)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case s.passed = <-done:
+			status <- svc.Status{State: svc.StopPending}
+
+			if !s.passed {
+				return false, 1
+			}
+
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		}
+	}
+}
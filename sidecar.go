@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+const defaultSidecarFormat = "gnu"
+
+func validateSidecarOptions(format string, hashOpts checksum.Options) error {
+	switch format {
+	case "gnu", "bsd":
+		if hashOpts.Encoding != "hex" {
+			return fmt.Errorf("%s sidecar files require hex encoding, got %s", format, hashOpts.Encoding)
+		}
+	case "plain":
+	default:
+		return fmt.Errorf("unsupported sidecar format: %s", format)
+	}
+
+	for _, algorithm := range hashOpts.Algorithms() {
+		if algorithm == "cid" {
+			return fmt.Errorf("sidecar files do not support the cid algorithm")
+		}
+	}
+
+	return nil
+}
+
+// sidecarExtensions returns the extensions of the sidecar files a run may
+// write, so earlier sidecars are not hashed as regular files.
+func sidecarExtensions(extension string, hashOpts checksum.Options) []string {
+	if extension != "" {
+		return []string{strings.TrimPrefix(extension, ".")}
+	}
+
+	return hashOpts.Algorithms()
+}
+
+func (o scanOptions) isSidecar(relativePath string) bool {
+	for _, extension := range o.sidecars {
+		if strings.HasSuffix(relativePath, "."+extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatSidecar renders the checksum file of a single entry. gnu and bsd name
+// the file by its base name, so "sha256sum -c" works from its directory.
+func formatSidecar(entry FileChecksum, format string) ([]byte, error) {
+	name := filepath.Base(filepath.FromSlash(entry.Path))
+
+	if format != "plain" {
+		if _, err := hex.DecodeString(entry.Checksum); err != nil {
+			return nil, fmt.Errorf("%s sidecar files require hex digests, got %q for %s", format, entry.Checksum, entry.Path)
+		}
+	}
+
+	switch format {
+	case "bsd":
+		return []byte(fmt.Sprintf("%s (%s) = %s\n", strings.ToUpper(entry.Algorithm), name, entry.Checksum)), nil
+	case "plain":
+		return []byte(entry.Checksum + "\n"), nil
+	}
+
+	if strings.ContainsAny(name, "\\\n") {
+		name = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(name)
+
+		return []byte(fmt.Sprintf("\\%s  %s\n", entry.Checksum, name)), nil
+	}
+
+	return []byte(fmt.Sprintf("%s  %s\n", entry.Checksum, name)), nil
+}
+
+// writeSidecars writes a checksum file next to every hashed file, named after
+// the file plus the configured extension or the entry's algorithm.
+func writeSidecars(cfg config, rootDir string, checksums []FileChecksum) (int, error) {
+	written := 0
+
+	for _, entry := range checksums {
+		if entry.PresenceOnly {
+			continue
+		}
+
+		data, err := formatSidecar(entry, cfg.sidecarFormat)
+
+		if err != nil {
+			return written, err
+		}
+
+		extension := strings.TrimPrefix(cfg.sidecarExtension, ".")
+
+		if extension == "" {
+			extension = entry.Algorithm
+		}
+
+		path := filepath.Join(rootDir, filepath.FromSlash(entry.Path)) + "." + extension
+
+		if err := writeFileAtomic(path, data, 0o644); err != nil {
+			return written, fmt.Errorf("failed to write sidecar for %s: %w", entry.Path, err)
+		}
+
+		written++
+	}
+
+	return written, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	siemVendor  = "edvinaskrucas"
+	siemProduct = "checksum-action"
+	// siemVersion is the version of the events below, bumped when their
+	// fields change meaning.
+	siemVersion = "1"
+)
+
+// siemSeverity rates change on the 0 to 10 scale of CEF and LEEF. Changes
+// that only warn under the severity policies rate low whatever their kind.
+func siemSeverity(change fileChange) int {
+	if change.Warning {
+		return 3
+	}
+
+	switch change.Kind {
+	case changeModified:
+		return 8
+	case changeRemoved:
+		return 7
+	case changeAdded, changeRenamed, changeError:
+		return 5
+	case changeUnlisted:
+		return 3
+	}
+
+	return 2
+}
+
+func siemOutcome(change fileChange) string {
+	if change.Warning {
+		return "warning"
+	}
+
+	return "failure"
+}
+
+func summaryEventName(report verifyReport) string {
+	if len(report.Changes) == 0 {
+		return "Verification passed"
+	}
+
+	return "Verification found changes"
+}
+
+// formatCEF renders report as ArcSight Common Event Format events, one per
+// change followed by one summarizing the verification.
+func formatCEF(report verifyReport) ([]byte, error) {
+	var builder strings.Builder
+
+	now := fmt.Sprint(time.Now().UnixMilli())
+
+	event := func(signature string, name string, severity int, extension [][2]string) {
+		fmt.Fprintf(
+			&builder, "CEF:0|%s|%s|%s|%s|%s|%d|",
+			escapeCEFHeader(siemVendor), escapeCEFHeader(siemProduct), siemVersion,
+			escapeCEFHeader(signature), escapeCEFHeader(name), severity,
+		)
+
+		for i, field := range extension {
+			if i > 0 {
+				builder.WriteByte(' ')
+			}
+
+			fmt.Fprintf(&builder, "%s=%s", field[0], escapeCEFValue(field[1]))
+		}
+
+		builder.WriteByte('\n')
+	}
+
+	for _, change := range report.Changes {
+		extension := [][2]string{
+			{"rt", now},
+			{"act", string(change.Kind)},
+			{"outcome", siemOutcome(change)},
+			{"filePath", change.Path},
+			{"fname", filepath.Base(change.Path)},
+			{"cs1Label", "root"},
+			{"cs1", report.Root},
+		}
+
+		if change.From != "" {
+			extension = append(extension, [2]string{"oldFilePath", change.From})
+		}
+
+		if change.Kind != changeRemoved {
+			extension = append(extension, [2]string{"fsize", fmt.Sprint(change.ActualSize)})
+		}
+
+		if change.Actual != "" {
+			extension = append(extension, [2]string{"fileHash", change.Actual})
+		}
+
+		if change.Expected != "" {
+			extension = append(extension, [2]string{"oldFileHash", change.Expected}, [2]string{"oldFileSize", fmt.Sprint(change.ExpectedSize)})
+		}
+
+		extension = append(extension, [2]string{"msg", describeChange(change)})
+
+		event(string(change.Kind), "File "+string(change.Kind), siemSeverity(change), extension)
+	}
+
+	summary := report.Summary
+	severity := 0
+
+	if len(report.Changes) > 0 {
+		severity = 6
+	}
+
+	event("summary", summaryEventName(report), severity, [][2]string{
+		{"rt", now},
+		{"cs1Label", "root"},
+		{"cs1", report.Root},
+		{"cn1Label", "expected"},
+		{"cn1", fmt.Sprint(summary.Expected)},
+		{"cn2Label", "unchanged"},
+		{"cn2", fmt.Sprint(summary.Unchanged)},
+		{"cn3Label", "changes"},
+		{"cn3", fmt.Sprint(len(report.Changes))},
+		{"msg", fmt.Sprintf("%d modified, %d removed, %d added, %d errors", summary.Modified, summary.Removed, summary.Added, summary.Errors)},
+	})
+
+	return []byte(builder.String()), nil
+}
+
+// formatLEEF renders report as QRadar Log Event Extended Format 1.0 events,
+// one per change followed by one summarizing the verification.
+func formatLEEF(report verifyReport) ([]byte, error) {
+	var builder strings.Builder
+
+	now := time.Now()
+
+	event := func(id string, attributes [][2]string) {
+		fmt.Fprintf(&builder, "LEEF:1.0|%s|%s|%s|%s|", siemVendor, siemProduct, siemVersion, escapeLEEFHeader(id))
+
+		attributes = append([][2]string{
+			{"devTime", now.Format("Jan 02 2006 15:04:05.000 MST")},
+			{"devTimeFormat", "MMM dd yyyy HH:mm:ss.SSS z"},
+		}, attributes...)
+
+		for i, attribute := range attributes {
+			if i > 0 {
+				builder.WriteByte('\t')
+			}
+
+			fmt.Fprintf(&builder, "%s=%s", attribute[0], escapeLEEFValue(attribute[1]))
+		}
+
+		builder.WriteByte('\n')
+	}
+
+	for _, change := range report.Changes {
+		attributes := [][2]string{
+			{"cat", string(change.Kind)},
+			{"sev", fmt.Sprint(max(siemSeverity(change), 1))},
+			{"outcome", siemOutcome(change)},
+			{"root", report.Root},
+			{"filePath", change.Path},
+			{"sizeDelta", fmt.Sprint(change.SizeDelta)},
+		}
+
+		if change.From != "" {
+			attributes = append(attributes, [2]string{"oldFilePath", change.From})
+		}
+
+		if change.Actual != "" {
+			attributes = append(attributes, [2]string{"fileHash", change.Actual})
+		}
+
+		if change.Expected != "" {
+			attributes = append(attributes, [2]string{"oldFileHash", change.Expected})
+		}
+
+		attributes = append(attributes, [2]string{"msg", describeChange(change)})
+
+		event(string(change.Kind), attributes)
+	}
+
+	summary := report.Summary
+	severity := 1
+
+	if len(report.Changes) > 0 {
+		severity = 6
+	}
+
+	event("summary", [][2]string{
+		{"cat", "summary"},
+		{"sev", fmt.Sprint(severity)},
+		{"root", report.Root},
+		{"expected", fmt.Sprint(summary.Expected)},
+		{"unchanged", fmt.Sprint(summary.Unchanged)},
+		{"modified", fmt.Sprint(summary.Modified)},
+		{"removed", fmt.Sprint(summary.Removed)},
+		{"added", fmt.Sprint(summary.Added)},
+		{"errors", fmt.Sprint(summary.Errors)},
+		{"msg", summaryEventName(report)},
+	})
+
+	return []byte(builder.String()), nil
+}
+
+func escapeCEFHeader(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ").Replace(value)
+}
+
+func escapeCEFValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
+
+func escapeLEEFHeader(value string) string {
+	return strings.NewReplacer(`|`, `\|`, "\n", " ", "\r", " ").Replace(value)
+}
+
+// escapeLEEFValue replaces the tab delimiting attributes and line breaks,
+// which LEEF 1.0 has no escape for.
+func escapeLEEFValue(value string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(value)
+}
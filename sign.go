@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+const defaultSignatureFormat = "cosign"
+
+// manifestSigner produces the detached signature stored next to a manifest,
+// and manifestVerifier checks one, in the format selected by -signature-format.
+type manifestSigner interface {
+	sign(data []byte) ([]byte, error)
+}
+
+type manifestVerifier interface {
+	verify(data []byte, signatureData []byte) error
+}
+
+func signaturePath(checksumsFilePath string, format string) string {
+	if format == "minisign" {
+		return checksumsFilePath + ".minisig"
+	}
+
+	return checksumsFilePath + ".sig"
+}
+
+func validateSignatureOptions(cfg config) error {
+	switch cfg.signatureFormat {
+	case "cosign", "minisign", "signify":
+	default:
+		return fmt.Errorf("unsupported signature format: %s", cfg.signatureFormat)
+	}
+
+	if cfg.fips && cfg.signatureFormat == "minisign" {
+		return fmt.Errorf("minisign signatures prehash with BLAKE2b, which is not FIPS approved, use cosign or signify")
+	}
+
+	if cfg.signKMS != "" && cfg.signKey != "" {
+		return fmt.Errorf("-sign-kms and -sign-key cannot be combined")
+	}
+
+	if cfg.signKMS != "" && cfg.signatureFormat != "cosign" {
+		return fmt.Errorf("KMS keys sign in the cosign format, %s signatures need an Ed25519 -sign-key", cfg.signatureFormat)
+	}
+
+	if (cfg.signKMS != "" || cfg.signKey != "") && (cfg.splitOutput || cfg.maxEntries > 0) {
+		return fmt.Errorf("signing covers a single manifest file and cannot be combined with -split-output or -max-entries")
+	}
+
+	return nil
+}
+
+// resolveManifestSigner loads the -sign-kms or -sign-key key in the configured
+// signature format.
+func resolveManifestSigner(ctx context.Context, cfg config) (manifestSigner, error) {
+	if cfg.signKMS != "" {
+		signer, err := resolveKMSSigner(ctx, cfg.signKMS)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return cosignSigner{signer}, nil
+	}
+
+	if cfg.signatureFormat == "cosign" {
+		signer, err := resolveSigner(ctx, cfg.signKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return cosignSigner{signer}, nil
+	}
+
+	data, err := readSecret(ctx, cfg.signKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.signatureFormat == "minisign" {
+		return parseMinisignSecretKey(data, cfg.signKeyPassword)
+	}
+
+	return parseSignifySecretKey(data)
+}
+
+// resolveManifestVerifier loads the -pubkey key in the configured signature
+// format.
+func resolveManifestVerifier(ctx context.Context, ref string, format string) (manifestVerifier, error) {
+	if format == "cosign" {
+		key, err := resolvePublicKey(ctx, ref)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return cosignVerifier{key}, nil
+	}
+
+	data, err := readSecret(ctx, ref)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parseEd25519PublicKey(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ref, err)
+	}
+
+	if format == "minisign" {
+		return minisignKey(key), nil
+	}
+
+	return signifyKey(key), nil
+}
+
+// resolveKMSSigner accepts only references whose provider signs remotely, so
+// -sign-kms never falls back to a key read onto the runner.
+func resolveKMSSigner(ctx context.Context, ref string) (crypto.Signer, error) {
+	provider, _ := lookupCredentialProvider(ref)
+
+	if _, ok := provider.(checksum.SigningProvider); !ok {
+		return nil, fmt.Errorf("%s is not a KMS key, use awskms:, gcpkms: or azurekms:", ref)
+	}
+
+	return resolveSigner(ctx, ref)
+}
+
+// signManifest writes a detached signature of the manifest next to it.
+func signManifest(signer manifestSigner, checksumsFilePath string, format string) error {
+	data, err := os.ReadFile(checksumsFilePath)
+
+	if err != nil {
+		return err
+	}
+
+	signature, err := signer.sign(data)
+
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(signaturePath(checksumsFilePath, format), signature, 0644)
+}
+
+// cosignSigner signs like cosign sign-blob, writing the base64 encoded
+// signature over the SHA-256 digest of the data.
+type cosignSigner struct {
+	signer crypto.Signer
+}
+
+func (s cosignSigner) sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+
+	signature, err := s.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(signature) + "\n"), nil
+}
+
+// resolvePublicKey reads a PEM encoded public key from a file or credential
+// reference, or asks a KMS provider for the public half of its key.
+func resolvePublicKey(ctx context.Context, ref string) (crypto.PublicKey, error) {
+	provider, name := lookupCredentialProvider(ref)
+
+	if signing, ok := provider.(checksum.SigningProvider); ok {
+		signer, err := signing.Signer(ctx, name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return signer.Public(), nil
+	}
+
+	data, err := provider.Secret(ctx, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s is not a PEM encoded public key", ref)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ref, err)
+	}
+
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %T in %s, use an ECDSA or RSA key", key, ref)
+}
+
+type cosignVerifier struct {
+	key crypto.PublicKey
+}
+
+func (v cosignVerifier) verify(data []byte, signatureData []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signatureData)))
+
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	digest := sha256.Sum256(data)
+
+	switch key := v.key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return fmt.Errorf("signature does not match the public key")
+		}
+
+		return nil
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("signature does not match the public key")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported key type %T", v.key)
+}
+
+// loadSignedManifest refuses a manifest whose detached signature is missing
+// or invalid, so an attacker cannot edit the tree and its manifest together.
+// The verified bytes are the ones parsed.
+func loadSignedManifest(verifier manifestVerifier, checksumsFilePath string, format string) ([]FileChecksum, error) {
+	data, err := os.ReadFile(checksumsFilePath)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums file: %w", err)
+	}
+
+	signatureData, err := os.ReadFile(signaturePath(checksumsFilePath, format))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest signature: %w", err)
+	}
+
+	if err := verifier.verify(data, signatureData); err != nil {
+		return nil, fmt.Errorf("untrusted manifest %s: %w", checksumsFilePath, err)
+	}
+
+	if isManifestIndex(data) {
+		return nil, fmt.Errorf("untrusted manifest %s: the signature does not cover the parts of an index", checksumsFilePath)
+	}
+
+	return parseManifestFile(checksumsFilePath, data)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSignifyKey writes an unencrypted signify secret key, as signify -G -n
+// creates it, and returns its path and public key.
+func testSignifyKey(t *testing.T) (string, ed25519.PublicKey) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha512.Sum512(private)
+
+	raw := []byte("EdBK")
+	raw = append(raw, make([]byte, 4+16)...)
+	raw = append(raw, digest[:8]...)
+	raw = append(raw, "testkey1"...)
+	raw = append(raw, private...)
+
+	path := filepath.Join(t.TempDir(), "key.sec")
+	data := untrustedComment + "signify secret key\n" + base64.StdEncoding.EncodeToString(raw) + "\n"
+
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, public
+}
+
+func TestSignManifestRun(t *testing.T) {
+	app := buildApp(t)
+	keyFile, public := testSignifyKey(t)
+
+	t.Run("signs the manifest", func(t *testing.T) {
+		workspace := t.TempDir()
+
+		if err := os.WriteFile(filepath.Join(workspace, "a"), []byte("abc"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		command := exec.Command(app, "-dir", workspace, "-signature-format", "signify", "-sign-key", keyFile)
+
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("run: %v\n%s", err, output)
+		}
+
+		manifest, err := os.ReadFile(filepath.Join(workspace, "checksums.json"))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signature, err := os.ReadFile(filepath.Join(workspace, "checksums.json.sig"))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key := signifyKey{keyID: []byte("testkey1"), public: public}
+
+		if err := key.verify(manifest, signature); err != nil {
+			t.Errorf("verify() error = %v", err)
+		}
+	})
+
+	t.Run("fails the run when signing fails", func(t *testing.T) {
+		workspace := t.TempDir()
+
+		if err := os.WriteFile(filepath.Join(workspace, "a"), []byte("abc"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// A directory in the way of the signature makes writing it fail.
+		if err := os.MkdirAll(filepath.Join(workspace, "checksums.json.sig", "d"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		output, err := exec.Command(app, "-dir", workspace, "-signature-format", "signify", "-sign-key", keyFile).CombinedOutput()
+
+		var exitErr *exec.ExitError
+
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			t.Fatalf("run error = %v, want exit status 1\n%s", err, output)
+		}
+
+		if !bytes.Contains(output, []byte("Error signing manifest")) {
+			t.Errorf("output %s does not report the signing error", strings.TrimSpace(string(output)))
+		}
+	})
+}
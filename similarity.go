@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+const (
+	// sketchSize is the number of chunk hashes kept per file, bounding the
+	// sketch to 256 bytes before encoding.
+	sketchSize = 64
+	// Chunks average about 64 bytes, a line or two of text, so a one-line edit
+	// touches few of them.
+	minChunkSize = 16
+	maxChunkSize = 512
+	chunkMask    = 1<<6 - 1
+)
+
+// gearTable holds the random values the rolling gear hash adds per byte. It is
+// derived from a fixed seed, since sketches recorded by one run are compared
+// by later ones.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+
+	state := uint64(0x9E3779B97F4A7C15)
+
+	for i := range table {
+		// splitmix64
+		state += 0x9E3779B97F4A7C15
+		z := state
+		z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+		z = (z ^ z>>27) * 0x94D049BB133111EB
+		table[i] = z ^ z>>31
+	}
+
+	return table
+}()
+
+// sketchInspector splits text content into content-defined chunks and keeps
+// the smallest hashes of the distinct chunks, a bottom-k sketch from which
+// the share of chunks two versions of a file have in common is estimated.
+// Binary files get no sketch.
+type sketchInspector struct {
+	chunk  []byte
+	gear   uint64
+	binary bool
+	hashes []uint32
+}
+
+func (i *sketchInspector) Write(p []byte) (int, error) {
+	if i.binary {
+		return len(p), nil
+	}
+
+	for _, b := range p {
+		if b == 0 {
+			i.binary = true
+			i.chunk, i.hashes = nil, nil
+
+			return len(p), nil
+		}
+
+		i.chunk = append(i.chunk, b)
+		i.gear = i.gear<<1 + gearTable[b]
+
+		if len(i.chunk) >= maxChunkSize || (len(i.chunk) >= minChunkSize && i.gear&chunkMask == 0) {
+			i.cut()
+		}
+	}
+
+	return len(p), nil
+}
+
+// cut ends the current chunk and adds its hash to the sketch if it is among
+// the smallest seen.
+func (i *sketchInspector) cut() {
+	if len(i.chunk) == 0 {
+		return
+	}
+
+	hasher := fnv.New32a()
+	hasher.Write(i.chunk)
+	i.chunk, i.gear = i.chunk[:0], 0
+
+	i.hashes = addToSketch(i.hashes, hasher.Sum32())
+}
+
+func addToSketch(hashes []uint32, hash uint32) []uint32 {
+	index := sort.Search(len(hashes), func(j int) bool { return hashes[j] >= hash })
+
+	if index < len(hashes) && hashes[index] == hash {
+		return hashes
+	}
+
+	if len(hashes) == sketchSize {
+		if index == sketchSize {
+			return hashes
+		}
+
+		hashes = hashes[:sketchSize-1]
+	}
+
+	hashes = append(hashes, 0)
+	copy(hashes[index+1:], hashes[index:])
+	hashes[index] = hash
+
+	return hashes
+}
+
+func (i *sketchInspector) sketch() string {
+	if i.binary {
+		return ""
+	}
+
+	i.cut()
+
+	data := make([]byte, 4*len(i.hashes))
+
+	for j, hash := range i.hashes {
+		binary.BigEndian.PutUint32(data[4*j:], hash)
+	}
+
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func (i *sketchInspector) record(entry *FileChecksum) {
+	entry.Sketch = i.sketch()
+}
+
+func decodeSketch(sketch string) ([]uint32, bool) {
+	data, err := base64.StdEncoding.DecodeString(sketch)
+
+	if err != nil || len(data)%4 != 0 {
+		return nil, false
+	}
+
+	hashes := make([]uint32, len(data)/4)
+
+	for j := range hashes {
+		hashes[j] = binary.BigEndian.Uint32(data[4*j:])
+	}
+
+	return hashes, true
+}
+
+// similarity estimates the share of distinct chunks two sketches have in
+// common, from 1 for the same chunks to 0 for none. It is the overlap among
+// the smallest hashes of both, which is exact for files of at most
+// sketchSize chunks.
+func similarity(expected string, actual string) *float64 {
+	left, ok := decodeSketch(expected)
+
+	if !ok {
+		return nil
+	}
+
+	right, ok := decodeSketch(actual)
+
+	if !ok || (len(left) == 0 && len(right) == 0) {
+		return nil
+	}
+
+	union := 0
+	shared := 0
+
+	for l, r := 0, 0; union < sketchSize && (l < len(left) || r < len(right)); union++ {
+		switch {
+		case r == len(right) || (l < len(left) && left[l] < right[r]):
+			l++
+		case l == len(left) || right[r] < left[l]:
+			r++
+		default:
+			shared++
+			l++
+			r++
+		}
+	}
+
+	score := math.Round(float64(shared)/float64(union)*1000) / 1000
+
+	return &score
+}
+
+// similarityOf compares the sketch recorded for a modified file with the
+// one the inspectors took of its new content.
+func similarityOf(expected string, inspectors []contentInspector) *float64 {
+	if expected == "" {
+		return nil
+	}
+
+	for _, inspector := range inspectors {
+		if sketch, ok := inspector.(*sketchInspector); ok && !sketch.binary {
+			return similarity(expected, sketch.sketch())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func resolveSink(cfg config, name string) (checksum.Sink, error) {
+	if command, ok := strings.CutPrefix(name, "exec:"); ok {
+		fields := strings.Fields(command)
+
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("sink %q has no command", name)
+		}
+
+		return checksum.CommandSink(fields[0], fields[1:]...), nil
+	}
+
+	if target, ok := strings.CutPrefix(name, "oci:"); ok {
+		if _, err := parseOCIReference(target); err != nil {
+			return nil, err
+		}
+
+		return ociSink(cfg, target), nil
+	}
+
+	sink, ok := checksum.LookupSink(name)
+
+	if !ok {
+		return nil, fmt.Errorf("unknown sink: %s", name)
+	}
+
+	return sink, nil
+}
+
+func sendToSinks(ctx context.Context, cfg config, checksums []FileChecksum) error {
+	if len(cfg.sinks) == 0 {
+		return nil
+	}
+
+	var data []byte
+	var err error
+
+	if cfg.format == "gosrc" {
+		data, err = formatGoSource(checksums, cfg.goPackage)
+	} else {
+		data, err = formatChecksums(checksums, cfg.format, cfg.header)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to format checksums: %w", err)
+	}
+
+	for _, name := range cfg.sinks {
+		sink, err := resolveSink(cfg, name)
+
+		if err != nil {
+			return err
+		}
+
+		if err := sink.Send(ctx, checksums, data); err != nil {
+			return fmt.Errorf("sink %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
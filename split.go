@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+const (
+	splitIndexFile = "index.json"
+	splitRootFile  = "root.json"
+	splitPartsDir  = "parts"
+
+	// splitRootPart names the part holding the files directly in the root,
+	// which no directory can be called.
+	splitRootPart = "."
+)
+
+func splitOutputDir(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile))
+}
+
+func splitPartName(relativePath string) string {
+	first, _, found := strings.Cut(filepath.ToSlash(relativePath), "/")
+
+	if !found {
+		return splitRootPart
+	}
+
+	return first
+}
+
+// splitPartFile is the file of a part, relative to the split output
+// directory. Directories get escaped names of their own below parts, so none
+// can collide with the index, the root part or the pages of another part.
+func splitPartFile(name string) string {
+	if name == splitRootPart {
+		return splitRootFile
+	}
+
+	return splitPartsDir + "/" + strings.ReplaceAll(url.PathEscape(name), "-", "%2D") + ".json"
+}
+
+func saveSplit(checksums []FileChecksum, outputDir string, format string, maxEntries int, header checksum.Header) error {
+	parts := make(map[string][]FileChecksum)
+
+	for _, checksum := range checksums {
+		name := splitPartName(checksum.Path)
+		parts[name] = append(parts[name], checksum)
+	}
+
+	names := make([]string, 0, len(parts))
+
+	for name := range parts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if err := os.MkdirAll(filepath.Join(outputDir, splitPartsDir), 0755); err != nil {
+		return fmt.Errorf("failed to create split output directory: %w", err)
+	}
+
+	index := manifestIndex{Compliance: header.Compliance, Explain: header.Explain}
+
+	for _, name := range names {
+		file := splitPartFile(name)
+
+		if err := saveManifest(parts[name], filepath.Join(outputDir, filepath.FromSlash(file)), format, maxEntries, partHeader(header)); err != nil {
+			return err
+		}
+
+		index.Parts = append(index.Parts, manifestPart{
+			Name:  name,
+			File:  file,
+			Files: len(parts[name]),
+		})
+	}
+
+	return saveIndex(index, filepath.Join(outputDir, splitIndexFile))
+}
+
+func loadSplit(outputDir string) ([]FileChecksum, error) {
+	return loadFromFile(filepath.Join(outputDir, splitIndexFile))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+const abcSHA256 = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+
+func testEntries(paths ...string) []FileChecksum {
+	entries := make([]FileChecksum, 0, len(paths))
+
+	for _, path := range paths {
+		entries = append(entries, FileChecksum{Path: path, Checksum: abcSHA256, Algorithm: "sha256", Size: 3})
+	}
+
+	return entries
+}
+
+func entryPaths(entries []FileChecksum) []string {
+	paths := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+func TestSplitPartFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{splitRootPart, "root.json"},
+		{"src", "parts/src.json"},
+		{"root", "parts/root.json"},
+		{"index", "parts/index.json"},
+		{"src-0001", "parts/src%2D0001.json"},
+		{"a b", "parts/a%20b.json"},
+		{"100%", "parts/100%25.json"},
+	}
+
+	for _, test := range tests {
+		if got := splitPartFile(test.name); got != test.want {
+			t.Errorf("splitPartFile(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSplitRoundTrip(t *testing.T) {
+	// Directories named after the index, the root part, the parts directory
+	// and the pages of another part must all come back as they were.
+	paths := []string{
+		"a", "index.json", "root.json",
+		"index/a", "root/a", "parts/a", "_root/a",
+		"src/a", "src/b", "src/c", "src-0001/a", "src-0002/a",
+	}
+
+	for _, maxEntries := range []int{0, 1, 2} {
+		outputDir := filepath.Join(t.TempDir(), "checksums")
+
+		if err := saveSplit(testEntries(paths...), outputDir, "json", maxEntries, checksum.Header{}); err != nil {
+			t.Fatalf("saveSplit() with -max-entries %d error = %v", maxEntries, err)
+		}
+
+		loaded, err := loadSplit(outputDir)
+
+		if err != nil {
+			t.Fatalf("loadSplit() with -max-entries %d error = %v", maxEntries, err)
+		}
+
+		want := append([]string(nil), paths...)
+		sort.Strings(want)
+
+		if got := entryPaths(loaded); !reflect.DeepEqual(got, want) {
+			t.Errorf("loadSplit() with -max-entries %d = %v, want %v", maxEntries, got, want)
+		}
+	}
+}
+
+func TestLoadIndexRefusesLoops(t *testing.T) {
+	tests := []struct {
+		name  string
+		index string
+	}{
+		{"lists itself", `{"parts":[{"name":"loop","file":"index.json","files":1}]}`},
+		{"lists a part twice", `{"parts":[{"name":"a","file":"a.json","files":1},{"name":"b","file":"./a.json","files":1}]}`},
+		{"nested index lists its parent", `{"parts":[{"name":"nested","file":"nested.json","files":1}]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{
+				"index.json":  test.index,
+				"a.json":      `[{"path":"a","checksum":"` + abcSHA256 + `","size":3}]`,
+				"nested.json": `{"parts":[{"name":"up","file":"index.json","files":1}]}`,
+			}
+
+			for name, data := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := loadFromFile(filepath.Join(dir, "index.json"))
+
+			if err == nil || !strings.Contains(err.Error(), "which is already listed") {
+				t.Fatalf("loadFromFile() error = %v, want the repeated part refused", err)
+			}
+		})
+	}
+}
+
+func TestSaveManifestPaginates(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "checksums.json")
+	paths := []string{"a", "b", "c", "d/e"}
+
+	if err := saveManifest(testEntries(paths...), outputFile, "json", 3, checksum.Header{}); err != nil {
+		t.Fatalf("saveManifest() error = %v", err)
+	}
+
+	data, err := os.ReadFile(outputFile)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isManifestIndex(data) {
+		t.Fatalf("saveManifest() wrote %s, want an index", data)
+	}
+
+	for _, page := range []string{"checksums-0001.json", "checksums-0002.json"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(outputFile), page)); err != nil {
+			t.Errorf("page %s: %v", page, err)
+		}
+	}
+
+	loaded, err := loadFromFile(outputFile)
+
+	if err != nil {
+		t.Fatalf("loadFromFile() error = %v", err)
+	}
+
+	if got := entryPaths(loaded); !reflect.DeepEqual(got, paths) {
+		t.Errorf("loadFromFile() = %v, want %v", got, paths)
+	}
+}
+
+func TestSaveManifestRemovesStalePages(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "checksums.json")
+	unlisted := filepath.Join(dir, "checksums-0009.json")
+
+	if err := os.WriteFile(unlisted, []byte("user file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pages := func() []string {
+		matches, err := filepath.Glob(filepath.Join(dir, "checksums-*.json"))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, match := range matches {
+			matches[i] = filepath.Base(match)
+		}
+
+		return matches
+	}
+
+	steps := []struct {
+		entries    []FileChecksum
+		maxEntries int
+		want       []string
+	}{
+		{testEntries("a", "b", "c"), 1, []string{"checksums-0001.json", "checksums-0002.json", "checksums-0003.json", "checksums-0009.json"}},
+		{testEntries("a", "b"), 1, []string{"checksums-0001.json", "checksums-0002.json", "checksums-0009.json"}},
+		{testEntries("a", "b"), 0, []string{"checksums-0009.json"}},
+	}
+
+	for i, step := range steps {
+		if err := saveManifest(step.entries, outputFile, "json", step.maxEntries, checksum.Header{}); err != nil {
+			t.Fatalf("step %d: saveManifest() error = %v", i, err)
+		}
+
+		if got := pages(); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: pages = %v, want %v", i, got, step.want)
+		}
+
+		loaded, err := loadFromFile(outputFile)
+
+		if err != nil {
+			t.Fatalf("step %d: loadFromFile() error = %v", i, err)
+		}
+
+		if got, want := entryPaths(loaded), entryPaths(step.entries); !reflect.DeepEqual(got, want) {
+			t.Errorf("step %d: loaded %v, want %v", i, got, want)
+		}
+	}
+}
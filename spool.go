@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// entryOverhead approximates the bytes an entry takes in memory besides its
+// strings: the struct itself, string headers and slice growth.
+const entryOverhead = 128
+
+// entrySpool bounds the entries held in memory during generation by moving
+// them to a temporary JSON lines file once their estimated size exceeds the
+// limit. The manifest is then streamed from the file and the in-memory tail.
+type entrySpool struct {
+	limit   int64
+	used    int64
+	seen    int
+	file    *os.File
+	writer  *bufio.Writer
+	files   int
+	bytes   int64
+	spilled bool
+}
+
+func newEntrySpool(limit int64) *entrySpool {
+	return &entrySpool{limit: limit}
+}
+
+func entrySize(entry FileChecksum) int64 {
+	size := int64(len(entry.Path)+len(entry.Checksum)+len(entry.Algorithm)) + entryOverhead
+
+	for _, tag := range entry.Tags {
+		size += int64(len(tag)) + 16
+	}
+
+	return size
+}
+
+// track accounts for the entries appended since the last call and spills all
+// of them when the limit is exceeded, returning the emptied slice.
+func (s *entrySpool) track(checksums []FileChecksum) ([]FileChecksum, error) {
+	for _, entry := range checksums[s.seen:] {
+		s.used += entrySize(entry)
+	}
+
+	s.seen = len(checksums)
+
+	if s.used <= s.limit {
+		return checksums, nil
+	}
+
+	if err := s.spill(checksums); err != nil {
+		return checksums, err
+	}
+
+	s.used = 0
+	s.seen = 0
+
+	return checksums[:0], nil
+}
+
+func (s *entrySpool) spill(checksums []FileChecksum) error {
+	if s.file == nil {
+		file, err := os.CreateTemp("", "checksum-spool-*.jsonl")
+
+		if err != nil {
+			return fmt.Errorf("failed to create spool file: %w", err)
+		}
+
+		s.file = file
+		s.writer = bufio.NewWriter(file)
+	}
+
+	encoder := json.NewEncoder(s.writer)
+
+	for _, entry := range checksums {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write spool file: %w", err)
+		}
+
+		s.files++
+		s.bytes += entry.Size
+	}
+
+	s.spilled = true
+
+	return nil
+}
+
+// each replays the spilled entries followed by tail.
+func (s *entrySpool) each(tail []FileChecksum, fn func(entry FileChecksum) error) error {
+	if s.file != nil {
+		if err := s.writer.Flush(); err != nil {
+			return fmt.Errorf("failed to write spool file: %w", err)
+		}
+
+		if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to read spool file: %w", err)
+		}
+
+		decoder := json.NewDecoder(bufio.NewReader(s.file))
+
+		for {
+			var entry FileChecksum
+
+			err := decoder.Decode(&entry)
+
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to read spool file: %w", err)
+			}
+
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if _, err := s.file.Seek(0, io.SeekEnd); err != nil {
+			return fmt.Errorf("failed to read spool file: %w", err)
+		}
+	}
+
+	for _, entry := range tail {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// save streams the spilled entries and tail as a versioned JSON manifest,
+// byte for byte what MarshalManifest would produce.
+func (s *entrySpool) save(tail []FileChecksum, outputFile string, header checksum.Header) error {
+	file, err := os.CreateTemp(filepath.Dir(outputFile), "."+filepath.Base(outputFile)+".tmp-*")
+
+	if err != nil {
+		return fmt.Errorf("failed to write checksums to file: %w", err)
+	}
+
+	tempPath := file.Name()
+
+	defer os.Remove(tempPath)
+
+	writer := bufio.NewWriter(file)
+
+	fmt.Fprintf(writer, "{\n  \"schemaVersion\": %d,\n", checksum.SchemaVersion)
+
+	if header.Compliance != "" {
+		data, _ := json.Marshal(header.Compliance)
+
+		fmt.Fprintf(writer, "  \"compliance\": %s,\n", data)
+	}
+
+	if header.Explain != nil {
+		data, err := json.MarshalIndent(header.Explain, "  ", "  ")
+
+		if err != nil {
+			file.Close()
+
+			return fmt.Errorf("failed to marshal explanation: %w", err)
+		}
+
+		fmt.Fprintf(writer, "  \"explain\": %s,\n", data)
+	}
+
+	writer.WriteString("  \"entries\": [")
+
+	first := true
+
+	err = s.each(tail, func(entry FileChecksum) error {
+		if header.DigestPrefix {
+			entry = entry.Prefixed()
+		}
+
+		data, err := json.MarshalIndent(entry, "    ", "  ")
+
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			writer.WriteString(",")
+		}
+
+		first = false
+
+		writer.WriteString("\n    ")
+		_, err = writer.Write(data)
+
+		return err
+	})
+
+	if err == nil {
+		if first {
+			writer.WriteString("]\n}")
+		} else {
+			writer.WriteString("\n  ]\n}")
+		}
+
+		err = writer.Flush()
+	}
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(tempPath, 0644)
+	}
+
+	if err == nil {
+		err = os.Rename(tempPath, outputFile)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write checksums to file: %w", err)
+	}
+
+	return nil
+}
+
+// addTo counts the spilled entries into a generation summary. The aggregate
+// digest sorts every entry, so it is left out once entries were spilled.
+func (s *entrySpool) addTo(summary *runSummary) {
+	if s == nil || !s.spilled {
+		return
+	}
+
+	summary.Files += s.files
+	summary.Bytes += s.bytes
+	summary.Digest = ""
+}
+
+func (s *entrySpool) close() {
+	if s == nil || s.file == nil {
+		return
+	}
+
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+const ownershipSupported = true
+
+func fileOwner(info fs.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	if !ok {
+		return 0, 0, false
+	}
+
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+func fileInode(info fs.FileInfo) (inodeID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	if !ok || stat.Nlink < 2 || !info.Mode().IsRegular() {
+		return inodeID{}, false
+	}
+
+	return inodeID{device: uint64(stat.Dev), inode: uint64(stat.Ino)}, true
+}
+
+func fileDevice(info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(stat.Dev), true
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package main
+
+import "io/fs"
+
+const ownershipSupported = false
+
+func fileOwner(info fs.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+func fileInode(info fs.FileInfo) (inodeID, bool) {
+	return inodeID{}, false
+}
+
+func fileDevice(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const largestChangesLimit = 5
+
+type directoryStats struct {
+	Directory  string  `json:"directory"`
+	Files      int     `json:"files"`
+	Changed    int     `json:"changed"`
+	ChangeRate float64 `json:"changeRate"`
+}
+
+type verifyStats struct {
+	TotalFiles     int              `json:"totalFiles"`
+	TotalBytes     int64            `json:"totalBytes"`
+	ChangedBytes   int64            `json:"changedBytes"`
+	LargestChanges []fileChange     `json:"largestChanges"`
+	Directories    []directoryStats `json:"directories"`
+}
+
+func newVerifyStats(result verifyResult) verifyStats {
+	stats := verifyStats{
+		TotalFiles:     len(result.unchanged),
+		TotalBytes:     result.unchangedBytes,
+		LargestChanges: []fileChange{},
+	}
+
+	directories := make(map[string]*directoryStats)
+
+	directory := func(name string) *directoryStats {
+		top, _, found := strings.Cut(name, "/")
+
+		if !found {
+			top = "."
+		}
+
+		if directories[top] == nil {
+			directories[top] = &directoryStats{Directory: top}
+		}
+
+		return directories[top]
+	}
+
+	for _, name := range result.unchanged {
+		directory(name).Files++
+	}
+
+	for _, change := range result.changes {
+		if change.Kind != changeRemoved && change.Kind != changeFiltered {
+			stats.TotalFiles++
+			stats.TotalBytes += change.ActualSize
+		}
+
+		if change.Kind == changeFiltered || change.Kind == changeUnlisted {
+			continue
+		}
+
+		stats.ChangedBytes += changedBytes(change)
+		stats.LargestChanges = append(stats.LargestChanges, change)
+
+		changed := directory(change.Path)
+		changed.Files++
+		changed.Changed++
+	}
+
+	sort.SliceStable(stats.LargestChanges, func(i, j int) bool {
+		return changedBytes(stats.LargestChanges[i]) > changedBytes(stats.LargestChanges[j])
+	})
+
+	if len(stats.LargestChanges) > largestChangesLimit {
+		stats.LargestChanges = stats.LargestChanges[:largestChangesLimit]
+	}
+
+	stats.Directories = make([]directoryStats, 0, len(directories))
+
+	for _, entry := range directories {
+		entry.ChangeRate = float64(entry.Changed) / float64(entry.Files)
+		stats.Directories = append(stats.Directories, *entry)
+	}
+
+	sort.Slice(stats.Directories, func(i, j int) bool {
+		if stats.Directories[i].ChangeRate != stats.Directories[j].ChangeRate {
+			return stats.Directories[i].ChangeRate > stats.Directories[j].ChangeRate
+		}
+
+		return stats.Directories[i].Directory < stats.Directories[j].Directory
+	})
+
+	return stats
+}
+
+func changedBytes(change fileChange) int64 {
+	switch change.Kind {
+	case changeRemoved:
+		return change.ExpectedSize
+	case changeRenamed:
+		// The content is unchanged, only its path moved.
+		return 0
+	}
+
+	return change.ActualSize
+}
+
+func printStats(stats verifyStats) {
+	fmt.Printf("Tree holds %d files, %d bytes, %d bytes changed\n", stats.TotalFiles, stats.TotalBytes, stats.ChangedBytes)
+
+	if len(stats.LargestChanges) == 0 {
+		return
+	}
+
+	largest := make([]string, 0, len(stats.LargestChanges))
+
+	for _, change := range stats.LargestChanges {
+		largest = append(largest, fmt.Sprintf("%s (%d bytes)", change.Path, changedBytes(change)))
+	}
+
+	fmt.Println("Largest changes:", strings.Join(largest, ", "))
+
+	var rates []string
+
+	for _, directory := range stats.Directories {
+		if directory.Changed > 0 {
+			rates = append(rates, fmt.Sprintf("%s %.1f%% (%d/%d)", directory.Directory, directory.ChangeRate*100, directory.Changed, directory.Files))
+		}
+	}
+
+	fmt.Println("Change rate by directory:", strings.Join(rates, ", "))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+const defaultStorageProfile = "auto"
+
+func validateStorageProfile(profile string) error {
+	switch profile {
+	case "auto", "ssd", "hdd", "network":
+		return nil
+	}
+
+	return fmt.Errorf("unsupported storage profile: %s", profile)
+}
+
+// resolveStorageProfile probes the file system holding dir when profile is
+// auto. Anything that cannot be identified is treated as an SSD.
+func resolveStorageProfile(profile string, dir string) string {
+	if profile != "auto" {
+		return profile
+	}
+
+	if detected, ok := detectStorageProfile(dir); ok {
+		return detected
+	}
+
+	return "ssd"
+}
+
+// profileWalkers is the directory walker count suited to a storage profile.
+// Spinning disks lose throughput to seeks when read concurrently, while
+// network file systems are bound by round trips and gain from many requests
+// in flight.
+func profileWalkers(profile string) int {
+	switch profile {
+	case "hdd":
+		return 1
+	case "network":
+		return 16
+	}
+
+	return min(max(runtime.NumCPU(), 2), 8)
+}
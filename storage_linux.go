@@ -0,0 +1,88 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+var networkFileSystems = map[string]bool{
+	"nfs":       true,
+	"nfs4":      true,
+	"cifs":      true,
+	"smb3":      true,
+	"smbfs":     true,
+	"9p":        true,
+	"afs":       true,
+	"ceph":      true,
+	"glusterfs": true,
+	"lustre":    true,
+	"gpfs":      true,
+}
+
+func detectStorageProfile(dir string) (string, bool) {
+	var stat unix.Stat_t
+
+	if err := unix.Stat(dir, &stat); err != nil {
+		return "", false
+	}
+
+	device := fmt.Sprintf("%d:%d", unix.Major(uint64(stat.Dev)), unix.Minor(uint64(stat.Dev)))
+
+	if fsType, ok := mountFileSystem(device); ok && (networkFileSystems[fsType] || strings.HasPrefix(fsType, "fuse")) {
+		return "network", true
+	}
+
+	// Partitions have no queue of their own, their parent device does.
+	for _, queue := range []string{"queue/rotational", "../queue/rotational"} {
+		data, err := os.ReadFile(filepath.Join("/sys/dev/block", device, queue))
+
+		if err != nil {
+			continue
+		}
+
+		if strings.TrimSpace(string(data)) == "1" {
+			return "hdd", true
+		}
+
+		return "ssd", true
+	}
+
+	return "", false
+}
+
+// mountFileSystem looks up the file system type of a device in
+// /proc/self/mountinfo, whose type follows the "-" separator.
+func mountFileSystem(device string) (string, bool) {
+	file, err := os.Open("/proc/self/mountinfo")
+
+	if err != nil {
+		return "", false
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) < 3 || fields[2] != device {
+			continue
+		}
+
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) {
+				return fields[i+1], true
+			}
+		}
+	}
+
+	return "", false
+}
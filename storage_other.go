@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func detectStorageProfile(dir string) (string, bool) {
+	return "", false
+}
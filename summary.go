@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// redactedFlags hold secrets, kept out of summaries, audit logs, explanations
+// and service command lines. Webhook and API URLs count, as they often carry
+// their token in the path or query.
+var redactedFlags = []string{"smtp-password", "github-token", "oci-password", "sign-key-password", "ioc-token", "notify-webhook", "ioc-url"}
+
+type runSummary struct {
+	Mode            string            `json:"mode"`
+	StartedAt       time.Time         `json:"startedAt"`
+	FinishedAt      time.Time         `json:"finishedAt"`
+	DurationSeconds float64           `json:"durationSeconds"`
+	Files           int               `json:"files"`
+	Bytes           int64             `json:"bytes"`
+	EmptyFiles      int               `json:"emptyFiles,omitempty"`
+	Verification    *verifySummary    `json:"verification,omitempty"`
+	Errors          []string          `json:"errors"`
+	Digest          string            `json:"digest,omitempty"`
+	TreeDigest      string            `json:"treeDigest,omitempty"`
+	Config          map[string]string `json:"config"`
+}
+
+func summaryPath(outputFile string) string {
+	return outputFile + ".summary.json"
+}
+
+func newRunSummary(cfg config, mode string) runSummary {
+	finished := time.Now()
+
+	return runSummary{
+		Mode:            mode,
+		StartedAt:       cfg.startedAt,
+		FinishedAt:      finished,
+		DurationSeconds: finished.Sub(cfg.startedAt).Seconds(),
+		Errors:          []string{},
+		Config:          configSnapshot(),
+	}
+}
+
+func configSnapshot() map[string]string {
+	snapshot := make(map[string]string)
+
+	flag.VisitAll(func(f *flag.Flag) {
+		snapshot[f.Name] = f.Value.String()
+	})
+
+	for name, value := range snapshot {
+		snapshot[name] = redactedValue(name, value)
+	}
+
+	return snapshot
+}
+
+// aggregateDigest is a SHA-256 over the sorted manifest entries, identifying
+// the manifest content independently of its output format.
+func aggregateDigest(checksums []FileChecksum) string {
+	sorted := make([]FileChecksum, len(checksums))
+	copy(sorted, checksums)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	hasher := sha256.New()
+
+	for _, checksum := range sorted {
+		fmt.Fprintf(hasher, "%s:%s  %s\n", checksum.Algorithm, checksum.Checksum, checksum.Path)
+	}
+
+	return "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+}
+
+func generationSummary(cfg config, checksums []FileChecksum, errs []string) runSummary {
+	summary := newRunSummary(cfg, "generate")
+	summary.Files = len(checksums)
+	summary.Digest = aggregateDigest(checksums)
+	summary.Errors = append(summary.Errors, errs...)
+
+	for _, checksum := range checksums {
+		summary.Bytes += checksum.Size
+	}
+
+	return summary
+}
+
+func verificationSummary(cfg config, report verifyReport) runSummary {
+	summary := newRunSummary(cfg, "verify")
+	summary.Files = report.Stats.TotalFiles
+	summary.Bytes = report.Stats.TotalBytes
+	summary.Verification = &report.Summary
+
+	for _, change := range report.Changes {
+		if change.Kind == changeError {
+			summary.Errors = append(summary.Errors, change.Path+": "+change.Error)
+		}
+	}
+
+	return summary
+}
+
+func saveRunSummary(cfg config, summary runSummary) {
+	if cfg.auditLog != "" {
+		if err := appendAuditRecord(cfg.auditLog, cfg.auditChain, summary); err != nil {
+			fmt.Println("Error writing audit log:", err)
+		}
+	}
+
+	if cfg.summaryFile == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+
+	if err == nil {
+		err = writeFileAtomic(cfg.summaryFile, data, 0644)
+	}
+
+	if err != nil {
+		fmt.Println("Error saving run summary:", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func validateSumsOptions(hashOpts checksum.Options) error {
+	if hashOpts.Encoding != "hex" {
+		return fmt.Errorf("sums format requires hex encoding, got %s", hashOpts.Encoding)
+	}
+
+	algorithms := hashOpts.Algorithms()
+
+	for _, algorithm := range algorithms {
+		if algorithm != algorithms[0] {
+			return fmt.Errorf("sums format requires a single algorithm, got %s and %s", algorithms[0], algorithm)
+		}
+
+		if algorithm == "cid" {
+			return fmt.Errorf("sums format does not support the cid algorithm")
+		}
+	}
+
+	return nil
+}
+
+// formatSums writes entries in the GNU coreutils "sha256sum" layout. Paths
+// containing a backslash or newline are escaped the way coreutils does.
+func formatSums(checksums []FileChecksum) ([]byte, error) {
+	sorted := make([]FileChecksum, 0, len(checksums))
+
+	for _, checksum := range checksums {
+		if !checksum.PresenceOnly {
+			sorted = append(sorted, checksum)
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	var buf bytes.Buffer
+
+	for _, checksum := range sorted {
+		if checksum.Algorithm != sorted[0].Algorithm {
+			return nil, fmt.Errorf("sums format requires a single algorithm, got %s and %s", sorted[0].Algorithm, checksum.Algorithm)
+		}
+
+		if _, err := hex.DecodeString(checksum.Checksum); err != nil {
+			return nil, fmt.Errorf("sums format requires hex digests, got %q for %s", checksum.Checksum, checksum.Path)
+		}
+
+		path := checksum.Path
+
+		if strings.ContainsAny(path, "\\\n") {
+			path = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(path)
+			buf.WriteString("\\")
+		}
+
+		fmt.Fprintf(&buf, "%s  %s\n", checksum.Checksum, path)
+	}
+
+	return buf.Bytes(), nil
+}
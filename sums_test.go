@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func TestFormatSumsRoundTrip(t *testing.T) {
+	entries := []FileChecksum{
+		{Path: "d/b", Checksum: abcSHA256, Algorithm: "sha256"},
+		{Path: "a", Checksum: abcSHA256, Algorithm: "sha256"},
+		{Path: "back\\slash", Checksum: abcSHA256, Algorithm: "sha256"},
+		{Path: "new\nline", Checksum: abcSHA256, Algorithm: "sha256"},
+		{Path: "present", PresenceOnly: true},
+	}
+
+	data, err := formatSums(entries)
+
+	if err != nil {
+		t.Fatalf("formatSums() error = %v", err)
+	}
+
+	want := abcSHA256 + "  a\n" +
+		"\\" + abcSHA256 + "  back\\\\slash\n" +
+		abcSHA256 + "  d/b\n" +
+		"\\" + abcSHA256 + "  new\\nline\n"
+
+	if string(data) != want {
+		t.Fatalf("formatSums() = %q, want %q", data, want)
+	}
+
+	parsed, err := checksum.ParseSums(data, "SHA256SUMS")
+
+	if err != nil {
+		t.Fatalf("ParseSums() error = %v", err)
+	}
+
+	wantParsed := []FileChecksum{
+		{Path: "a", Checksum: abcSHA256, Algorithm: "sha256"},
+		{Path: "back\\slash", Checksum: abcSHA256, Algorithm: "sha256"},
+		{Path: "d/b", Checksum: abcSHA256, Algorithm: "sha256"},
+		{Path: "new\nline", Checksum: abcSHA256, Algorithm: "sha256"},
+	}
+
+	if !reflect.DeepEqual(parsed, wantParsed) {
+		t.Errorf("ParseSums() = %+v, want %+v", parsed, wantParsed)
+	}
+}
+
+func TestFormatSumsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []FileChecksum
+		wantErr string
+	}{
+		{
+			name:    "mixed algorithms",
+			entries: []FileChecksum{{Path: "a", Checksum: abcSHA256, Algorithm: "sha256"}, {Path: "b", Checksum: abcSHA256[:40], Algorithm: "sha1"}},
+			wantErr: "single algorithm",
+		},
+		{
+			name:    "non-hex digest",
+			entries: []FileChecksum{{Path: "a", Checksum: "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0=", Algorithm: "sha256"}},
+			wantErr: "hex digests",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := formatSums(test.entries); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("formatSums() error = %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
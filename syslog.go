@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSyslogFacility = "daemon"
+	// syslogAppName is the APP-NAME of the messages sent to -syslog.
+	syslogAppName = "checksum"
+	// syslogSDID names the structured data of the messages. 32473 is the
+	// private enterprise number reserved for documentation.
+	syslogSDID = "checksum@32473"
+)
+
+const (
+	syslogWarning = 4
+	syslogNotice  = 5
+	syslogInfo    = 6
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// parseSyslogAddress splits a -syslog address such as udp://host:514,
+// tcp://host:601 or unix:///dev/log into a network and address for net.Dial.
+func parseSyslogAddress(address string) (string, string, error) {
+	parsed, err := url.Parse(address)
+
+	if err != nil {
+		return "", "", fmt.Errorf("invalid syslog address %q: %w", address, err)
+	}
+
+	switch parsed.Scheme {
+	case "udp", "tcp":
+		if parsed.Host == "" {
+			return "", "", fmt.Errorf("syslog address %q has no host", address)
+		}
+
+		return parsed.Scheme, parsed.Host, nil
+	case "unix":
+		return "unixgram", parsed.Path, nil
+	default:
+		return "", "", fmt.Errorf("unsupported syslog transport: %s", parsed.Scheme)
+	}
+}
+
+func parseSyslogFacility(name string) (int, error) {
+	facility, ok := syslogFacilities[name]
+
+	if !ok {
+		return 0, fmt.Errorf("unsupported syslog facility: %s", name)
+	}
+
+	return facility, nil
+}
+
+// sendSyslog sends an RFC 5424 message for every change in report, followed
+// by one summarizing the verification, so a SIEM can alert on single files.
+func sendSyslog(address string, facilityName string, report verifyReport) error {
+	network, target, err := parseSyslogAddress(address)
+
+	if err != nil {
+		return err
+	}
+
+	facility, err := parseSyslogFacility(facilityName)
+
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout(network, target, 30*time.Second)
+
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	defer conn.Close()
+
+	hostname, err := os.Hostname()
+
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	send := func(severity int, msgID string, params [][2]string, message string) error {
+		var data strings.Builder
+
+		data.WriteString("[" + syslogSDID)
+
+		for _, param := range params {
+			fmt.Fprintf(&data, ` %s="%s"`, param[0], escapeSyslogParam(param[1]))
+		}
+
+		data.WriteString("]")
+
+		line := fmt.Sprintf(
+			"<%d>1 %s %s %s %d %s %s %s",
+			facility*8+severity, time.Now().Format("2006-01-02T15:04:05.000000Z07:00"),
+			hostname, syslogAppName, os.Getpid(), msgID, data.String(), message,
+		)
+
+		// Stream transports frame messages by octet counting (RFC 6587).
+		if network == "tcp" {
+			line = fmt.Sprintf("%d %s", len(line), line)
+		}
+
+		_, err := conn.Write([]byte(line))
+
+		return err
+	}
+
+	for _, change := range report.Changes {
+		severity := syslogWarning
+
+		if change.Warning {
+			severity = syslogNotice
+		}
+
+		params := [][2]string{{"root", report.Root}, {"path", change.Path}, {"kind", string(change.Kind)}}
+
+		if change.From != "" {
+			params = append(params, [2]string{"from", change.From})
+		}
+
+		if change.Expected != "" {
+			params = append(params, [2]string{"expected", change.Expected})
+		}
+
+		if change.Actual != "" {
+			params = append(params, [2]string{"actual", change.Actual})
+		}
+
+		if err := send(severity, "change", params, fmt.Sprintf("%s %s", change.Kind, change.Path)); err != nil {
+			return fmt.Errorf("failed to send to syslog: %w", err)
+		}
+	}
+
+	summary := report.Summary
+	severity := syslogInfo
+
+	if len(report.Changes) > 0 {
+		severity = syslogWarning
+	}
+
+	params := [][2]string{
+		{"root", report.Root},
+		{"expected", fmt.Sprint(summary.Expected)},
+		{"unchanged", fmt.Sprint(summary.Unchanged)},
+		{"modified", fmt.Sprint(summary.Modified)},
+		{"removed", fmt.Sprint(summary.Removed)},
+		{"added", fmt.Sprint(summary.Added)},
+		{"errors", fmt.Sprint(summary.Errors)},
+	}
+
+	message, _, _ := strings.Cut(summarizeReport(report), "\n")
+
+	if err := send(severity, "summary", params, message); err != nil {
+		return fmt.Errorf("failed to send to syslog: %w", err)
+	}
+
+	return nil
+}
+
+// escapeSyslogParam escapes the characters RFC 5424 reserves in PARAM-VALUE.
+func escapeSyslogParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
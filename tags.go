@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+const (
+	tagPolicyFatal   = "fatal"
+	tagPolicyWarning = "warning"
+)
+
+// tagRule attaches tag to the entries whose path matches pattern.
+type tagRule struct {
+	pattern string
+	tag     string
+}
+
+// parseTagRules parses pattern=tag rules.
+func parseTagRules(rules []string) ([]tagRule, error) {
+	parsed := make([]tagRule, 0, len(rules))
+
+	for _, rule := range rules {
+		pattern, tag, found := strings.Cut(rule, "=")
+
+		if !found || pattern == "" || tag == "" {
+			return nil, fmt.Errorf("invalid tag rule %q, expected pattern=tag", rule)
+		}
+
+		if err := checksum.ValidateGlob(pattern); err != nil {
+			return nil, fmt.Errorf("invalid tag rule %q: %w", rule, err)
+		}
+
+		parsed = append(parsed, tagRule{pattern: pattern, tag: tag})
+	}
+
+	return parsed, nil
+}
+
+// parseTagPolicies parses tag=fatal and tag=warning rules deciding how
+// changes to tagged files count when verifying. Fatal rules come first, so a
+// change with both kinds of tags still fails.
+func parseTagPolicies(rules []string) ([]severityRule, error) {
+	var fatal, warning []severityRule
+
+	for _, rule := range rules {
+		tag, policy, found := strings.Cut(rule, "=")
+
+		switch {
+		case found && tag != "" && policy == tagPolicyFatal:
+			fatal = append(fatal, severityRule{Tags: []string{tag}, Severity: severityFail})
+		case found && tag != "" && policy == tagPolicyWarning:
+			warning = append(warning, severityRule{Tags: []string{tag}, Severity: severityWarn})
+		default:
+			return nil, fmt.Errorf("invalid tag policy %q, expected tag=fatal or tag=warning", rule)
+		}
+	}
+
+	return append(fatal, warning...), nil
+}
+
+// tagsFor returns the sorted tags of every rule matching relativePath.
+func (o scanOptions) tagsFor(relativePath string) []string {
+	var tags []string
+
+	for _, rule := range o.tags {
+		if checksum.MatchGlob(rule.pattern, filepath.ToSlash(relativePath)) && !containsString(tags, rule.tag) {
+			tags = append(tags, rule.tag)
+		}
+	}
+
+	sort.Strings(tags)
+
+	return tags
+}
+
+// entryTags merges the tags recorded for a manifest entry with those the
+// current rules give its path, so rules added after a baseline apply to it.
+// Hashed paths cannot be matched and keep their recorded tags.
+func (o scanOptions) entryTags(entry FileChecksum) []string {
+	if o.pathKey != nil {
+		return entry.Tags
+	}
+
+	return mergeTags(entry.Tags, o.tagsFor(entry.Path))
+}
+
+func mergeTags(recorded []string, current []string) []string {
+	var tags []string
+
+	for _, tag := range append(append([]string{}, recorded...), current...) {
+		if !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	sort.Strings(tags)
+
+	return tags
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
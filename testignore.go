@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// ignoreRule explains why the walk leaves out relativePath, asking the rules
+// walkTree applies about each directory above it and then the path itself.
+func (o scanOptions) ignoreRule(rootDir string, relativePath string) (string, bool) {
+	parts := strings.Split(relativePath, "/")
+
+	for i := range parts {
+		current := strings.Join(parts[:i+1], "/")
+		last := i == len(parts)-1
+
+		var entry fs.DirEntry = missingEntry{name: parts[i], dir: !last}
+
+		if info, err := os.Lstat(filepath.Join(rootDir, filepath.FromSlash(current))); err == nil {
+			entry = fs.FileInfoToDirEntry(info)
+		}
+
+		if entry.IsDir() {
+			if rule := o.pruneRule(current, entry); rule != "" {
+				return rule, true
+			}
+		}
+
+		if last && (!entry.IsDir() || o.emptyDirs) {
+			if rule := o.skipRule(rootDir, current, entry); rule != "" {
+				return rule, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// missingEntry stands in for a path that does not exist, which only the
+// rules matching names can leave out, as with git check-ignore.
+type missingEntry struct {
+	name string
+	dir  bool
+}
+
+func (e missingEntry) Name() string {
+	return e.name
+}
+
+func (e missingEntry) IsDir() bool {
+	return e.dir
+}
+
+func (e missingEntry) Type() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir
+	}
+
+	return 0
+}
+
+func (e missingEntry) Info() (fs.FileInfo, error) {
+	return nil, fs.ErrNotExist
+}
+
+// runTestIgnore mirrors git check-ignore -v -n: ignored paths are printed with
+// the matching rule, others with "::", and the command succeeds only if at
+// least one path is ignored.
+func runTestIgnore(cfg config, projectDir string, opts scanOptions) bool {
+	if len(cfg.args) == 0 {
+		fmt.Println("Error testing ignore rules: test-ignore expects one or more paths")
+
+		return false
+	}
+
+	ignored := false
+
+	for _, arg := range cfg.args {
+		path, err := filepath.Abs(arg)
+
+		if err != nil {
+			fmt.Println("Error resolving path:", err)
+
+			return false
+		}
+
+		relativePath, err := filepath.Rel(projectDir, path)
+
+		if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+			fmt.Printf("Error testing ignore rules: %s is outside %s\n", arg, projectDir)
+
+			return false
+		}
+
+		relativePath = filepath.ToSlash(relativePath)
+
+		if rule, ok := opts.ignoreRule(projectDir, relativePath); ok {
+			fmt.Printf("%s\t%s\n", rule, relativePath)
+			ignored = true
+
+			continue
+		}
+
+		fmt.Printf("::\t%s\n", relativePath)
+
+		for _, pattern := range opts.presenceOnly {
+			if checksum.MatchGlob(pattern, relativePath) {
+				fmt.Printf("  recorded without hashing by -presence-only:%s\n", pattern)
+
+				break
+			}
+		}
+	}
+
+	return ignored
+}
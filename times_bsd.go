@@ -0,0 +1,35 @@
+//go:build darwin || freebsd || netbsd
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func captureTimes(path string) (checksum.Timestamps, error) {
+	var stat unix.Stat_t
+
+	if err := unix.Lstat(path, &stat); err != nil {
+		return checksum.Timestamps{}, &os.PathError{Op: "lstat", Path: path, Err: err}
+	}
+
+	at := func(timestamp unix.Timespec) string {
+		if timestamp.Sec == 0 && timestamp.Nsec == 0 {
+			return ""
+		}
+
+		return formatTimestamp(time.Unix(timestamp.Unix()))
+	}
+
+	return checksum.Timestamps{
+		Modified: at(stat.Mtim),
+		Accessed: at(stat.Atim),
+		Changed:  at(stat.Ctim),
+		Born:     at(stat.Btim),
+	}, nil
+}
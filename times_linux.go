@@ -0,0 +1,40 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// captureTimes reads the timestamps of path with statx, which reports the
+// birth time on file systems recording one.
+func captureTimes(path string) (checksum.Timestamps, error) {
+	var stat unix.Statx_t
+
+	err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BASIC_STATS|unix.STATX_BTIME, &stat)
+
+	if err != nil {
+		return checksum.Timestamps{}, &os.PathError{Op: "statx", Path: path, Err: err}
+	}
+
+	at := func(timestamp unix.StatxTimestamp) string {
+		return formatTimestamp(time.Unix(timestamp.Sec, int64(timestamp.Nsec)))
+	}
+
+	times := checksum.Timestamps{
+		Modified: at(stat.Mtime),
+		Accessed: at(stat.Atime),
+		Changed:  at(stat.Ctime),
+	}
+
+	if stat.Mask&unix.STATX_BTIME != 0 {
+		times.Born = at(stat.Btime)
+	}
+
+	return times, nil
+}
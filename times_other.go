@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+
+package main
+
+import (
+	"os"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func captureTimes(path string) (checksum.Timestamps, error) {
+	info, err := os.Lstat(path)
+
+	if err != nil {
+		return checksum.Timestamps{}, err
+	}
+
+	return checksum.Timestamps{Modified: formatTimestamp(info.ModTime())}, nil
+}
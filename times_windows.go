@@ -0,0 +1,37 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// captureTimes reads the timestamps of path from its file attributes, which
+// hold no change time.
+func captureTimes(path string) (checksum.Timestamps, error) {
+	info, err := os.Lstat(path)
+
+	if err != nil {
+		return checksum.Timestamps{}, err
+	}
+
+	attributes, ok := info.Sys().(*syscall.Win32FileAttributeData)
+
+	if !ok {
+		return checksum.Timestamps{Modified: formatTimestamp(info.ModTime())}, nil
+	}
+
+	at := func(filetime syscall.Filetime) string {
+		return formatTimestamp(time.Unix(0, filetime.Nanoseconds()))
+	}
+
+	return checksum.Timestamps{
+		Modified: at(attributes.LastWriteTime),
+		Accessed: at(attributes.LastAccessTime),
+		Born:     at(attributes.CreationTime),
+	}, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// carryTombstones adds tombstones for the files of the previous manifest at
+// checksumsFilePath that are gone from the tree, keeping their last-known
+// entries marked with the time they were first found missing. Tombstones of
+// earlier runs are kept until the file comes back, and files still on disk
+// but no longer scanned, such as newly ignored ones, get none. It returns the
+// entries to save and the number of new tombstones.
+func carryTombstones(cfg config, projectDir string, checksums []FileChecksum, checksumsFilePath string, opts scanOptions) ([]FileChecksum, int, error) {
+	previous, err := loadManifest(cfg, checksumsFilePath, opts.hash)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return checksums, 0, nil
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, found := addTombstones(projectDir, checksums, previous, opts)
+
+	return entries, found, nil
+}
+
+// addTombstones adds tombstones for the previous entries gone from the tree,
+// as carryTombstones describes.
+func addTombstones(projectDir string, checksums []FileChecksum, previous []FileChecksum, opts scanOptions) ([]FileChecksum, int) {
+	present := make(map[string]bool, len(checksums))
+
+	for _, entry := range checksums {
+		present[filepath.ToSlash(entry.Path)] = true
+	}
+
+	deleted := formatTimestamp(time.Now())
+	entries := append([]FileChecksum{}, checksums...)
+	found := 0
+
+	for _, entry := range previous {
+		if present[filepath.ToSlash(entry.Path)] {
+			continue
+		}
+
+		if entry.Deleted == "" {
+			// Hashed paths cannot be mapped back to files, so only their
+			// absence from the scan counts.
+			if opts.pathKey == nil {
+				if _, err := os.Lstat(filepath.Join(projectDir, filepath.FromSlash(entry.Path))); err == nil {
+					continue
+				}
+			}
+
+			entry.Deleted = deleted
+			found++
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, found
+}
+
+// removeTombstones leaves out the entries of deleted files, which
+// verification does not expect to find.
+func removeTombstones(entries []FileChecksum) []FileChecksum {
+	kept := entries[:0]
+
+	for _, entry := range entries {
+		if entry.Deleted == "" {
+			kept = append(kept, entry)
+		}
+	}
+
+	return kept
+}
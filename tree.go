@@ -0,0 +1,134 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func calculateTreeDigest(ctx context.Context, rootDir string, opts scanOptions, method string, prefix string) (string, error) {
+	switch method {
+	case "tar":
+		return calculateTarDigest(ctx, rootDir, opts)
+	case "dirhash":
+		return calculateDirHash(ctx, rootDir, opts, prefix)
+	}
+
+	return "", fmt.Errorf("unsupported tree digest method: %s", method)
+}
+
+func calculateTarDigest(ctx context.Context, rootDir string, opts scanOptions) (string, error) {
+	hasher, err := opts.hash.NewHasher(opts.hash.Algorithm)
+
+	if err != nil {
+		return "", err
+	}
+
+	archive := tar.NewWriter(hasher)
+
+	err = walkFiles(ctx, rootDir, opts, func(path string, relativePath string) error {
+		return writeTarEntry(ctx, archive, path, relativePath)
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := archive.Close(); err != nil {
+		return "", fmt.Errorf("failed to finish tar stream: %w", err)
+	}
+
+	return opts.hash.Encode(opts.hash.Algorithm, hasher.Sum(nil)), nil
+}
+
+func writeTarEntry(ctx context.Context, archive *tar.Writer, path string, relativePath string) error {
+	file, err := openScanned(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	mode := int64(0644)
+
+	if info.Mode()&0111 != 0 {
+		mode = 0755
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     filepath.ToSlash(relativePath),
+		Mode:     mode,
+		Size:     info.Size(),
+		ModTime:  time.Unix(0, 0),
+		Format:   tar.FormatPAX,
+	}
+
+	if err := archive.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", relativePath, err)
+	}
+
+	if _, err := io.CopyN(archive, contextReader{ctx: ctx, reader: file}, info.Size()); err != nil {
+		return fmt.Errorf("failed to write tar entry for %s: %w", relativePath, err)
+	}
+
+	return nil
+}
+
+// calculateDirHash mirrors golang.org/x/mod/sumdb/dirhash.HashDir with Hash1,
+// producing the same h1: value go.sum records for a module tree.
+func calculateDirHash(ctx context.Context, rootDir string, opts scanOptions, prefix string) (string, error) {
+	files := make(map[string]string)
+
+	var names []string
+
+	err := walkFiles(ctx, rootDir, opts, func(filePath string, relativePath string) error {
+		name := path.Join(prefix, filepath.ToSlash(relativePath))
+
+		if strings.Contains(name, "\n") {
+			return fmt.Errorf("dirhash: filenames with newlines are not supported: %q", name)
+		}
+
+		files[name] = filePath
+		names = append(names, name)
+
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(names)
+
+	summary := sha256.New()
+
+	for _, name := range names {
+		digest, _, err := generateDigest(ctx, files[name], checksum.Options{}, "sha256")
+
+		if err != nil {
+			return "", fmt.Errorf("failed to calculate checksum for %s: %w", files[name], err)
+		}
+
+		fmt.Fprintf(summary, "%x  %s\n", digest, name)
+	}
+
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
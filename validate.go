@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+func validateRootDir(dir string) error {
+	info, err := os.Stat(dir)
+
+	if os.IsNotExist(err) {
+		return fmt.Errorf("root directory %s does not exist, check -dir", dir)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to access root directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("root directory %s is not a directory, check -dir", dir)
+	}
+
+	return nil
+}
+
+func validateOutputDir(outputPath string) error {
+	dir := filepath.Dir(outputPath)
+	info, err := os.Stat(dir)
+
+	if os.IsNotExist(err) {
+		return fmt.Errorf("output directory %s does not exist, check -output", dir)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to access output directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("output directory %s is not a directory, check -output", dir)
+	}
+
+	return nil
+}
+
+// validateIgnorePatterns rejects ignore prefixes that would silently match
+// everything or nothing, since they are compared against relative paths.
+func validateIgnorePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		switch {
+		case strings.TrimSpace(pattern) == "":
+			return fmt.Errorf("empty entry in -ignore would ignore every file, check for stray commas")
+		case strings.HasPrefix(pattern, "/") || filepath.IsAbs(pattern):
+			return fmt.Errorf("ignore path %q must be relative to the root directory", pattern)
+		case pattern == ".." || strings.HasPrefix(path.Clean(filepath.ToSlash(pattern)), "../"):
+			return fmt.Errorf("ignore path %q points outside the root directory", pattern)
+		}
+	}
+
+	return nil
+}
+
+func validatePresencePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if err := checksum.ValidateGlob(pattern); err != nil {
+			return fmt.Errorf("invalid -presence-only pattern: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func validateAllowEmptyPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if err := checksum.ValidateGlob(pattern); err != nil {
+			return fmt.Errorf("invalid -allow-empty pattern: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// parseScope normalizes a -scope prefix to a clean slash-separated relative
+// path, with "" meaning the whole tree.
+func parseScope(scope string) (string, error) {
+	if scope == "" {
+		return "", nil
+	}
+
+	if strings.HasPrefix(scope, "/") || filepath.IsAbs(scope) {
+		return "", fmt.Errorf("scope %q must be relative to the root directory", scope)
+	}
+
+	cleaned := path.Clean(filepath.ToSlash(scope))
+
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("scope %q points outside the root directory", scope)
+	}
+
+	if cleaned == "." {
+		return "", nil
+	}
+
+	return cleaned, nil
+}
+
+func inScope(relativePath string, scope string) bool {
+	return scope == "" || relativePath == scope || strings.HasPrefix(relativePath, scope+"/")
+}
+
+func scopeEntries(entries []FileChecksum, scope string) []FileChecksum {
+	if scope == "" {
+		return entries
+	}
+
+	scoped := make([]FileChecksum, 0, len(entries))
+
+	for _, entry := range entries {
+		if inScope(filepath.ToSlash(entry.Path), scope) {
+			scoped = append(scoped, entry)
+		}
+	}
+
+	return scoped
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+// validateManifestFile checks a manifest, or every part listed by a manifest
+// index, against the current schema.
+func validateManifestFile(path string) error {
+	return validateManifestParts(path, map[string]bool{filepath.Clean(path): true})
+}
+
+func validateManifestParts(path string, seen map[string]bool) error {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if !isManifestIndex(data) {
+		return checksum.ValidateManifest(data)
+	}
+
+	var index manifestIndex
+
+	if err := json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("failed to parse manifest index: %w", err)
+	}
+
+	if index.SchemaVersion != checksum.SchemaVersion {
+		return &checksum.SchemaError{Problems: []string{fmt.Sprintf("unsupported index schemaVersion %d, expected %d", index.SchemaVersion, checksum.SchemaVersion)}}
+	}
+
+	partFiles, err := indexPartFiles(path, index, seen)
+
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+
+	for i, part := range index.Parts {
+		err := validateManifestParts(partFiles[i], seen)
+
+		var schemaErr *checksum.SchemaError
+
+		switch {
+		case errors.As(err, &schemaErr):
+			for _, problem := range schemaErr.Problems {
+				problems = append(problems, fmt.Sprintf("part %s: %s", part.Name, problem))
+			}
+		case err != nil:
+			return fmt.Errorf("part %s: %w", part.Name, err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &checksum.SchemaError{Problems: problems}
+	}
+
+	return nil
+}
+
+func runValidateManifest(cfg config, checksumsFilePath string) bool {
+	path := checksumsFilePath
+
+	switch len(cfg.args) {
+	case 0:
+	case 1:
+		path = cfg.args[0]
+	default:
+		fmt.Println("Error validating manifest: validate-manifest expects at most one manifest")
+
+		return false
+	}
+
+	err := validateManifestFile(path)
+
+	var schemaErr *checksum.SchemaError
+
+	if errors.As(err, &schemaErr) {
+		fmt.Printf("%s is not a valid schema version %d manifest:\n", path, checksum.SchemaVersion)
+
+		for _, problem := range schemaErr.Problems {
+			fmt.Println("  " + problem)
+		}
+
+		return false
+	}
+
+	if err != nil {
+		fmt.Println("Error validating manifest:", err)
+
+		return false
+	}
+
+	fmt.Printf("%s is a valid schema version %d manifest\n", path, checksum.SchemaVersion)
+
+	return true
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+const defaultVaultAuthPath = "jwt"
+
+type vaultCredentials struct{}
+
+// Secret reads field from the Vault secret at path, given as path#field. KV
+// version 2 responses are unwrapped, and the field may be omitted when the
+// secret holds a single one.
+func (vaultCredentials) Secret(ctx context.Context, name string) ([]byte, error) {
+	secretPath, field, _ := strings.Cut(name, "#")
+	address := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+
+	if address == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is not set")
+	}
+
+	token, err := vaultToken(ctx, address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, address+"/v1/"+strings.TrimPrefix(secretPath, "/"), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	setVaultHeaders(request, token)
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+
+	if err := doCredentialRequest(request, &secret); err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s: %w", secretPath, err)
+	}
+
+	data := secret.Data
+
+	if nested, ok := data["data"].(map[string]any); ok && data["metadata"] != nil {
+		data = nested
+	}
+
+	if field == "" {
+		if len(data) != 1 {
+			fields := make([]string, 0, len(data))
+
+			for name := range data {
+				fields = append(fields, name)
+			}
+
+			sort.Strings(fields)
+
+			return nil, fmt.Errorf("vault secret %s has fields %s, select one as %s#field", secretPath, strings.Join(fields, ", "), secretPath)
+		}
+
+		for name := range data {
+			field = name
+		}
+	}
+
+	value, ok := data[field]
+
+	if !ok {
+		return nil, fmt.Errorf("vault secret %s has no field %s", secretPath, field)
+	}
+
+	if text, ok := value.(string); ok {
+		return []byte(text), nil
+	}
+
+	return json.Marshal(value)
+}
+
+// vaultToken uses VAULT_TOKEN when set, and otherwise logs in to the JWT auth
+// method with the job's GitHub OIDC token and VAULT_ROLE.
+func vaultToken(ctx context.Context, address string) (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	role := os.Getenv("VAULT_ROLE")
+
+	if role == "" {
+		return "", fmt.Errorf("set VAULT_TOKEN, or VAULT_ROLE to log in with GitHub OIDC")
+	}
+
+	jwt, err := githubIDToken(ctx, os.Getenv("VAULT_AUDIENCE"))
+
+	if err != nil {
+		return "", err
+	}
+
+	authPath := os.Getenv("VAULT_AUTH_PATH")
+
+	if authPath == "" {
+		authPath = defaultVaultAuthPath
+	}
+
+	body, err := json.Marshal(map[string]string{"role": role, "jwt": jwt})
+
+	if err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, address+"/v1/auth/"+strings.Trim(authPath, "/")+"/login", bytes.NewReader(body))
+
+	if err != nil {
+		return "", err
+	}
+
+	setVaultHeaders(request, "")
+	request.Header.Set("Content-Type", "application/json")
+
+	var login struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+
+	if err := doCredentialRequest(request, &login); err != nil {
+		return "", fmt.Errorf("failed to log in to vault: %w", err)
+	}
+
+	return login.Auth.ClientToken, nil
+}
+
+func setVaultHeaders(request *http.Request, token string) {
+	if token != "" {
+		request.Header.Set("X-Vault-Token", token)
+	}
+
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		request.Header.Set("X-Vault-Namespace", namespace)
+	}
+}
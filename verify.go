@@ -0,0 +1,647 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/edvinaskrucas/checksum-action/checksum"
+)
+
+type changeKind string
+
+const (
+	changeAdded    changeKind = "added"
+	changeRemoved  changeKind = "removed"
+	changeModified changeKind = "modified"
+	changeError    changeKind = "error"
+	// changeFiltered is a manifest entry still on disk but skipped by the
+	// current ignore and filter rules.
+	changeFiltered changeKind = "filtered"
+	// changeUnlisted is a file missing from the manifest although it predates
+	// it, i.e. it was most likely filtered out when the manifest was generated.
+	changeUnlisted changeKind = "unlisted"
+	// changeRenamed is a removed file whose content was added under another
+	// path.
+	changeRenamed changeKind = "renamed"
+)
+
+type fileChange struct {
+	Path string     `json:"path"`
+	Kind changeKind `json:"kind"`
+	// From is the previous path of a renamed file.
+	From         string   `json:"from,omitempty"`
+	Expected     string   `json:"expected,omitempty"`
+	Actual       string   `json:"actual,omitempty"`
+	ExpectedSize int64    `json:"expectedSize"`
+	ActualSize   int64    `json:"actualSize"`
+	SizeDelta    int64    `json:"sizeDelta"`
+	Error        string   `json:"error,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	Entropy      float64  `json:"entropy,omitempty"`
+	// HighEntropy marks new content that looks packed or encrypted under
+	// -entropy-threshold.
+	HighEntropy bool                 `json:"highEntropy,omitempty"`
+	Secrets     []checksum.SecretHit `json:"secrets,omitempty"`
+	// Similarity is the estimated share of content a modified text file still
+	// has in common with the recorded one, from 0 to 1.
+	Similarity *float64 `json:"similarity,omitempty"`
+	// Empty marks new content of zero bytes where -allow-empty expects none:
+	// most often a truncated file, or a new file created empty.
+	Empty bool `json:"empty,omitempty"`
+	// KnownGood names the known-good file the new content matches.
+	KnownGood string `json:"knownGood,omitempty"`
+	// Warning marks a change that does not fail verification under the
+	// severity policies.
+	Warning bool `json:"warning,omitempty"`
+}
+
+type verifyResult struct {
+	changes        []fileChange
+	unchanged      []string
+	unchangedBytes int64
+	// unsampled counts the unchanged entries outside the -sample, whose
+	// presence alone was checked.
+	unsampled int
+	// verified holds the keys of the entries hashed and found unchanged.
+	verified []string
+	// ignored counts the changes dropped by the severity policies.
+	ignored int
+	// stopped is set when -fail-fast ended verification before every file was
+	// checked.
+	stopped bool
+}
+
+func (r *verifyResult) keep(name string, size int64) {
+	r.unchanged = append(r.unchanged, name)
+	r.unchangedBytes += size
+}
+
+// inspected adds what the inspectors found while hashing the new content of
+// change to it.
+func (o scanOptions) inspected(change fileChange, inspectors []contentInspector) fileChange {
+	var entry FileChecksum
+
+	for _, inspector := range inspectors {
+		inspector.record(&entry)
+	}
+
+	change.Entropy = entry.Entropy
+	change.HighEntropy = o.isHighEntropy(change.ActualSize, entry.Entropy)
+	change.Secrets = entry.Secrets
+
+	return change
+}
+
+// failingFast reports whether -fail-fast ends verification, which it does once
+// the last change found fails under the severity rules.
+func (o scanOptions) failingFast(result verifyResult) bool {
+	if !o.failFast || len(result.changes) == 0 {
+		return false
+	}
+
+	last := verifyResult{changes: result.changes[len(result.changes)-1:]}
+	markKnownGood(&last, o.knownGood)
+	markEmpty(&last, o.allowEmpty)
+
+	return severityOf(last.changes[0], o.severityRules) == severityFail
+}
+
+func verifyChecksums(ctx context.Context, rootDir string, expected []FileChecksum, opts scanOptions) (verifyResult, error) {
+	present := make(map[string]string)
+	names := make(map[string]string)
+
+	dirs := make(map[string]bool)
+
+	var order []string
+
+	visit := func(path string, relativePath string) error {
+		present[opts.manifestPath(relativePath)] = path
+		names[opts.manifestPath(relativePath)] = relativePath
+		order = append(order, relativePath)
+
+		return nil
+	}
+
+	err := walkTree(ctx, rootDir, opts, visit, func(path string, relativePath string) error {
+		dirs[opts.manifestPath(relativePath)] = true
+
+		return visit(path, relativePath)
+	})
+
+	if err != nil {
+		return verifyResult{}, err
+	}
+
+	var result verifyResult
+
+	known := make(map[string]bool, len(expected))
+	removed := make(renames)
+
+	for _, entry := range opts.orderEntries(expected, present) {
+		if opts.failingFast(result) {
+			result.stopped = true
+
+			return result, nil
+		}
+
+		key := filepath.ToSlash(entry.Path)
+		known[key] = true
+		tags := opts.entryTags(entry)
+
+		path, ok := present[key]
+
+		// A recorded empty directory that has since gained files is still
+		// there, although the walk no longer reports it.
+		if !ok && entry.Type == checksum.TypeDir && opts.isDir(rootDir, entry.Path) {
+			result.keep(entry.Path, 0)
+
+			continue
+		}
+
+		if !ok {
+			kind := changeRemoved
+
+			if opts.isFiltered(rootDir, entry.Path) {
+				kind = changeFiltered
+			}
+
+			result.changes = append(result.changes, fileChange{
+				Path:         entry.Path,
+				Kind:         kind,
+				Expected:     entry.Checksum,
+				ExpectedSize: entry.Size,
+				SizeDelta:    -entry.Size,
+				Tags:         tags,
+				Type:         entry.Type,
+			})
+
+			// Empty files all share a digest, so they say nothing about renames.
+			if kind == changeRemoved && entry.Size > 0 {
+				removed.add(entry.Algorithm, entry.Checksum, len(result.changes)-1)
+			}
+
+			continue
+		}
+
+		name := names[key]
+
+		if entry.PresenceOnly {
+			result.keep(name, entry.Size)
+
+			continue
+		}
+
+		if opts.sampled != nil && !opts.sampled[key] {
+			result.keep(name, entry.Size)
+			result.unsampled++
+
+			continue
+		}
+
+		if opts.quickCheck && entry.ModTime != 0 {
+			if info, err := os.Stat(path); err == nil && info.Size() == entry.Size && info.ModTime().UnixNano() == entry.ModTime {
+				result.keep(name, entry.Size)
+
+				continue
+			}
+		}
+
+		algorithm := entry.Algorithm
+		inspectors := opts.newInspectors()
+
+		digest, size, err := opts.digestInspected(ctx, path, algorithm, inspectors)
+
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		if err != nil {
+			result.changes = append(result.changes, fileChange{
+				Path:         name,
+				Kind:         changeError,
+				Expected:     entry.Checksum,
+				ExpectedSize: entry.Size,
+				Error:        err.Error(),
+				Tags:         tags,
+				Type:         entry.Type,
+			})
+
+			continue
+		}
+
+		if opts.hash.Encode(algorithm, digest) != entry.Checksum {
+			change := opts.inspected(fileChange{
+				Path:         name,
+				Kind:         changeModified,
+				Expected:     entry.Checksum,
+				Actual:       opts.hash.EncodeFull(algorithm, digest),
+				ExpectedSize: entry.Size,
+				ActualSize:   size,
+				SizeDelta:    size - entry.Size,
+				Tags:         tags,
+				Type:         entry.Type,
+			}, inspectors)
+
+			change.Similarity = similarityOf(entry.Sketch, inspectors)
+			result.changes = append(result.changes, change)
+
+			continue
+		}
+
+		result.keep(name, entry.Size)
+		result.verified = append(result.verified, key)
+	}
+
+	for _, relativePath := range order {
+		path := present[opts.manifestPath(relativePath)]
+
+		if known[opts.manifestPath(relativePath)] {
+			continue
+		}
+
+		if opts.failingFast(result) {
+			result.stopped = true
+
+			break
+		}
+
+		kind := changeAdded
+
+		if opts.isUnlisted(path) {
+			kind = changeUnlisted
+		}
+
+		fileType, _ := opts.fileType(path)
+
+		if dirs[opts.manifestPath(relativePath)] {
+			fileType = checksum.TypeDir
+		}
+
+		if opts.isPresenceOnly(relativePath) || fileType == checksum.TypeDir || isSpecialType(fileType) {
+			result.changes = append(result.changes, fileChange{
+				Path: relativePath,
+				Kind: kind,
+				Tags: opts.tagsFor(relativePath),
+				Type: fileType,
+			})
+
+			continue
+		}
+
+		algorithm := opts.hash.AlgorithmFor(relativePath)
+		inspectors := opts.newInspectors()
+
+		digest, size, err := opts.digestInspected(ctx, path, algorithm, inspectors)
+
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		if err != nil {
+			result.changes = append(result.changes, fileChange{
+				Path:  relativePath,
+				Kind:  changeError,
+				Error: err.Error(),
+				Tags:  opts.tagsFor(relativePath),
+				Type:  fileType,
+			})
+
+			continue
+		}
+
+		change := opts.inspected(fileChange{
+			Path:       relativePath,
+			Kind:       kind,
+			Actual:     opts.hash.EncodeFull(algorithm, digest),
+			ActualSize: size,
+			SizeDelta:  size,
+			Tags:       opts.tagsFor(relativePath),
+			Type:       fileType,
+		}, inspectors)
+
+		if kind == changeAdded && size > 0 {
+			if index, ok := removed.claim(algorithm, opts.hash.Encode(algorithm, digest)); ok {
+				result.changes[index] = renamedChange(result.changes[index], change)
+
+				continue
+			}
+		}
+
+		result.changes = append(result.changes, change)
+	}
+
+	return result, nil
+}
+
+// isFiltered reports whether a manifest entry missing from the walk still
+// exists on disk. Hashed paths cannot be mapped back and never match.
+func (o scanOptions) isFiltered(rootDir string, manifestPath string) bool {
+	if !o.coverageCheck || o.pathKey != nil {
+		return false
+	}
+
+	info, err := os.Lstat(filepath.Join(rootDir, filepath.FromSlash(manifestPath)))
+
+	return err == nil && info.Mode().IsRegular()
+}
+
+func (o scanOptions) isDir(rootDir string, manifestPath string) bool {
+	if o.pathKey != nil {
+		return false
+	}
+
+	info, err := os.Stat(filepath.Join(rootDir, filepath.FromSlash(manifestPath)))
+
+	return err == nil && info.IsDir()
+}
+
+func (o scanOptions) isUnlisted(path string) bool {
+	if !o.coverageCheck || o.manifestTime.IsZero() {
+		return false
+	}
+
+	info, err := os.Stat(path)
+
+	return err == nil && info.ModTime().Before(o.manifestTime)
+}
+
+// loadExpected reads the manifest to verify against and detects the algorithm
+// of entries recorded without one.
+func loadExpected(cfg config, checksumsFilePath string, hashOpts checksum.Options) ([]FileChecksum, error) {
+	expected, err := loadManifest(cfg, checksumsFilePath, hashOpts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	expected = removeTombstones(expected)
+
+	if cfg.fips {
+		err = checkFIPSManifest(expected)
+	}
+
+	return expected, err
+}
+
+// loadManifest reads every entry of the manifest at checksumsFilePath,
+// tombstones included.
+func loadManifest(cfg config, checksumsFilePath string, hashOpts checksum.Options) ([]FileChecksum, error) {
+	var (
+		expected []FileChecksum
+		err      error
+	)
+
+	switch {
+	case cfg.splitOutput:
+		expected, err = loadSplit(checksumsFilePath)
+	case cfg.manifestVerifier != nil:
+		expected, err = loadSignedManifest(cfg.manifestVerifier, checksumsFilePath, cfg.signatureFormat)
+	default:
+		expected, err = loadFromFile(checksumsFilePath)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entry := range expected {
+		expected[i] = hashOpts.DetectAlgorithm(entry)
+	}
+
+	return expected, nil
+}
+
+// checkFIPSManifest refuses a manifest holding digests of algorithms outside
+// the FIPS approved set up front, rather than failing on every file.
+func checkFIPSManifest(expected []FileChecksum) error {
+	for _, entry := range expected {
+		if !entry.PresenceOnly && !checksum.IsFIPSApproved(entry.Algorithm) {
+			return fmt.Errorf("%s is recorded with %s, which is not FIPS approved", entry.Path, entry.Algorithm)
+		}
+	}
+
+	return nil
+}
+
+func runVerify(ctx context.Context, cfg config, projectDir string, expected []FileChecksum, opts scanOptions) bool {
+	var scrub scrubState
+
+	if cfg.scrubAge > 0 {
+		var err error
+
+		scrub, err = loadScrubState(cfg.scrubStateFile)
+
+		if err != nil {
+			fmt.Println("Error loading scrub state:", err)
+
+			return false
+		}
+
+		opts.sampled = chooseScrub(cfg, expected, scrub)
+	} else {
+		opts.sampled = chooseSample(cfg, expected)
+	}
+
+	result, err := verifyChecksums(ctx, projectDir, expected, opts)
+
+	// Files verified before an interruption or a failure count as scrubbed.
+	if cfg.scrubAge > 0 {
+		if err := recordScrub(cfg.scrubStateFile, scrub, expected, result.verified); err != nil {
+			fmt.Println("Error saving scrub state:", err)
+		}
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println("Verification interrupted:", interruptReason(ctx))
+
+			// Changes found before the interruption are still worth reporting,
+			// most of all when -verify-order checks the likeliest first.
+			for _, change := range result.changes {
+				fmt.Printf("%s\t%s\n", change.Kind, change.Path)
+			}
+
+			return false
+		}
+
+		fmt.Println("Error verifying checksums:", err)
+
+		return false
+	}
+
+	// Marked here as well as in the report, so quarantining spares the
+	// known-good files the severity rules ignore.
+	markKnownGood(&result, cfg.knownGood)
+	markEmpty(&result, cfg.allowEmpty)
+
+	passed := reportVerification(ctx, cfg, cfg.rootDir, result, len(expected))
+
+	if !passed && cfg.par2Redundancy > 0 {
+		printRepairHints(cfg, projectDir, result.changes)
+	}
+
+	if !passed && cfg.quarantineDir != "" {
+		copied, err := quarantineChanges(cfg.quarantineDir, projectDir, result.changes, cfg.severityRules)
+
+		if err != nil {
+			fmt.Println("Error quarantining changed files:", err)
+		} else {
+			fmt.Printf("Quarantined %d changed files into %s\n", copied, cfg.quarantineDir)
+		}
+	}
+
+	return passed
+}
+
+func reportVerification(ctx context.Context, cfg config, root string, result verifyResult, expected int) bool {
+	markKnownGood(&result, cfg.knownGood)
+	markEmpty(&result, cfg.allowEmpty)
+	applySeverities(&result, cfg.severityRules)
+
+	report := newVerifyReport(root, result, expected)
+
+	if cfg.regoPolicy != "" {
+		decision, err := evaluateRegoPolicy(ctx, cfg.opaCommand, cfg.regoPolicy, report)
+
+		if err != nil {
+			fmt.Println("Error evaluating policy:", err)
+
+			return false
+		}
+
+		report.Policy = &decision
+	}
+
+	printReport(report)
+
+	saveRunSummary(cfg, verificationSummary(cfg, report))
+
+	if cfg.reportFile != "" {
+		if err := saveReport(report, cfg.reportFile, cfg.reportFormat); err != nil {
+			fmt.Println("Error saving report:", err)
+		}
+	}
+
+	drift := len(result.changes) > 0
+
+	if cfg.notifyWebhook != "" && (drift || cfg.notifyAlways) {
+		if err := sendWebhook(cfg.notifyWebhook, report, cfg.notifyFormat); err != nil {
+			fmt.Println("Error sending notification:", err)
+		}
+	}
+
+	if cfg.syslog != "" && (drift || cfg.notifyAlways) {
+		if err := sendSyslog(cfg.syslog, cfg.syslogFacility, report); err != nil {
+			fmt.Println("Error sending to syslog:", err)
+		}
+	}
+
+	if email := cfg.email(); email.enabled() && (drift || cfg.notifyAlways) {
+		if err := sendEmail(email, report, cfg.reportFormat); err != nil {
+			fmt.Println("Error sending email:", err)
+		}
+	}
+
+	if cfg.fileIssue && drift {
+		issue, err := fileGithubIssue(cfg.githubToken, cfg.issueTitle, report)
+
+		if err != nil {
+			fmt.Println("Error filing GitHub issue:", err)
+		} else {
+			fmt.Println("Filed GitHub issue:", issue.HTMLURL)
+		}
+	}
+
+	if report.Policy != nil {
+		return len(report.Policy.Deny) == 0
+	}
+
+	for _, change := range result.changes {
+		if !change.Warning {
+			return false
+		}
+	}
+
+	return true
+}
+
+func compareChecksums(expected []FileChecksum, actual []FileChecksum) verifyResult {
+	present := make(map[string]FileChecksum, len(actual))
+
+	for _, entry := range actual {
+		present[entry.Path] = entry
+	}
+
+	var result verifyResult
+
+	known := make(map[string]bool, len(expected))
+	removed := make(renames)
+
+	for _, entry := range expected {
+		known[entry.Path] = true
+
+		current, ok := present[entry.Path]
+
+		switch {
+		case !ok:
+			result.changes = append(result.changes, fileChange{
+				Path:         entry.Path,
+				Kind:         changeRemoved,
+				Expected:     entry.Checksum,
+				ExpectedSize: entry.Size,
+				SizeDelta:    -entry.Size,
+				Tags:         entry.Tags,
+				Type:         entry.Type,
+			})
+
+			if !entry.PresenceOnly && entry.Size > 0 {
+				removed.add(entry.Algorithm, entry.Checksum, len(result.changes)-1)
+			}
+		case entry.PresenceOnly || current.Checksum == entry.Checksum:
+			result.keep(entry.Path, entry.Size)
+		default:
+			result.changes = append(result.changes, fileChange{
+				Path:         entry.Path,
+				Kind:         changeModified,
+				Expected:     entry.Checksum,
+				Actual:       current.Checksum,
+				ExpectedSize: entry.Size,
+				ActualSize:   current.Size,
+				SizeDelta:    current.Size - entry.Size,
+				Tags:         mergeTags(entry.Tags, current.Tags),
+				Type:         current.Type,
+			})
+		}
+	}
+
+	for _, entry := range actual {
+		if known[entry.Path] {
+			continue
+		}
+
+		change := fileChange{
+			Path:       entry.Path,
+			Kind:       changeAdded,
+			Actual:     entry.Checksum,
+			ActualSize: entry.Size,
+			SizeDelta:  entry.Size,
+			Tags:       entry.Tags,
+			Type:       entry.Type,
+		}
+
+		if !entry.PresenceOnly && entry.Size > 0 {
+			if index, ok := removed.claim(entry.Algorithm, entry.Checksum); ok {
+				result.changes[index] = renamedChange(result.changes[index], change)
+
+				continue
+			}
+		}
+
+		result.changes = append(result.changes, change)
+	}
+
+	return result
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func runVerifyFile(ctx context.Context, cfg config, projectDir string, checksumsFilePath string, opts scanOptions) bool {
+	if len(cfg.args) != 1 {
+		fmt.Println("Error verifying file: verify-file expects exactly one path")
+
+		return false
+	}
+
+	path, err := filepath.Abs(cfg.args[0])
+
+	if err != nil {
+		fmt.Println("Error resolving file:", err)
+
+		return false
+	}
+
+	relativePath, err := filepath.Rel(projectDir, path)
+
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		fmt.Printf("Error verifying file: %s is outside %s\n", cfg.args[0], projectDir)
+
+		return false
+	}
+
+	expected, err := loadExpected(cfg, checksumsFilePath, opts.hash)
+
+	if err != nil {
+		fmt.Println("Error loading checksums:", err)
+
+		return false
+	}
+
+	entry, ok := findEntry(expected, opts.manifestPath(relativePath))
+
+	if !ok {
+		fmt.Printf("%s: not in manifest\n", filepath.ToSlash(relativePath))
+
+		return false
+	}
+
+	if entry.PresenceOnly {
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("%s: FAILED (%v)\n", filepath.ToSlash(relativePath), err)
+
+			return false
+		}
+
+		fmt.Printf("%s: OK\n", filepath.ToSlash(relativePath))
+
+		return true
+	}
+
+	algorithm := entry.Algorithm
+
+	digest, _, err := opts.digest(ctx, path, algorithm)
+
+	if err != nil {
+		fmt.Printf("%s: FAILED (%v)\n", filepath.ToSlash(relativePath), err)
+
+		return false
+	}
+
+	if actual := opts.hash.Encode(algorithm, digest); actual != entry.Checksum {
+		fmt.Printf("%s: FAILED (expected %s, got %s)\n", filepath.ToSlash(relativePath), entry.Checksum, actual)
+
+		return false
+	}
+
+	fmt.Printf("%s: OK\n", filepath.ToSlash(relativePath))
+
+	return true
+}
+
+func findEntry(checksums []FileChecksum, path string) (FileChecksum, bool) {
+	for _, entry := range checksums {
+		if filepath.ToSlash(entry.Path) == path {
+			return entry, true
+		}
+	}
+
+	return FileChecksum{}, false
+}
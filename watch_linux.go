@@ -0,0 +1,97 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF
+
+// watchTree signals on the returned channel whenever something under root
+// changes, watching every directory with inotify, including those created
+// later. Signals coalesce while nobody receives them.
+func watchTree(ctx context.Context, root string) (<-chan struct{}, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[int]string)
+
+	addTree := func(dir string) error {
+		return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+
+			wd, err := unix.InotifyAddWatch(fd, path, watchMask)
+
+			if err != nil {
+				return err
+			}
+
+			dirs[wd] = path
+
+			return nil
+		})
+	}
+
+	if err := addTree(root); err != nil {
+		unix.Close(fd)
+
+		return nil, err
+	}
+
+	changed := make(chan struct{}, 1)
+
+	go func() {
+		defer unix.Close(fd)
+
+		buffer := make([]byte, 64*1024)
+		poll := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+
+		for ctx.Err() == nil {
+			// Polling with a timeout notices the context ending, which a
+			// blocking read would not.
+			if ready, err := unix.Poll(poll, 500); err != nil || ready == 0 {
+				continue
+			}
+
+			n, err := unix.Read(fd, buffer)
+
+			if err != nil || n < unix.SizeofInotifyEvent {
+				continue
+			}
+
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+				name := buffer[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+				offset += unix.SizeofInotifyEvent + int(event.Len)
+
+				if event.Mask&unix.IN_ISDIR != 0 && event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+					if dir, ok := dirs[int(event.Wd)]; ok {
+						addTree(filepath.Join(dir, unix.ByteSliceToString(name)))
+					}
+				}
+
+				if event.Mask&unix.IN_IGNORED != 0 {
+					delete(dirs, int(event.Wd))
+				}
+			}
+
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changed, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+func watchTree(ctx context.Context, root string) (<-chan struct{}, error) {
+	return nil, errors.New("watching files is only supported on Linux")
+}